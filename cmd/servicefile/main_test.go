@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testdata = "../../internal/parser/golang/testdata"
+
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "servicefile")
+	if err != nil {
+		panic(err)
+	}
+
+	binary = filepath.Join(dir, "servicefile")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	if out, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		panic("failed to build the binary: " + err.Error() + "\n" + string(out))
+	}
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// run runs the binary with args and returns its standard output and standard error.
+func run(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	return stdout.String(), stderr.String(), err
+}
+
+func TestGenerateYAML(t *testing.T) {
+	t.Parallel()
+
+	stdout, stderr, err := run(t, "generate", "--dir", filepath.Join(testdata, "default"))
+	require.NoError(t, err, stderr)
+
+	sf, err := servicefile.ParseYAML([]byte(stdout))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Example", sf.Info.Name)
+	assert.Len(t, sf.Relationships, 3)
+}
+
+func TestGenerateToFile(t *testing.T) {
+	t.Parallel()
+
+	out := filepath.Join(t.TempDir(), "services.mmd")
+
+	stdout, stderr, err := run(t, "generate", "--dir", filepath.Join(testdata, "explicit"), "--format", "mermaid", "--out", out)
+	require.NoError(t, err, stderr)
+	assert.Empty(t, stdout)
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(data), "flowchart LR\n"), string(data))
+}
+
+func TestGenerateWarnings(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "services.yaml")
+
+	stdout, stderr, err := run(t, "generate", "--dir", filepath.Join(testdata, "malformed"), "--out", out)
+	require.NoError(t, err, stderr)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, `warning: `)
+	assert.Contains(t, stderr, `unknown key "technolgy" is ignored`)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary file must be left next to the output")
+	assert.Equal(t, "services.yaml", entries[0].Name())
+}
+
+func TestGenerateFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format string
+		prefix string
+	}{
+		{format: "json", prefix: "["},
+		{format: "dot", prefix: "digraph servicefile {"},
+		{format: "plantuml", prefix: "@startuml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+
+			stdout, stderr, err := run(t, "generate", "--dir", filepath.Join(testdata, "default"), "--format", tt.format)
+			require.NoError(t, err, stderr)
+
+			assert.True(t, strings.HasPrefix(stdout, tt.prefix), stdout)
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		args   []string
+		stderr string
+	}{
+		{
+			name:   "parse error",
+			args:   []string{"generate", "--dir", filepath.Join(testdata, "nonexistent")},
+			stderr: "error parsing service files",
+		},
+		{
+			name:   "unknown format",
+			args:   []string{"generate", "--dir", filepath.Join(testdata, "default"), "--format", "svg"},
+			stderr: `unknown format "svg"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, stderr, err := run(t, tt.args...)
+
+			var exitErr *exec.ExitError
+			require.True(t, errors.As(err, &exitErr), "error = %v, want a non-zero exit", err)
+			assert.NotZero(t, exitErr.ExitCode())
+			assert.Contains(t, stderr, tt.stderr)
+		})
+	}
+}
@@ -0,0 +1,707 @@
+// Package annotation implements the grammar of service annotations independently of the language
+// they are written in. Language frontends turn source comments into lines, Parse extracts the services
+// and relationships they declare, and Build assembles those into service files, so that every frontend
+// produces the same service files for the same annotations.
+package annotation
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// Line is a single line of a comment and where it starts.
+type Line struct {
+	// Text is the raw text of the line, comment markers included.
+	Text string
+	// Pos is the position of the first character of Text, token.NoPos when the line was not parsed from a file.
+	Pos token.Pos
+}
+
+// Span is a range of source text.
+type Span struct {
+	Pos token.Pos
+	End token.Pos
+}
+
+// Service is a service definition.
+type Service struct {
+	Name        string
+	Alias       string
+	Description string
+	System      string
+	Owner       string
+	Tags        []string
+	Annotations map[string]string
+	// Dir is the directory of the file declaring the service.
+	Dir string
+	// Pos is the start of the comment declaring the service.
+	Pos token.Pos
+}
+
+func (s Service) String() string {
+	return fmt.Sprintf("name: %s, description: %s, system: %s, owner: %s",
+		s.Name,
+		s.Description,
+		s.System,
+		s.Owner,
+	)
+}
+
+// Relationship is a relationship annotation, or a relationship discovered by a frontend.
+type Relationship struct {
+	// Service is the service the relationship belongs to, empty for implicit relationships
+	// and "all" for package level relationships.
+	Service    string
+	Action     string
+	Target     string
+	Technology string
+	// Technologies lists every technology of a comma-separated technology line, nil when it has
+	// a single technology. Technology is then the first of them.
+	Technologies []string
+	Description  string
+	Proto        string
+	Port         int
+	// Async is set by an async: true or a sync: false line.
+	Async bool
+	// Environments lists the environments of a comma-separated env line.
+	Environments []string
+	// Deprecated is set by a deprecated: true line.
+	Deprecated bool
+	// Declaration is the line declaring the relationship, without comment markers.
+	Declaration string
+	SLA         string
+	// TimeoutMS is the timeout of the relationship line in milliseconds, zero when it has none or an invalid one.
+	TimeoutMS   int
+	Annotations map[string]string
+	// Dir is the directory of the file declaring the relationship.
+	Dir string
+	// Discovered is set for relationships a frontend found in code rather than in annotations.
+	Discovered bool
+	// Span delimits the annotation, from the relationship line to its last attribute,
+	// or the code a discovered relationship was found in.
+	Span Span
+	// Attributes holds the range of each annotation line keyed by attribute,
+	// "service" being the line declaring the relationship itself.
+	Attributes map[string]Span
+}
+
+func (r Relationship) String() string {
+	return fmt.Sprintf("service_name: %s, action: %s, target_name: %s, technology: %s, proto: %s, port: %d, description: %s",
+		r.Service,
+		r.Action,
+		r.Target,
+		r.Technology,
+		r.Proto,
+		r.Port,
+		r.Description,
+	)
+}
+
+// Annotations holds what was found in a comment, a file or a whole codebase.
+type Annotations struct {
+	Services      []Service
+	Relationships []Relationship
+	Warnings      []Warning
+}
+
+// Add appends the annotations of other.
+func (a *Annotations) Add(other Annotations) {
+	a.Services = append(a.Services, other.Services...)
+	a.Relationships = append(a.Relationships, other.Relationships...)
+	a.Warnings = append(a.Warnings, other.Warnings...)
+}
+
+// DefaultPrefix is the marker starting service annotations.
+const DefaultPrefix = "service:"
+
+// Options configures parsing and building.
+type Options struct {
+	// Prefix is the marker starting service annotations, DefaultPrefix when empty.
+	// A colon is appended when missing, so that arch and arch: are the same prefix.
+	Prefix string
+	// FileSet resolves the positions of lines. It may be nil when lines have no positions.
+	FileSet *token.FileSet
+	// Uncomment removes the comment markers of a line, already trimmed of surrounding whitespace.
+	// Lines are used as they are when Uncomment is nil.
+	Uncomment func(line string) string
+	// CollectUnknown keeps "key: value" lines that don't match any known key as annotations
+	// of the service or relationship they belong to.
+	CollectUnknown bool
+	// FoldTargetCase compares relationship targets and service names case-insensitively.
+	// The first seen casing is kept for output, declared service names taking precedence.
+	FoldTargetCase bool
+	// StrictActions makes Build fail on relationships whose action is not one of the actions
+	// of the specification, instead of keeping them with a warning.
+	StrictActions bool
+	// StrictServices makes Build fail on services declared more than once, instead of merging
+	// their definitions with a warning.
+	StrictServices bool
+	// StrictSelfRelationships makes Build fail on relationships targeting the service they belong to,
+	// instead of keeping them with a warning.
+	StrictSelfRelationships bool
+	// StrictProtos makes Parse warn about protos that are not one of servicefile.CanonicalProtos
+	// once normalized. They are kept either way.
+	StrictProtos bool
+	// MarkExternal makes Build classify relationship targets, see servicefile.MarkExternal.
+	MarkExternal bool
+}
+
+// Parse returns the service or relationship declared by the lines of a comment found in dir.
+// Comments without service annotation declare nothing.
+func Parse(dir string, lines []Line, opts Options) Annotations {
+	var found Annotations
+
+	var comment strings.Builder
+	for _, line := range lines {
+		comment.WriteString(line.Text)
+		comment.WriteString("\n")
+	}
+
+	prefix := opts.prefix()
+	if !strings.Contains(comment.String(), prefix) {
+		return found
+	}
+
+	switch {
+	case strings.Contains(comment.String(), prefix+"name"):
+		parseServiceDefinition(&found, dir, lines, opts)
+	default:
+		parseRelationshipDefinition(&found, dir, lines, opts)
+	}
+
+	return found
+}
+
+// ParseText returns the service or relationship declared by a comment given as raw text,
+// such as a comment written by hand rather than read from a file. Lines have no position.
+func ParseText(dir, comment string, opts Options) Annotations {
+	var lines []Line
+	for _, text := range strings.Split(comment, "\n") {
+		lines = append(lines, Line{Text: text})
+	}
+
+	return Parse(dir, lines, opts)
+}
+
+func parseServiceDefinition(found *Annotations, dir string, lines []Line, opts Options) {
+	s := Service{Dir: dir}
+	if len(lines) > 0 {
+		s.Pos = lines[0].Pos
+	}
+
+	var declared, continued bool
+
+	for i, line := range lines {
+		comment := opts.text(line.Text)
+		if comment == "" {
+			continued = continued && opts.codeBlockFollows(lines[i+1:])
+			continue
+		}
+
+		if continued && !isKey(comment) {
+			s.Description += " " + comment
+			continue
+		}
+		continued = false
+
+		if strings.HasPrefix(comment, opts.prefix()+"name") {
+			declared = true
+			parts := strings.SplitN(comment, " ", 2)
+			if len(parts) == 2 {
+				s.Name = strings.TrimSpace(parts[1])
+			}
+			continue
+		}
+
+		if hasKey(comment, "alias") {
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				s.Alias, _ = unquote(parts[1])
+			}
+			continue
+		}
+
+		if hasKey(comment, servicefile.RenamedFromAnnotation) {
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				if s.Annotations == nil {
+					s.Annotations = make(map[string]string)
+				}
+				s.Annotations[servicefile.RenamedFromAnnotation] = strings.Join(splitList(parts[1]), ", ")
+			}
+			continue
+		}
+
+		if hasKey(comment, "description") {
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				var quoted bool
+				s.Description, quoted = unquote(parts[1])
+				continued = !quoted
+			}
+			continue
+		}
+
+		if hasKey(comment, "system") {
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				s.System, _ = unquote(parts[1])
+			}
+			continue
+		}
+
+		if hasKey(comment, "owner") {
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				s.Owner, _ = unquote(parts[1])
+			}
+			continue
+		}
+
+		if hasKey(comment, "tags") {
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				s.Tags = append(s.Tags, splitList(parts[1])...)
+			}
+			continue
+		}
+
+		if key, value, ok := splitAnnotation(comment); ok && opts.CollectUnknown {
+			if s.Annotations == nil {
+				s.Annotations = make(map[string]string)
+			}
+			s.Annotations[key] = value
+		} else if ok && isUnknownKey(comment, serviceKeys, opts) {
+			found.Warnings = append(found.Warnings, unknownKeyWarning(lines, line, comment, serviceKeys, opts))
+		}
+	}
+
+	if s.Name == "" {
+		if !declared {
+			return
+		}
+		found.Warnings = append(found.Warnings, opts.warn(lines, "service definition without a name is ignored"))
+		return
+	}
+
+	found.Services = append(found.Services, s)
+}
+
+func parseRelationshipDefinition(found *Annotations, dir string, lines []Line, opts Options) {
+	r := Relationship{Dir: dir, Attributes: make(map[string]Span)}
+
+	var continued bool
+
+	for i, line := range lines {
+		comment := opts.text(line.Text)
+		if comment == "" {
+			continued = continued && opts.codeBlockFollows(lines[i+1:])
+			continue
+		}
+
+		if continued && !isKey(comment) {
+			r.Description += " " + comment
+			sp := commentSpan(line, comment)
+			r.Attributes["description"] = Span{Pos: r.Attributes["description"].Pos, End: sp.End}
+			r.Span.End = max(r.Span.End, sp.End)
+			continue
+		}
+		continued = false
+
+		var key string
+
+		switch {
+		case strings.HasPrefix(comment, opts.prefix()):
+			key = "service"
+			r.Declaration = comment
+			r.Service, r.Action, r.Target = extractRelationshipInfo(strings.TrimPrefix(comment, opts.prefix()))
+			var timeout string
+			r.Target, r.SLA, timeout = splitInlineTokens(r.Target)
+			if timeout != "" {
+				ms, err := parseTimeoutMS(timeout)
+				if err != nil {
+					found.Warnings = append(found.Warnings, opts.warnAt(lines, line, err.Error()))
+				}
+				r.TimeoutMS = ms
+			}
+		case hasKey(comment, "technology"):
+			key = "technology"
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				r.Technology, r.Technologies = splitTechnologies(parts[1])
+			}
+		case hasKey(comment, "description"):
+			key = "description"
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				var quoted bool
+				r.Description, quoted = unquote(parts[1])
+				continued = !quoted
+			}
+		case hasKey(comment, "proto"):
+			key = "proto"
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				value, _ := unquote(parts[1])
+				proto, canonical := servicefile.NormalizeProto(value)
+				if !canonical && proto != "" && opts.StrictProtos {
+					found.Warnings = append(found.Warnings, opts.warnAt(lines, line, fmt.Sprintf("unknown proto %q, expected one of %s",
+						proto, strings.Join(servicefile.CanonicalProtos(), ", "))))
+				}
+				r.Proto = proto
+			}
+		case hasKey(comment, "port"):
+			key = "port"
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				value, _ := unquote(parts[1])
+				port, err := parsePort(value)
+				if err != nil {
+					found.Warnings = append(found.Warnings, opts.warnAt(lines, line, err.Error()))
+					continue
+				}
+				r.Port = port
+			}
+		case hasKey(comment, "env"):
+			key = "env"
+			parts := strings.SplitN(comment, ":", 2)
+			if len(parts) == 2 {
+				r.Environments = append(r.Environments, splitList(parts[1])...)
+			}
+		case hasKey(comment, "async"), hasKey(comment, "sync"):
+			parts := strings.SplitN(comment, ":", 2)
+			key = strings.ToLower(parts[0])
+			value, _ := unquote(parts[1])
+			async, err := strconv.ParseBool(value)
+			if err != nil {
+				found.Warnings = append(found.Warnings, opts.warnAt(lines, line,
+					fmt.Sprintf("invalid %s %q is ignored: must be true or false", key, value)))
+				continue
+			}
+			r.Async = async == (key == "async")
+		case hasKey(comment, "deprecated"):
+			key = "deprecated"
+			parts := strings.SplitN(comment, ":", 2)
+			value, _ := unquote(parts[1])
+			deprecated, err := strconv.ParseBool(value)
+			if err != nil {
+				found.Warnings = append(found.Warnings, opts.warnAt(lines, line,
+					fmt.Sprintf("invalid deprecated %q is ignored: must be true or false", value)))
+				continue
+			}
+			r.Deprecated = deprecated
+		default:
+			var value string
+			var ok bool
+			if key, value, ok = splitAnnotation(comment); !ok || !opts.CollectUnknown {
+				if ok && isUnknownKey(comment, relationshipKeys, opts) {
+					found.Warnings = append(found.Warnings, unknownKeyWarning(lines, line, comment, relationshipKeys, opts))
+				}
+				continue
+			}
+			if r.Annotations == nil {
+				r.Annotations = make(map[string]string)
+			}
+			r.Annotations[key] = value
+		}
+
+		sp := commentSpan(line, comment)
+		r.Attributes[key] = sp
+
+		if r.Span.Pos == token.NoPos || sp.Pos < r.Span.Pos {
+			r.Span.Pos = sp.Pos
+		}
+		if sp.End > r.Span.End {
+			r.Span.End = sp.End
+		}
+	}
+
+	if r.Action == "" {
+		if _, declared := r.Attributes["service"]; !declared {
+			return
+		}
+		found.Warnings = append(found.Warnings, opts.warn(lines, "relationship without an action is ignored"))
+		return
+	}
+
+	found.Relationships = append(found.Relationships, r)
+}
+
+// splitTechnologies splits a comma-separated list of technologies into the first one and,
+// when there are several, all of them. A quoted value is a single technology.
+// Example: grpc, http2, protobuf
+func splitTechnologies(list string) (first string, all []string) {
+	if value, quoted := unquote(list); quoted {
+		return value, nil
+	}
+
+	all = splitList(list)
+
+	switch len(all) {
+	case 0:
+		return "", nil
+	case 1:
+		return all[0], nil
+	default:
+		return all[0], all
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+// Example: payments, critical
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseTimeoutMS returns the number of milliseconds of a timeout token,
+// either a duration such as 500ms or 2s, or a plain number of milliseconds.
+func parseTimeoutMS(timeout string) (int, error) {
+	if ms, err := strconv.Atoi(timeout); err == nil && ms >= 0 {
+		return ms, nil
+	}
+
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q is ignored: expected a duration such as 500ms or a number of milliseconds", timeout)
+	}
+
+	return int(d.Milliseconds()), nil
+}
+
+// parsePort parses the port of a relationship, a number between 1 and 65535.
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q is ignored: must be a number between 1 and 65535", value)
+	}
+
+	return port, nil
+}
+
+// prefix returns the marker starting service annotations.
+func (o Options) prefix() string {
+	if o.Prefix == "" {
+		return DefaultPrefix
+	}
+
+	return strings.TrimSuffix(o.Prefix, ":") + ":"
+}
+
+// text returns the annotation text of a line, without comment markers, list markers
+// and surrounding whitespace.
+func (o Options) text(line string) string {
+	comment := strings.TrimSpace(line)
+	if o.Uncomment != nil {
+		comment = o.Uncomment(comment)
+	}
+	comment = trimListMarker(strings.TrimSpace(comment))
+	return strings.TrimSpace(comment)
+}
+
+// codeBlockFollows reports whether the first non-blank line of lines is indented with a tab, as gofmt indents
+// the code blocks of doc comments. gofmt turns the indented continuation lines of a description into such
+// a block surrounded by blank lines, which then don't end the description.
+func (o Options) codeBlockFollows(lines []Line) bool {
+	for _, line := range lines {
+		if o.text(line.Text) == "" {
+			continue
+		}
+
+		text := line.Text
+		if o.Uncomment != nil {
+			text = o.Uncomment(text)
+		}
+
+		return strings.HasPrefix(text, "\t")
+	}
+
+	return false
+}
+
+// UncommentC removes the markers of C style line and block comments, as used by Go and protobuf.
+func UncommentC(line string) string {
+	line = strings.TrimPrefix(line, "//")
+	line = strings.TrimPrefix(line, "/*")
+	return strings.TrimSuffix(line, "*/")
+}
+
+// serviceKeys are the keys parsed in service definitions, and relationshipKeys the keys parsed
+// in relationship definitions. They must be kept in sync with the parsing of both definitions.
+var (
+	serviceKeys      = []string{"alias", "renamed-from", "description", "system", "owner", "tags"}
+	relationshipKeys = []string{"technology", "description", "proto", "port", "async", "sync", "env", "deprecated"}
+)
+
+// isUnknownKey reports whether a "key: value" comment line looks like a misspelled annotation:
+// its key is not one of keys, and is written in lower case as annotation keys are.
+// Capitalized keys such as Note: or Deprecated:, URLs and lines starting with the prefix, or with
+// the default prefix that a custom prefix is chosen to avoid, are not misspelled annotations.
+func isUnknownKey(comment string, keys []string, opts Options) bool {
+	key, value, ok := splitAnnotation(comment)
+	if !ok || strings.HasPrefix(comment, opts.prefix()) || strings.HasPrefix(comment, DefaultPrefix) || strings.HasPrefix(value, "//") {
+		return false
+	}
+	if r := key[0]; r < 'a' || r > 'z' {
+		return false
+	}
+
+	return !slices.Contains(keys, key)
+}
+
+// unknownKeyWarning returns the warning about the unknown key of a line of the comment made of lines.
+func unknownKeyWarning(lines []Line, line Line, comment string, keys []string, opts Options) Warning {
+	key, _, _ := splitAnnotation(comment)
+
+	return opts.warnAt(lines, line,
+		fmt.Sprintf("unknown key %q is ignored, expected one of %s", key, strings.Join(keys, ", ")))
+}
+
+// hasKey reports whether a comment line is a "key: value" annotation for key.
+// Keys are matched regardless of their case, so that Description: is read as description:,
+// values keeping their case.
+func hasKey(comment, key string) bool {
+	return len(comment) > len(key) && comment[len(key)] == ':' && strings.EqualFold(comment[:len(key)], key)
+}
+
+// isKey reports whether a comment line starts with a key, ending a multi-line description.
+func isKey(comment string) bool {
+	_, _, ok := splitAnnotation(comment)
+	return ok
+}
+
+// splitAnnotation splits a "key: value" comment line.
+// Keys are single words, so that regular sentences containing a colon are not taken for annotations.
+func splitAnnotation(comment string) (key, value string, ok bool) {
+	key, value, ok = strings.Cut(comment, ":")
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+
+	value, _ = unquote(value)
+
+	return key, value, true
+}
+
+// unquote returns a trimmed annotation value, or the text between its double quotes, verbatim,
+// when it is quoted, so that values can contain commas and colons. A value missing its closing
+// quote is returned as written.
+// Example: "HTTP/2 over TLS: strict"
+func unquote(value string) (unquoted string, quoted bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, `"`) {
+		return value, false
+	}
+
+	end := strings.Index(value[1:], `"`)
+	if end < 0 {
+		return value, false
+	}
+
+	return value[1 : end+1], true
+}
+
+// commentSpan returns the range of the annotation text within a comment line,
+// leaving out comment markers and surrounding whitespace.
+func commentSpan(line Line, comment string) Span {
+	if line.Pos == token.NoPos {
+		return Span{}
+	}
+
+	pos := line.Pos + token.Pos(strings.Index(line.Text, comment))
+
+	return Span{Pos: pos, End: pos + token.Pos(len(comment))}
+}
+
+// trimListMarker removes the marker of a doc comment list item, so that annotations
+// reformatted by gofmt into a list are still recognized.
+// Markers are the ones of Go doc comments: -, *, +, • and numbers followed by . or ).
+func trimListMarker(comment string) string {
+	for _, marker := range []string{"-", "*", "+", "•"} {
+		if rest, ok := strings.CutPrefix(comment, marker+" "); ok {
+			return rest
+		}
+	}
+
+	digits := 0
+	for digits < len(comment) && comment[digits] >= '0' && comment[digits] <= '9' {
+		digits++
+	}
+
+	if digits > 0 && len(comment) > digits+1 &&
+		(comment[digits] == '.' || comment[digits] == ')') && comment[digits+1] == ' ' {
+		return comment[digits+2:]
+	}
+
+	return comment
+}
+
+// splitInlineTokens separates the sla=... and timeout=... tokens ending a relationship target.
+// Example: billing sla=p99<200ms timeout=500ms
+func splitInlineTokens(target string) (name, sla, timeout string) {
+	fields := strings.Fields(target)
+
+	for len(fields) > 0 {
+		key, value, ok := strings.Cut(fields[len(fields)-1], "=")
+		if !ok {
+			break
+		}
+
+		switch key {
+		case "sla":
+			sla = value
+		case "timeout":
+			timeout = value
+		default:
+			return strings.Join(fields, " "), sla, timeout
+		}
+
+		fields = fields[:len(fields)-1]
+	}
+
+	return strings.Join(fields, " "), sla, timeout
+}
+
+// AllServices is the service name of package level relationships,
+// which apply to every service declared in the same package.
+const AllServices = "all"
+
+// extractRelationshipInfo extracts the service name, action, and target name from a comment
+// without its prefix.
+// Format: {service_name}:{action} [target_service] or {action} [target_service]
+// Example: database:uses PostgreSQL
+// Example: uses PostgreSQL
+// Example: all:uses Logger
+func extractRelationshipInfo(comment string) (serviceName, action, targetName string) {
+	parts := strings.SplitN(comment, " ", 2)
+	serviceActionPart := parts[0]
+
+	serviceActionParts := strings.Split(serviceActionPart, ":")
+	if len(serviceActionParts) >= 2 {
+		// Format: {service_name}:{action}
+		serviceName = serviceActionParts[0]
+		action = serviceActionParts[1]
+	} else {
+		// Format: {action}
+		action = serviceActionParts[0]
+	}
+
+	// Extract target name if present
+	if len(parts) > 1 {
+		targetName = strings.TrimSpace(parts[1])
+	}
+
+	return serviceName, action, targetName
+}
@@ -0,0 +1,761 @@
+package annotation
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+func TestParseText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                  string
+		comment               string
+		opts                  Options
+		expectedServices      []Service
+		expectedRelationships []Relationship
+		expectedWarnings      []string
+	}{
+		{
+			name: "service definition",
+			comment: `service:name Billing
+description: Charges customers
+system: payments`,
+			expectedServices: []Service{
+				{Name: "Billing", Description: "Charges customers", System: "payments", Dir: "billing"},
+			},
+		},
+		{
+			name: "implicit relationship",
+			comment: `service:uses PostgreSQL
+description: Stores invoices
+technology:postgresql
+proto: tcp`,
+			expectedRelationships: []Relationship{
+				{
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Description: "Stores invoices",
+					Technology:  "postgresql",
+					Proto:       "tcp",
+					Declaration: "service:uses PostgreSQL",
+				},
+			},
+		},
+		{
+			name:    "explicit relationship with inline tokens",
+			comment: "service:Billing:requests Fraud Check sla=p99<200ms timeout=500ms",
+			expectedRelationships: []Relationship{
+				{
+					Service:     "Billing",
+					Action:      "requests",
+					Target:      "Fraud Check",
+					SLA:         "p99<200ms",
+					TimeoutMS:   500,
+					Declaration: "service:Billing:requests Fraud Check sla=p99<200ms timeout=500ms",
+				},
+			},
+		},
+		{
+			name:    "package level relationship without target",
+			comment: "service:all:replies",
+			expectedRelationships: []Relationship{
+				{Service: AllServices, Action: "replies", Declaration: "service:all:replies"},
+			},
+		},
+		{
+			name: "list markers",
+			comment: `- service:sends Kafka
+1. technology: kafka`,
+			expectedRelationships: []Relationship{
+				{Action: "sends", Target: "Kafka", Technology: "kafka", Declaration: "service:sends Kafka"},
+			},
+		},
+		{
+			name: "comment markers removed by the frontend",
+			comment: `# service:receives Kafka
+# technology: kafka`,
+			opts: Options{Uncomment: func(line string) string { return strings.TrimPrefix(line, "#") }},
+			expectedRelationships: []Relationship{
+				{Action: "receives", Target: "Kafka", Technology: "kafka", Declaration: "service:receives Kafka"},
+			},
+		},
+		{
+			name: "unknown keys collected",
+			comment: `service:name Billing
+lifecycle: production
+Note this sentence is not an annotation: it contains spaces before the colon`,
+			opts: Options{CollectUnknown: true},
+			expectedServices: []Service{
+				{Name: "Billing", Annotations: map[string]string{"lifecycle": "production"}, Dir: "billing"},
+			},
+		},
+		{
+			name: "unknown keys reported by default",
+			comment: `service:uses Redis
+slo: 99.9%`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "Redis", Declaration: "service:uses Redis"},
+			},
+			expectedWarnings: []string{`unknown key "slo" is ignored, expected one of technology, description, proto, port, async, sync, env, deprecated`},
+		},
+		{
+			name: "misspelled key",
+			comment: `service:uses Redis
+technolgy: redis`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "Redis", Declaration: "service:uses Redis"},
+			},
+			expectedWarnings: []string{`unknown key "technolgy" is ignored, expected one of technology, description, proto, port, async, sync, env, deprecated`},
+		},
+		{
+			name: "misspelled service key",
+			comment: `service:name Billing
+sytem: payments`,
+			expectedServices: []Service{
+				{Name: "Billing", Dir: "billing"},
+			},
+			expectedWarnings: []string{`unknown key "sytem" is ignored, expected one of alias, renamed-from, description, system, owner, tags`},
+		},
+		{
+			name: "prose is not an unknown key",
+			comment: `Note: Redis is flushed nightly, see https://wiki.example.com/redis.
+service:uses Redis`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "Redis", Declaration: "service:uses Redis"},
+			},
+		},
+		{
+			name: "service with system and owner",
+			comment: `service:name Billing
+system: payments
+owner: payments-team`,
+			expectedServices: []Service{
+				{Name: "Billing", System: "payments", Owner: "payments-team", Dir: "billing"},
+			},
+		},
+		{
+			name: "service alias",
+			comment: `service:name PaymentsAuthorizationService
+alias: Payments`,
+			expectedServices: []Service{
+				{Name: "PaymentsAuthorizationService", Alias: "Payments", Dir: "billing"},
+			},
+		},
+		{
+			name: "former service names",
+			comment: `service:name Checkout
+alias: Checkout, the cart
+renamed-from: cart, , basket`,
+			expectedServices: []Service{
+				{
+					Name:        "Checkout",
+					Alias:       "Checkout, the cart",
+					Annotations: map[string]string{servicefile.RenamedFromAnnotation: "cart, basket"},
+					Dir:         "billing",
+				},
+			},
+		},
+		{
+			name: "tags",
+			comment: `service:name Billing
+tags: payments, , critical ,
+tags: pci`,
+			expectedServices: []Service{
+				{Name: "Billing", Tags: []string{"payments", "critical", "pci"}, Dir: "billing"},
+			},
+		},
+		{
+			name: "multi-line descriptions",
+			comment: `service:name Billing
+description: Charges customers
+  and refunds them.
+system: payments`,
+			expectedServices: []Service{
+				{Name: "Billing", Description: "Charges customers and refunds them.", System: "payments", Dir: "billing"},
+			},
+		},
+		{
+			name: "description ended by a blank line",
+			comment: `service:uses PostgreSQL
+description: Stores invoices
+
+Invoices are kept for ten years.`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "PostgreSQL", Description: "Stores invoices", Declaration: "service:uses PostgreSQL"},
+			},
+		},
+		{
+			name: "several technologies",
+			comment: `service:requests Billing
+technology: grpc, http2 ,protobuf`,
+			expectedRelationships: []Relationship{
+				{
+					Action:       "requests",
+					Target:       "Billing",
+					Technology:   "grpc",
+					Technologies: []string{"grpc", "http2", "protobuf"},
+					Declaration:  "service:requests Billing",
+				},
+			},
+		},
+		{
+			name: "port",
+			comment: `service:uses PostgreSQL
+port: 5432`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "PostgreSQL", Port: 5432, Declaration: "service:uses PostgreSQL"},
+			},
+		},
+		{
+			name: "quoted port",
+			comment: `service:uses PostgreSQL
+port: "5432"`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "PostgreSQL", Port: 5432, Declaration: "service:uses PostgreSQL"},
+			},
+		},
+		{
+			name: "quoted async",
+			comment: `service:sends Kafka
+async: "true"`,
+			expectedRelationships: []Relationship{
+				{Action: "sends", Target: "Kafka", Async: true, Declaration: "service:sends Kafka"},
+			},
+		},
+		{
+			name: "quoted deprecated",
+			comment: `service:uses LegacyAuth
+deprecated: "true"`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "LegacyAuth", Deprecated: true, Declaration: "service:uses LegacyAuth"},
+			},
+		},
+		{
+			name: "invalid port",
+			comment: `service:uses PostgreSQL
+port: 70000`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "PostgreSQL", Declaration: "service:uses PostgreSQL"},
+			},
+			expectedWarnings: []string{`invalid port "70000" is ignored: must be a number between 1 and 65535`},
+		},
+		{
+			name:    "invalid timeout",
+			comment: `service:requests Fraud timeout=soon`,
+			expectedRelationships: []Relationship{
+				{Action: "requests", Target: "Fraud", Declaration: "service:requests Fraud timeout=soon"},
+			},
+			expectedWarnings: []string{`invalid timeout "soon" is ignored: expected a duration such as 500ms or a number of milliseconds`},
+		},
+		{
+			name: "keys matched regardless of their case",
+			comment: `service:uses PostgreSQL
+Description: Stores Invoices
+TECHNOLOGY: PostgreSQL
+Sync: false`,
+			expectedRelationships: []Relationship{
+				{
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Description: "Stores Invoices",
+					Technology:  "PostgreSQL",
+					Async:       true,
+					Declaration: "service:uses PostgreSQL",
+				},
+			},
+		},
+		{
+			name: "quoted values",
+			comment: `service:requests Gateway
+technology: "HTTP/2 over TLS: strict, pinned"
+description: "Proxies: every request"
+proto: "grpc:native"`,
+			expectedRelationships: []Relationship{
+				{
+					Action:      "requests",
+					Target:      "Gateway",
+					Technology:  "HTTP/2 over TLS: strict, pinned",
+					Description: "Proxies: every request",
+					Proto:       "grpc:native",
+					Declaration: "service:requests Gateway",
+				},
+			},
+		},
+		{
+			name: "unquoted values containing colons",
+			comment: `service:uses Redis
+technology: redis://cluster
+description: Caches sessions: 1h TTL
+proto: grpc:native`,
+			expectedRelationships: []Relationship{
+				{
+					Action:      "uses",
+					Target:      "Redis",
+					Technology:  "redis://cluster",
+					Description: "Caches sessions: 1h TTL",
+					Proto:       "grpc:native",
+					Declaration: "service:uses Redis",
+				},
+			},
+		},
+		{
+			name: "quoted service values",
+			comment: `service:name Billing
+description: "Charges customers"
+  not a continuation: line
+system: "payments: core"
+owner: "payments-team"
+lifecycle: "production: eu"`,
+			opts: Options{CollectUnknown: true},
+			expectedServices: []Service{
+				{
+					Name:        "Billing",
+					Description: "Charges customers",
+					System:      "payments: core",
+					Owner:       "payments-team",
+					Annotations: map[string]string{"lifecycle": "production: eu"},
+					Dir:         "billing",
+				},
+			},
+		},
+		{
+			name: "quoted value without closing quote",
+			comment: `service:name Billing
+description: "Charges customers`,
+			expectedServices: []Service{
+				{Name: "Billing", Description: `"Charges customers`, Dir: "billing"},
+			},
+		},
+		{
+			name: "async",
+			comment: `service:sends Kafka
+async: true`,
+			expectedRelationships: []Relationship{
+				{Action: "sends", Target: "Kafka", Async: true, Declaration: "service:sends Kafka"},
+			},
+		},
+		{
+			name: "sync false",
+			comment: `service:uses Kafka
+sync: false`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "Kafka", Async: true, Declaration: "service:uses Kafka"},
+			},
+		},
+		{
+			name: "environments",
+			comment: `service:requests Debugger
+env: dev, staging`,
+			expectedRelationships: []Relationship{
+				{Action: "requests", Target: "Debugger", Environments: []string{"dev", "staging"}, Declaration: "service:requests Debugger"},
+			},
+		},
+		{
+			name: "deprecated",
+			comment: `service:requests LegacyAuth
+deprecated: true`,
+			expectedRelationships: []Relationship{
+				{Action: "requests", Target: "LegacyAuth", Deprecated: true, Declaration: "service:requests LegacyAuth"},
+			},
+		},
+		{
+			name: "invalid deprecated",
+			comment: `service:requests LegacyAuth
+deprecated: soon`,
+			expectedRelationships: []Relationship{
+				{Action: "requests", Target: "LegacyAuth", Declaration: "service:requests LegacyAuth"},
+			},
+			expectedWarnings: []string{`invalid deprecated "soon" is ignored: must be true or false`},
+		},
+		{
+			name: "invalid async",
+			comment: `service:uses Kafka
+async: sometimes`,
+			expectedRelationships: []Relationship{
+				{Action: "uses", Target: "Kafka", Declaration: "service:uses Kafka"},
+			},
+			expectedWarnings: []string{`invalid async "sometimes" is ignored: must be true or false`},
+		},
+		{
+			name: "custom prefix",
+			comment: `arch:Billing:uses PostgreSQL
+technology: postgresql
+service:uses Redis`,
+			opts: Options{Prefix: "arch"},
+			expectedRelationships: []Relationship{
+				{
+					Service:     "Billing",
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Technology:  "postgresql",
+					Declaration: "arch:Billing:uses PostgreSQL",
+				},
+			},
+		},
+		{
+			name:    "regular comment",
+			comment: "Billing charges customers.",
+		},
+		{
+			name: "service without name",
+			comment: `service:name
+description: Unnamed`,
+			expectedWarnings: []string{"service definition without a name is ignored"},
+		},
+		{
+			name: "relationship without action",
+			comment: `service:
+description: Missing action`,
+			expectedWarnings: []string{"relationship without an action is ignored"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			found := ParseText("billing", tt.comment, tt.opts)
+
+			if len(found.Services) != len(tt.expectedServices) || (len(found.Services) > 0 && !reflect.DeepEqual(found.Services, tt.expectedServices)) {
+				t.Errorf("ParseText() services = %+v, want %+v", found.Services, tt.expectedServices)
+			}
+
+			if len(found.Relationships) != len(tt.expectedRelationships) {
+				t.Fatalf("ParseText() relationships = %+v, want %+v", found.Relationships, tt.expectedRelationships)
+			}
+
+			for i, r := range found.Relationships {
+				expected := tt.expectedRelationships[i]
+				expected.Dir = "billing"
+				r.Attributes = nil
+
+				if !reflect.DeepEqual(r, expected) {
+					t.Errorf("ParseText() relationship = %+v, want %+v", r, expected)
+				}
+			}
+
+			var warnings []string
+			for _, w := range found.Warnings {
+				if w.Text != tt.comment {
+					t.Errorf("ParseText() warning text = %q, want %q", w.Text, tt.comment)
+				}
+				warnings = append(warnings, w.String())
+			}
+
+			if !reflect.DeepEqual(warnings, tt.expectedWarnings) {
+				t.Errorf("ParseText() warnings = %v, want %v", warnings, tt.expectedWarnings)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	parse := func(dir string, comments ...string) Annotations {
+		var found Annotations
+		for _, comment := range comments {
+			found.Add(ParseText(dir, comment, Options{}))
+		}
+		return found
+	}
+
+	tests := []struct {
+		name          string
+		found         Annotations
+		discovered    []Relationship
+		opts          Options
+		expected      []*servicefile.ServiceFile
+		expectedError string
+	}{
+		{
+			name: "relationships attributed to the service of their package",
+			found: parse("billing",
+				"service:name Billing\ndescription: Charges customers",
+				"service:uses PostgreSQL\ntechnology:postgresql\ndescription: Stores invoices",
+				"service:uses PostgreSQL\ntechnology:postgresql",
+				"service:requests Fraud timeout=2s",
+			),
+			discovered: []Relationship{
+				{Service: "Billing", Action: "uses", Target: "PostgreSQL", Discovered: true},
+			},
+			expected: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info:    servicefile.Info{Name: "Billing", Description: "Charges customers"},
+					Relationships: []servicefile.Relationship{
+						{Action: servicefile.RelationshipActionRequests, Name: "Fraud", TimeoutMS: 2000},
+						{Action: servicefile.RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores invoices"},
+					},
+				},
+			},
+		},
+		{
+			name: "relationships with several technologies",
+			found: parse("billing",
+				"service:name Billing",
+				"service:requests Fraud\ntechnology: grpc, http2",
+				"service:requests Fraud\ntechnology: grpc,http2\ndescription: Scores payments",
+				"service:requests Fraud\ntechnology: grpc",
+			),
+			expected: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info:    servicefile.Info{Name: "Billing"},
+					Relationships: []servicefile.Relationship{
+						{Action: servicefile.RelationshipActionRequests, Name: "Fraud", Technology: "grpc"},
+						{
+							Action:       servicefile.RelationshipActionRequests,
+							Name:         "Fraud",
+							Technology:   "grpc",
+							Technologies: []string{"grpc", "http2"},
+							Description:  "Scores payments",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "case folded targets",
+			found: parse("billing",
+				"service:name Kafka",
+				"service:Billing:sends kafka",
+			),
+			opts: Options{FoldTargetCase: true},
+			expected: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info:    servicefile.Info{Name: "Billing"},
+					Relationships: []servicefile.Relationship{
+						{Action: servicefile.RelationshipActionSends, Name: "Kafka"},
+					},
+				},
+				{
+					Version:       servicefile.Version,
+					Info:          servicefile.Info{Name: "Kafka"},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+		},
+		{
+			name: "service alias",
+			found: parse("billing",
+				"service:name PaymentsAuthorizationService\nalias: Payments",
+				"service:uses Vault",
+			),
+			expected: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info:    servicefile.Info{Name: "PaymentsAuthorizationService", Alias: "Payments"},
+					Relationships: []servicefile.Relationship{
+						{Action: servicefile.RelationshipActionUses, Name: "Vault"},
+					},
+				},
+			},
+		},
+		{
+			name: "mixed patterns",
+			found: parse("billing",
+				"service:name Billing",
+				"service:Billing:uses PostgreSQL",
+				"service:uses Redis",
+			),
+			expectedError: "mixed relationship definition patterns detected",
+		},
+		{
+			name:          "package level relationship without service",
+			found:         parse("billing", "service:all:uses Logger"),
+			expectedError: "no services declared in billing for package level relationship",
+		},
+		{
+			name: "unknown action in strict mode",
+			found: parse("billing",
+				"service:name Billing",
+				"service:usess PostgreSQL",
+			),
+			opts:          Options{StrictActions: true},
+			expectedError: `unknown relationship action "usess"`,
+		},
+		{
+			name: "redeclared service merged",
+			found: parse("billing",
+				"service:name Billing\ndescription: Charges customers",
+				"service:name Billing\ndescription: Refunds customers\nsystem: payments",
+			),
+			expected: []*servicefile.ServiceFile{
+				{
+					Version:       servicefile.Version,
+					Info:          servicefile.Info{Name: "Billing", Description: "Charges customers", System: "payments"},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+		},
+		{
+			name: "redeclared service in strict mode",
+			found: parse("billing",
+				"service:name Billing",
+				"service:name Billing",
+			),
+			opts:          Options{StrictServices: true},
+			expectedError: `service "Billing" is declared more than once`,
+		},
+		{
+			name: "self relationship in strict mode",
+			found: parse("billing",
+				"service:name Billing",
+				"service:requests Billing",
+			),
+			opts:          Options{StrictSelfRelationships: true},
+			expectedError: `relationship targets its own service "Billing"`,
+		},
+		{
+			name: "external targets marked",
+			found: func() Annotations {
+				found := parse("orders", "service:name Orders", "service:requests Billing", "service:uses PostgreSQL")
+				found.Add(parse("billing", "service:name Billing", "service:replies Orders"))
+				return found
+			}(),
+			opts: Options{MarkExternal: true},
+			expected: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info:    servicefile.Info{Name: "Billing"},
+					Relationships: []servicefile.Relationship{
+						{Action: servicefile.RelationshipActionReplies, Name: "Orders"},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info:    servicefile.Info{Name: "Orders"},
+					Relationships: []servicefile.Relationship{
+						{Action: servicefile.RelationshipActionRequests, Name: "Billing"},
+						{Action: servicefile.RelationshipActionUses, Name: "PostgreSQL", External: true},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, _, err := Build(tt.found, tt.discovered, tt.opts)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Build() error = %v, want %q", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			sortByName(result)
+
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Build() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildUnknownActionWarning(t *testing.T) {
+	t.Parallel()
+
+	var found Annotations
+	found.Add(ParseText("billing", "service:name Billing", Options{}))
+	found.Add(ParseText("billing", "service:usess PostgreSQL", Options{}))
+
+	result, warnings, err := Build(found, nil, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || len(result[0].Relationships) != 1 {
+		t.Errorf("Build() = %+v, want the relationship kept", result)
+	}
+
+	expected := []Warning{{Text: "service:usess PostgreSQL", Message: `unknown relationship action "usess"`}}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Build() warnings = %+v, want %+v", warnings, expected)
+	}
+}
+
+func sortByName(files []*servicefile.ServiceFile) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Info.Name < files[j].Info.Name
+	})
+}
+
+func TestKnownKeysParsed(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range serviceKeys {
+		if found := ParseText("billing", "service:name Billing\n"+key+": value", Options{}); len(found.Warnings) != 0 {
+			t.Errorf("service key %q: warnings = %v, want none", key, found.Warnings)
+		}
+	}
+
+	for _, key := range relationshipKeys {
+		found := ParseText("billing", "service:uses Redis\n"+key+": true", Options{})
+		for _, w := range found.Warnings {
+			if strings.Contains(w.Message, "unknown key") {
+				t.Errorf("relationship key %q: warning = %v, want none", key, w)
+			}
+		}
+	}
+}
+
+func TestProtoNormalized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		proto        string
+		opts         Options
+		wantProto    string
+		wantWarnings []string
+	}{
+		{name: "canonical", proto: "grpc", wantProto: "grpc"},
+		{name: "lower-cased", proto: "gRPC", wantProto: "grpc"},
+		{name: "alias", proto: "HTTP/1.1", wantProto: "http"},
+		{name: "unknown passes through", proto: "QUIC", wantProto: "quic"},
+		{
+			name:         "unknown in strict mode",
+			proto:        "QUIC",
+			opts:         Options{StrictProtos: true},
+			wantProto:    "quic",
+			wantWarnings: []string{`unknown proto "quic", expected one of tcp, udp, http, https, grpc, amqp, kafka`},
+		},
+		{name: "known in strict mode", proto: "TCP", opts: Options{StrictProtos: true}, wantProto: "tcp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			found := ParseText("billing", "service:uses Redis\nproto: "+tt.proto, tt.opts)
+			if len(found.Relationships) != 1 {
+				t.Fatalf("ParseText() relationships = %+v, want one relationship", found.Relationships)
+			}
+
+			if got := found.Relationships[0].Proto; got != tt.wantProto {
+				t.Errorf("proto = %q, want %q", got, tt.wantProto)
+			}
+
+			var warnings []string
+			for _, w := range found.Warnings {
+				warnings = append(warnings, w.Message)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
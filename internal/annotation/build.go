@@ -0,0 +1,455 @@
+package annotation
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// Build assembles the service files described by the parsed services and relationships.
+// Discovered relationships, found by a frontend in code rather than in annotations, are added
+// unless the service already has a relationship with the same action and target.
+// Services declared more than once are merged, the first non-empty fields winning, see StrictServices.
+// Warnings about relationships with an unknown action, about relationships targeting their own service
+// and about services declared more than once are returned along with the service files.
+func Build(found Annotations, discovered []Relationship, opts Options) ([]*servicefile.ServiceFile, []Warning, error) {
+	b := builder{services: found.Services, relationships: found.Relationships, opts: opts}
+
+	if err := b.validateNoMixedUsage(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := b.checkActions(); err != nil {
+		return nil, nil, err
+	}
+
+	serviceFiles := make(map[string]*servicefile.ServiceFile)
+	declarations := make(map[string]Service)
+
+	for _, s := range b.services {
+		sf := &servicefile.ServiceFile{
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        s.Name,
+				Alias:       s.Alias,
+				Description: s.Description,
+				System:      s.System,
+				Owner:       s.Owner,
+				Tags:        slices.Clone(s.Tags),
+				Annotations: maps.Clone(s.Annotations),
+				Source:      b.opts.source(s.Pos),
+			},
+			Relationships: []servicefile.Relationship{},
+		}
+
+		first, exists := declarations[s.Name]
+		if !exists {
+			declarations[s.Name] = s
+			serviceFiles[s.Name] = sf
+			continue
+		}
+
+		if err := b.duplicateService(first, s); err != nil {
+			return nil, nil, err
+		}
+
+		if err := serviceFiles[s.Name].Merge(sf); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	names := b.canonicalNames()
+
+	relationships, err := b.expandPackageRelationships()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relationships = append(relationships, discovered...)
+
+	for _, r := range relationships {
+		if opts.FoldTargetCase {
+			r.Service = names.resolve(r.Service)
+			r.Target = names.resolve(r.Target)
+		}
+
+		serviceName, err := b.determineServiceName(r, serviceFiles)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to determine service name: %w", err)
+		}
+
+		if err := b.checkSelfRelationship(r, serviceName); err != nil {
+			return nil, nil, err
+		}
+
+		if _, exists := serviceFiles[serviceName]; !exists {
+			serviceFiles[serviceName] = &servicefile.ServiceFile{
+				Version: servicefile.Version,
+				Info: servicefile.Info{
+					Name: serviceName,
+				},
+				Relationships: []servicefile.Relationship{},
+			}
+		}
+
+		relationship := servicefile.Relationship{
+			Action:       servicefile.RelationshipAction(r.Action),
+			Name:         r.Target,
+			Port:         r.Port,
+			Async:        r.Async,
+			Environments: slices.Clone(r.Environments),
+			Deprecated:   r.Deprecated,
+			Discovered:   r.Discovered,
+			Annotations:  maps.Clone(r.Annotations),
+			Source:       b.opts.source(r.Span.Pos),
+		}
+
+		if r.Technology != "" {
+			relationship.Technology = r.Technology
+			relationship.Technologies = slices.Clone(r.Technologies)
+		}
+
+		if r.Description != "" {
+			relationship.Description = r.Description
+		}
+
+		if r.Proto != "" {
+			relationship.Proto = r.Proto
+		}
+
+		relationship.SLA = r.SLA
+		relationship.TimeoutMS = r.TimeoutMS
+
+		if i := indexRelationship(serviceFiles[serviceName].Relationships, relationship); i >= 0 {
+			existing := &serviceFiles[serviceName].Relationships[i]
+			if existing.Description == "" {
+				existing.Description = relationship.Description
+			}
+			for key, value := range relationship.Annotations {
+				if _, exists := existing.Annotations[key]; exists {
+					continue
+				}
+				if existing.Annotations == nil {
+					existing.Annotations = make(map[string]string)
+				}
+				existing.Annotations[key] = value
+			}
+			continue
+		}
+
+		if r.Discovered && hasRelationshipTo(serviceFiles[serviceName].Relationships, relationship.Action, relationship.Name) {
+			continue
+		}
+
+		serviceFiles[serviceName].Relationships = append(serviceFiles[serviceName].Relationships, relationship)
+	}
+
+	if len(serviceFiles) == 0 {
+		return nil, nil, fmt.Errorf("no services found")
+	}
+
+	result := make([]*servicefile.ServiceFile, 0, len(serviceFiles))
+	for _, sf := range serviceFiles {
+		sf.Sort()
+		result = append(result, sf)
+	}
+
+	if opts.MarkExternal {
+		servicefile.MarkExternal(result)
+	}
+
+	return result, b.warnings, nil
+}
+
+// builder holds the state of a single Build.
+type builder struct {
+	services      []Service
+	relationships []Relationship
+	warnings      []Warning
+	opts          Options
+}
+
+// duplicateService reports a service declared again by s, first being its first declaration.
+// It returns an error in strict mode, and records a warning otherwise.
+func (b *builder) duplicateService(first, s Service) error {
+	if b.opts.StrictServices {
+		return fmt.Errorf("service %q is declared more than once: at %s and at %s",
+			s.Name, b.opts.location(first.Pos), b.opts.location(s.Pos))
+	}
+
+	b.warnings = append(b.warnings, b.opts.warning(s.Pos, b.opts.prefix()+"name "+s.Name,
+		fmt.Sprintf("service %q is already declared at %s, definitions are merged", s.Name, b.opts.location(first.Pos))))
+
+	return nil
+}
+
+// checkSelfRelationship reports a relationship r whose target is serviceName, the service it belongs to,
+// which usually means the relationship was attributed to the wrong service.
+// It returns an error in strict mode, and records a warning otherwise.
+func (b *builder) checkSelfRelationship(r Relationship, serviceName string) error {
+	if r.Target != serviceName {
+		return nil
+	}
+
+	w := b.opts.warning(r.Span.Pos, r.Declaration, fmt.Sprintf("relationship targets its own service %q", serviceName))
+	if b.opts.StrictSelfRelationships {
+		return errors.New(w.String())
+	}
+
+	b.warnings = append(b.warnings, w)
+
+	return nil
+}
+
+// caseFoldedNames maps lower-cased names to the casing used for output.
+type caseFoldedNames map[string]string
+
+// canonicalNames collects the output casing of every name that relationships can refer to.
+// Declared services are seen first, so their casing wins over the casing of relationship targets.
+func (b *builder) canonicalNames() caseFoldedNames {
+	names := make(caseFoldedNames)
+	if !b.opts.FoldTargetCase {
+		return names
+	}
+
+	for _, s := range b.services {
+		names.add(s.Name)
+	}
+
+	for _, r := range b.relationships {
+		if r.Service != AllServices {
+			names.add(r.Service)
+		}
+		names.add(r.Target)
+	}
+
+	return names
+}
+
+func (n caseFoldedNames) add(name string) {
+	if name == "" {
+		return
+	}
+
+	key := strings.ToLower(name)
+	if _, exists := n[key]; !exists {
+		n[key] = name
+	}
+}
+
+func (n caseFoldedNames) resolve(name string) string {
+	if canonical, exists := n[strings.ToLower(name)]; exists {
+		return canonical
+	}
+
+	return name
+}
+
+// indexRelationship returns the index of the relationship with the same action, target, technologies, proto,
+// port, mode, environments, deprecation, SLA and timeout, or -1 if there is none. Such relationships are
+// duplicates, annotated more than once. Relationships listing technologies in a different order are not
+// duplicates, the first technology being the main one.
+func indexRelationship(relationships []servicefile.Relationship, relationship servicefile.Relationship) int {
+	for i, r := range relationships {
+		if r.Action == relationship.Action &&
+			r.Name == relationship.Name &&
+			r.Technology == relationship.Technology &&
+			slices.Equal(r.Technologies, relationship.Technologies) &&
+			r.Proto == relationship.Proto &&
+			r.Port == relationship.Port &&
+			r.Async == relationship.Async &&
+			slices.Equal(r.Environments, relationship.Environments) &&
+			r.Deprecated == relationship.Deprecated &&
+			r.SLA == relationship.SLA &&
+			r.TimeoutMS == relationship.TimeoutMS {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hasRelationshipTo(relationships []servicefile.Relationship, action servicefile.RelationshipAction, name string) bool {
+	for _, r := range relationships {
+		if r.Action == action && r.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandPackageRelationships returns the parsed relationships with every package level relationship
+// replaced by a copy for each service declared in the package it was found in.
+func (b *builder) expandPackageRelationships() ([]Relationship, error) {
+	relationships := make([]Relationship, 0, len(b.relationships))
+
+	for _, r := range b.relationships {
+		if r.Service != AllServices {
+			relationships = append(relationships, r)
+			continue
+		}
+
+		var expanded bool
+		for _, s := range b.services {
+			if s.Dir != r.Dir {
+				continue
+			}
+
+			r.Service = s.Name
+			relationships = append(relationships, r)
+			expanded = true
+		}
+
+		if !expanded {
+			return nil, fmt.Errorf("no services declared in %s for package level relationship: %s", r.Dir, r)
+		}
+	}
+
+	return relationships, nil
+}
+
+// checkActions reports relationships with an action that is not one of the actions of the specification,
+// as an error in strict mode and as warnings otherwise.
+func (b *builder) checkActions() error {
+	for _, r := range b.relationships {
+		if servicefile.RelationshipAction(r.Action).IsValid() {
+			continue
+		}
+
+		w := b.opts.warning(r.Span.Pos, r.Declaration, fmt.Sprintf("unknown relationship action %q", r.Action))
+		if b.opts.StrictActions {
+			return errors.New(w.String())
+		}
+
+		b.warnings = append(b.warnings, w)
+	}
+
+	return nil
+}
+
+func (b *builder) validateNoMixedUsage() error {
+	var (
+		hasExplicit bool
+		hasImplicit bool
+	)
+
+	for _, r := range b.relationships {
+		if r.Service == AllServices {
+			continue
+		}
+
+		if r.Service != "" {
+			hasExplicit = true
+		} else {
+			hasImplicit = true
+		}
+	}
+
+	if hasExplicit && hasImplicit {
+		return fmt.Errorf("mixed relationship definition patterns detected: some relationships use explicit patterns (service:name:action) while others use implicit patterns (service:action)")
+	}
+
+	return nil
+}
+
+func (b *builder) determineServiceName(r Relationship, serviceFiles map[string]*servicefile.ServiceFile) (string, error) {
+	if r.Service != "" {
+		return r.Service, nil
+	}
+
+	// Implicit relationships belong to the closest service declared above them in the same file,
+	// then to the service declared in the same package, or to the only service there is.
+	if name, ok := b.precedingService(r.Span.Pos); ok {
+		return name, nil
+	}
+
+	if name, ok := ServiceInDir(b.services, r.Dir); ok {
+		return name, nil
+	}
+
+	if len(serviceFiles) == 1 {
+		for name := range serviceFiles {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no service name found for relationship: %s", r)
+}
+
+// precedingService returns the name of the service declared closest before pos in the same file.
+func (b *builder) precedingService(pos token.Pos) (string, bool) {
+	if b.opts.FileSet == nil {
+		return "", false
+	}
+
+	file := b.opts.FileSet.File(pos)
+	if file == nil {
+		return "", false
+	}
+
+	var (
+		name    string
+		closest token.Pos
+	)
+
+	for _, s := range b.services {
+		if s.Pos >= pos || s.Pos <= closest || b.opts.FileSet.File(s.Pos) != file {
+			continue
+		}
+
+		name, closest = s.Name, s.Pos
+	}
+
+	return name, name != ""
+}
+
+// source returns the location of pos, zero when it is unknown.
+func (o Options) source(pos token.Pos) servicefile.Source {
+	if pos == token.NoPos || o.FileSet == nil {
+		return servicefile.Source{}
+	}
+
+	position := o.FileSet.Position(pos)
+
+	return servicefile.Source{File: position.Filename, Line: position.Line}
+}
+
+// ServiceInDir returns the name of the only service declared in dir.
+func ServiceInDir(services []Service, dir string) (string, bool) {
+	var name string
+
+	for _, s := range services {
+		if s.Dir != dir {
+			continue
+		}
+
+		if name != "" && name != s.Name {
+			return "", false
+		}
+
+		name = s.Name
+	}
+
+	return name, name != ""
+}
+
+// OnlyService returns the name of the service when a single one is declared.
+func OnlyService(services []Service) (string, bool) {
+	var name string
+
+	for _, s := range services {
+		if name != "" && name != s.Name {
+			return "", false
+		}
+
+		name = s.Name
+	}
+
+	return name, name != ""
+}
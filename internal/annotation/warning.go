@@ -0,0 +1,80 @@
+package annotation
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// Warning is an annotation that looks like a service annotation but was ignored.
+type Warning struct {
+	// Path and Line locate the comment of the annotation.
+	// They are empty when the comment was not parsed from a file.
+	Path string
+	Line int
+	// Text is the raw text of the comment.
+	Text string
+	// Message explains why the annotation was ignored.
+	Message string
+}
+
+// String returns the warning prefixed with its location.
+func (w Warning) String() string {
+	if w.Path == "" {
+		return w.Message
+	}
+
+	return fmt.Sprintf("%s:%d: %s", w.Path, w.Line, w.Message)
+}
+
+// warn returns a warning about the comment made of lines.
+func (o Options) warn(lines []Line, message string) Warning {
+	var pos token.Pos
+	if len(lines) > 0 {
+		pos = lines[0].Pos
+	}
+
+	return o.warning(pos, joinLines(lines), message)
+}
+
+// warnAt returns a warning about a line of the comment made of lines, located at that line.
+func (o Options) warnAt(lines []Line, line Line, message string) Warning {
+	return o.warning(line.Pos, joinLines(lines), message)
+}
+
+// joinLines returns the raw text of the comment made of lines.
+func joinLines(lines []Line) string {
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// location returns the file and line of pos, for messages.
+func (o Options) location(pos token.Pos) string {
+	if pos == token.NoPos || o.FileSet == nil {
+		return "an unknown location"
+	}
+
+	position := o.FileSet.Position(pos)
+
+	return fmt.Sprintf("%s:%d", position.Filename, position.Line)
+}
+
+// warning returns a warning about text found at pos.
+func (o Options) warning(pos token.Pos, text, message string) Warning {
+	w := Warning{
+		Text:    text,
+		Message: message,
+	}
+
+	if pos != token.NoPos && o.FileSet != nil {
+		position := o.FileSet.Position(pos)
+		w.Path = position.Filename
+		w.Line = position.Line
+	}
+
+	return w
+}
@@ -12,6 +12,10 @@ func Command() *cobra.Command {
 
 	cmd.AddCommand(
 		commands.Parse(),
+		commands.Generate(),
+		commands.Schema(),
+		commands.Stats(),
+		commands.Lint(),
 	)
 
 	return cmd
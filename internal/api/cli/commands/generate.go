@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/denchenko/servicefile/internal/parser/golang"
+	"github.com/denchenko/servicefile/internal/watch"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+	"github.com/spf13/cobra"
+)
+
+// renderers are the output formats of the generate command.
+var renderers = map[string]func(files []*servicefile.ServiceFile, w io.Writer) error{
+	"yaml":        writeYAML,
+	"json":        writeJSON,
+	"system-yaml": renderWith(servicefile.RenderYAML),
+	"system-json": renderWith(servicefile.RenderJSON),
+	"mermaid":     renderWith(servicefile.RenderMermaid),
+	"dot":         renderWith(servicefile.RenderDOT),
+	"plantuml":    renderWith(servicefile.RenderPlantUML),
+	"d2":          renderWith(servicefile.RenderD2),
+	"structurizr": renderWith(servicefile.RenderStructurizr),
+	"markdown":    renderWith(servicefile.RenderMarkdown),
+	"csv":         renderWith(servicefile.RenderCSV),
+}
+
+func Generate() *cobra.Command {
+	var (
+		dir       string
+		recursive bool
+		format    string
+		out       string
+		watching  bool
+		env       string
+		tmplPath  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Parse servicefiles from source and render them",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			render, ok := renderers[format]
+			if tmplPath != "" {
+				tmpl, err := os.ReadFile(tmplPath)
+				if err != nil {
+					return fmt.Errorf("error reading template: %w", err)
+				}
+				render = func(files []*servicefile.ServiceFile, w io.Writer) error {
+					return servicefile.RenderTemplate(files, string(tmpl), w)
+				}
+			} else if !ok {
+				return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(formats(), ", "))
+			}
+
+			if !watching {
+				return generate(cmd.Context(), dir, recursive, env, render, out, cmd.OutOrStdout(), cmd.ErrOrStderr())
+			}
+
+			// Parse errors are reported without stopping, so that the watch survives intermediate edits.
+			return watch.Watch(cmd.Context(), dir, watch.Options{Recursive: recursive}, func() {
+				if err := generate(cmd.Context(), dir, recursive, env, render, out, cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
+					if cmd.Context().Err() != nil {
+						return
+					}
+					fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", err)
+					return
+				}
+				if out != "" {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s regenerated\n", out)
+				}
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to analyze")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", true, "Recursively analyze subdirectories")
+	cmd.Flags().StringVarP(&format, "format", "f", "yaml", "Output format: "+strings.Join(formats(), ", "))
+	cmd.Flags().StringVarP(&out, "out", "o", "", "Output file path, standard output when empty")
+	cmd.Flags().StringVar(&env, "env", "", "Only render the relationships existing in the environment")
+	cmd.Flags().StringVarP(&tmplPath, "template", "t", "", "Render with the text/template of this file instead of a format")
+	cmd.Flags().BoolVarP(&watching, "watch", "w", false, "Regenerate the output each time Go files change")
+
+	return cmd
+}
+
+// generate parses the service files of dir and writes their rendering to out, or to w when out is empty,
+// parse warnings being written to errw. A new parser is used for every call, as parsers accumulate annotations.
+// Parsing stops once ctx is done. Only the relationships existing in env are rendered, unless env is empty.
+func generate(ctx context.Context, dir string, recursive bool, env string, render func([]*servicefile.ServiceFile, io.Writer) error, out string, w, errw io.Writer) error {
+	parser := golang.NewCommentParser()
+
+	serviceFiles, err := parser.ParseContext(ctx, dir, recursive)
+	if err != nil {
+		return fmt.Errorf("error parsing service files: %w", err)
+	}
+
+	for _, warning := range parser.Warnings() {
+		fmt.Fprintf(errw, "warning: %s\n", warning)
+	}
+
+	if env != "" {
+		serviceFiles = servicefile.FilterByEnvironment(serviceFiles, env)
+	}
+
+	var buf bytes.Buffer
+	if err := render(serviceFiles, &buf); err != nil {
+		return fmt.Errorf("error rendering service files: %w", err)
+	}
+
+	if out == "" {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	if err := servicefile.WriteFileAtomic(out, buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+
+	return nil
+}
+
+// formats returns the sorted names of the output formats.
+func formats() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func renderWith(render func([]*servicefile.ServiceFile, io.Writer, ...servicefile.RenderOption) error) func([]*servicefile.ServiceFile, io.Writer) error {
+	return func(files []*servicefile.ServiceFile, w io.Writer) error {
+		return render(files, w)
+	}
+}
+
+// writeYAML writes the service files as YAML documents, sorted by service name.
+func writeYAML(files []*servicefile.ServiceFile, w io.Writer) error {
+	for i, sf := range sortedByName(files) {
+		data, err := servicefile.MarshalYAML(sf)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeJSON writes the service files as a JSON array sorted by service name, whatever their number.
+func writeJSON(files []*servicefile.ServiceFile, w io.Writer) error {
+	if err := writeJSONValue(w, sortedByName(files)); err != nil {
+		return fmt.Errorf("failed to encode service files: %w", err)
+	}
+
+	return nil
+}
+
+// writeJSONValue writes v as indented JSON.
+func writeJSONValue(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+func sortedByName(files []*servicefile.ServiceFile) []*servicefile.ServiceFile {
+	sorted := make([]*servicefile.ServiceFile, len(files))
+	copy(sorted, files)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Info.Name < sorted[j].Info.Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/denchenko/servicefile/internal/parser/golang"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+	"github.com/spf13/cobra"
+)
+
+func Lint() *cobra.Command {
+	var (
+		dir        string
+		recursive  bool
+		rules      []string
+		severities map[string]string
+		failOn     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Parse servicefiles from source and report gaps in their documentation",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			for _, rule := range rules {
+				if _, ok := servicefile.DefaultLintSeverities()[rule]; !ok {
+					return fmt.Errorf("unknown lint rule %q", rule)
+				}
+			}
+
+			threshold, err := servicefile.ParseSeverity(failOn)
+			if err != nil {
+				return fmt.Errorf("invalid --fail-on: %w", err)
+			}
+
+			overrides := make(map[string]servicefile.Severity, len(severities))
+			for rule, name := range severities {
+				if _, ok := servicefile.DefaultLintSeverities()[rule]; !ok {
+					return fmt.Errorf("unknown lint rule %q", rule)
+				}
+				severity, err := servicefile.ParseSeverity(name)
+				if err != nil {
+					return fmt.Errorf("invalid severity of lint rule %s: %w", rule, err)
+				}
+				overrides[rule] = severity
+			}
+
+			serviceFiles, err := golang.NewCommentParser().ParseContext(cmd.Context(), dir, recursive)
+			if err != nil {
+				return fmt.Errorf("error parsing service files: %w", err)
+			}
+
+			issues := servicefile.Lint(serviceFiles,
+				servicefile.WithLintRules(rules...),
+				servicefile.WithLintSeverities(overrides),
+			)
+			for _, issue := range issues {
+				fmt.Fprintln(cmd.OutOrStdout(), issue)
+			}
+
+			if issues.Fail(threshold) {
+				return fmt.Errorf("lint issues found, the highest being %s", issues.MaxSeverity())
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to analyze")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", true, "Recursively analyze subdirectories")
+	cmd.Flags().StringToStringVar(&severities, "severity", nil, "Severity of lint rules: off, info, warning or error, e.g. service-system=error")
+	cmd.Flags().StringVar(&failOn, "fail-on", "error", "Fail when an issue has this severity or a higher one: info, warning or error, off never failing")
+	cmd.Flags().StringSliceVar(&rules, "rules", nil, "Lint rules to check, all of them when empty: service-description, service-system, relationship-description, relationship-technology")
+
+	return cmd
+}
@@ -2,49 +2,92 @@ package commands
 
 import (
 	"fmt"
+	"go/build"
 	"os"
 	"strings"
 
+	"github.com/denchenko/servicefile/internal/annotation"
 	"github.com/denchenko/servicefile/internal/parser/golang"
 	"github.com/denchenko/servicefile/pkg/servicefile"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 func Parse() *cobra.Command {
 	var (
-		dir       string
-		recursive bool
-		output    string
+		dirs       []string
+		recursive  bool
+		output     string
+		layout     string
+		opts       golang.ParseOptions
+		calls      bool
+		matchBuild bool
+		tags       []string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "parse",
 		Short: "Parse servicefiles from source",
 		RunE: func(_ *cobra.Command, _ []string) error {
-			return parseServiceFiles(dir, recursive, output)
+			if calls {
+				opts.CallSiteRules = golang.DefaultCallSiteRules()
+			}
+			if matchBuild || len(tags) > 0 {
+				ctxt := build.Default
+				ctxt.BuildTags = append(ctxt.BuildTags, tags...)
+				opts.BuildContext = &ctxt
+			}
+
+			return parseServiceFiles(dirs, recursive, output, layout, golang.WithParseOptions(opts))
 		},
 	}
 
-	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to analyze")
+	cmd.Flags().StringSliceVarP(&dirs, "dir", "d", []string{"."}, "Directories to analyze, as a single tree")
 	cmd.Flags().BoolVarP(&recursive, "recursive", "r", true, "Recursively analyze subdirectories")
 	cmd.Flags().StringVarP(&output, "output", "o", "servicefile.yaml", "Output file path suffix for YAML")
+	cmd.Flags().StringVar(&layout, "layout", "", "Path pattern of the file of each service, such as {system}/{name}.yaml, overriding --output")
+	cmd.Flags().BoolVar(&opts.AllDirs, "all-dirs", false, "Also analyze vendor, node_modules and hidden directories")
+	cmd.Flags().BoolVar(&opts.FollowSymlinks, "follow-symlinks", false, "Also analyze the directories symbolic links point to")
+	cmd.Flags().BoolVar(&opts.IncludeTests, "include-tests", false, "Also analyze _test.go files")
+	cmd.Flags().BoolVar(&opts.StrictActions, "strict", false, "Fail on relationships with an unknown action")
+	cmd.Flags().BoolVar(&opts.StrictServices, "strict-services", false, "Fail on services declared more than once instead of merging them")
+	cmd.Flags().BoolVar(&opts.StrictSelfRelationships, "strict-self", false, "Fail on relationships targeting the service they belong to")
+	cmd.Flags().BoolVar(&opts.StrictProtos, "strict-protos", false, "Warn about protos that are not one of the canonical protos")
+	cmd.Flags().BoolVar(&calls, "discover-calls", false, "Experimental: also discover relationships from calls to common client constructors")
+	cmd.Flags().BoolVar(&matchBuild, "match-build", false, "Skip files whose build constraints don't match GOOS, GOARCH and --tags")
+	cmd.Flags().StringSliceVar(&tags, "tags", nil, "Build tags satisfied when matching build constraints, implies --match-build")
+	cmd.Flags().BoolVar(&opts.MarkExternal, "mark-external", false, "Mark relationships whose target is not one of the parsed services as external")
+	cmd.Flags().StringVar(&opts.Prefix, "prefix", annotation.DefaultPrefix, "Marker starting service annotations")
+	cmd.Flags().StringSliceVar(&opts.Excludes, "exclude", nil, "Glob patterns of files and directories to skip, relative to the analyzed directory")
 
 	return cmd
 }
 
-func parseServiceFiles(dir string, recursive bool, output string) error {
-	parser := golang.NewCommentParser()
+func parseServiceFiles(dirs []string, recursive bool, output, layout string, opts ...golang.Option) error {
+	parser := golang.NewCommentParser(opts...)
 
-	serviceFiles, err := parser.Parse(dir, recursive)
+	serviceFiles, err := parser.ParseDirs(dirs, recursive)
 	if err != nil {
 		return fmt.Errorf("error parsing service file: %w", err)
 	}
 
+	for _, warning := range parser.Warnings() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+
 	if len(serviceFiles) == 0 {
 		return fmt.Errorf("no services found in the specified directory")
 	}
 
+	if layout != "" {
+		if err := servicefile.WriteServiceFiles(serviceFiles, ".", layout); err != nil {
+			return fmt.Errorf("error saving service files: %w", err)
+		}
+
+		fmt.Printf("%d ServiceFiles generated and saved following: %s\n", len(serviceFiles), layout)
+
+		return nil
+	}
+
 	if len(serviceFiles) == 1 {
 		sf := serviceFiles[0]
 
@@ -71,7 +114,7 @@ func parseServiceFiles(dir string, recursive bool, output string) error {
 }
 
 func saveServiceFileToYAML(sf *servicefile.ServiceFile, filepath string) error {
-	yamlData, err := yaml.Marshal(sf)
+	yamlData, err := servicefile.MarshalYAML(sf)
 	if err != nil {
 		return fmt.Errorf("error marshaling to YAML: %w", err)
 	}
@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+	"github.com/spf13/cobra"
+)
+
+func Schema() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of servicefiles",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			schema, err := servicefile.Schema()
+			if err != nil {
+				return fmt.Errorf("error generating schema: %w", err)
+			}
+
+			_, err = cmd.OutOrStdout().Write(schema)
+			return err
+		},
+	}
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/denchenko/servicefile/internal/parser/golang"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+	"github.com/spf13/cobra"
+)
+
+func Stats() *cobra.Command {
+	var (
+		dir       string
+		recursive bool
+		format    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Parse servicefiles from source and print statistics about them",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != "text" && format != "json" {
+				return fmt.Errorf("unknown format %q, expected one of json, text", format)
+			}
+
+			serviceFiles, err := golang.NewCommentParser().ParseContext(cmd.Context(), dir, recursive)
+			if err != nil {
+				return fmt.Errorf("error parsing service files: %w", err)
+			}
+
+			stats := servicefile.Summary(serviceFiles)
+			if format == "json" {
+				return writeJSONValue(cmd.OutOrStdout(), stats)
+			}
+
+			_, err = fmt.Fprint(cmd.OutOrStdout(), stats)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", ".", "Directory to analyze")
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", true, "Recursively analyze subdirectories")
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "Output format: json, text")
+
+	return cmd
+}
@@ -0,0 +1,117 @@
+package golang
+
+import (
+	"go/ast"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// DefaultBlankImportTechnologies returns the technologies of common drivers registered
+// through blank imports, keyed by package name.
+func DefaultBlankImportTechnologies() map[string]string {
+	return map[string]string{
+		"pq":         "postgresql",
+		"pgx":        "postgresql",
+		"mysql":      "mysql",
+		"sqlite3":    "sqlite",
+		"sqlite":     "sqlite",
+		"clickhouse": "clickhouse",
+		"mssql":      "mssql",
+		"godror":     "oracle",
+	}
+}
+
+// WithBlankImports enables discovery of uses relationships from blank imports of non standard
+// library packages, which usually register a driver the service depends on at runtime.
+// The target of the relationship is the package name. Its technology is looked up in technologies
+// by package name, DefaultBlankImportTechnologies being used when technologies is nil.
+func WithBlankImports(technologies map[string]string) Option {
+	return func(cp *CommentParser) {
+		if technologies == nil {
+			technologies = DefaultBlankImportTechnologies()
+		}
+		cp.opts.BlankImportTechnologies = technologies
+	}
+}
+
+// blankImport is a package imported for its side effects only.
+type blankImport struct {
+	dir        string
+	importPath string
+	span       annotation.Span
+}
+
+func (cp *CommentParser) collectBlankImports(found *annotations, dir string, f *ast.File) {
+	for _, spec := range f.Imports {
+		if spec.Name == nil || spec.Name.Name != "_" {
+			continue
+		}
+
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || isStandardLibrary(importPath) {
+			continue
+		}
+
+		found.blankImports = append(found.blankImports, blankImport{
+			dir:        dir,
+			importPath: importPath,
+			span:       annotation.Span{Pos: spec.Pos(), End: spec.End()},
+		})
+	}
+}
+
+// resolveBlankImports turns collected blank imports into uses relationships of the service
+// declared in the importing package, or of the only service there is when that package declares none.
+func (cp *CommentParser) resolveBlankImports() []annotation.Relationship {
+	relationships := make([]annotation.Relationship, 0, len(cp.blankImports))
+
+	for _, imp := range cp.blankImports {
+		source, ok := annotation.ServiceInDir(cp.services, imp.dir)
+		if !ok {
+			source, ok = annotation.OnlyService(cp.services)
+		}
+		if !ok {
+			continue
+		}
+
+		target, technology := cp.blankImportTarget(imp.importPath)
+
+		relationships = append(relationships, annotation.Relationship{
+			Service:    source,
+			Action:     string(servicefile.RelationshipActionUses),
+			Target:     target,
+			Technology: technology,
+			Dir:        imp.dir,
+			Discovered: true,
+			Span:       imp.span,
+		})
+	}
+
+	return relationships
+}
+
+// blankImportTarget returns the package name of importPath and its technology.
+// Path elements are looked up from the last one, so that drivers living in a sub-package,
+// like github.com/jackc/pgx/v5/stdlib, are matched by the name of their module.
+func (cp *CommentParser) blankImportTarget(importPath string) (target, technology string) {
+	for p := importPath; p != "." && p != "/"; p = path.Dir(p) {
+		name := importName(p)
+		if technology, ok := cp.opts.BlankImportTechnologies[name]; ok {
+			return name, technology
+		}
+	}
+
+	return importName(importPath), ""
+}
+
+// isStandardLibrary reports whether importPath is a standard library package,
+// whose first path element, unlike module paths, has no dot.
+func isStandardLibrary(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,54 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WithBuildContext makes Parse skip the files whose build constraints, //go:build lines and
+// _GOOS or _GOARCH file name suffixes, aren't satisfied by ctxt, as the go command would,
+// build.Default being used when ctxt is nil. Every file is parsed by default.
+func WithBuildContext(ctxt *build.Context) Option {
+	return func(cp *CommentParser) {
+		if ctxt == nil {
+			ctxt = &build.Default
+		}
+		c := *ctxt
+		cp.opts.BuildContext = &c
+	}
+}
+
+// matchesBuildContext reports whether the file at path, whose content is src, is part of the build
+// described by the build context. The file is read from path when src is nil.
+func (cp *CommentParser) matchesBuildContext(path string, src any) (bool, []byte, error) {
+	var content []byte
+	switch s := src.(type) {
+	case nil:
+		var err error
+		if content, err = os.ReadFile(path); err != nil {
+			return false, nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	case []byte:
+		content = s
+	case string:
+		content = []byte(s)
+	default:
+		return false, nil, fmt.Errorf("unsupported source of %s: %T", path, src)
+	}
+
+	ctxt := *cp.opts.BuildContext
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+
+	match, err := ctxt.MatchFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to evaluate the build constraints of %s: %w", path, err)
+	}
+
+	return match, content, nil
+}
@@ -0,0 +1,192 @@
+package golang
+
+import (
+	"go/ast"
+	"slices"
+	"strconv"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// CallSiteRule describes calls to a client constructor that reveal a dependency of the calling service.
+type CallSiteRule struct {
+	// Packages are the import paths of the client package.
+	Packages []string
+	// Functions lists the constructors of the package.
+	Functions []string
+	// Action is the action of the discovered relationships.
+	Action servicefile.RelationshipAction
+	// Target is the name of the discovered relationships, Technology when empty.
+	Target string
+	// Technology is the technology of the discovered relationships.
+	Technology string
+	// Proto is the proto of the discovered relationships.
+	Proto string
+}
+
+// DefaultCallSiteRules returns the rules matching the constructors of common clients.
+func DefaultCallSiteRules() []CallSiteRule {
+	return []CallSiteRule{
+		{
+			Packages:   []string{"database/sql"},
+			Functions:  []string{"Open", "OpenDB"},
+			Action:     servicefile.RelationshipActionUses,
+			Target:     "Database",
+			Technology: "sql",
+			Proto:      "tcp",
+		},
+		{
+			Packages:   []string{"github.com/redis/go-redis/v9", "github.com/go-redis/redis/v8", "github.com/go-redis/redis"},
+			Functions:  []string{"NewClient", "NewClusterClient", "NewFailoverClient", "NewUniversalClient"},
+			Action:     servicefile.RelationshipActionUses,
+			Target:     "Redis",
+			Technology: "redis",
+			Proto:      "tcp",
+		},
+		{
+			Packages:   []string{"github.com/segmentio/kafka-go"},
+			Functions:  []string{"NewWriter"},
+			Action:     servicefile.RelationshipActionSends,
+			Target:     "Kafka",
+			Technology: "kafka",
+			Proto:      "kafka",
+		},
+		{
+			Packages:   []string{"github.com/segmentio/kafka-go"},
+			Functions:  []string{"NewReader"},
+			Action:     servicefile.RelationshipActionReceives,
+			Target:     "Kafka",
+			Technology: "kafka",
+			Proto:      "kafka",
+		},
+		{
+			Packages:   []string{"net/http"},
+			Functions:  []string{"NewRequest", "NewRequestWithContext", "Get", "Post", "PostForm", "Head"},
+			Action:     servicefile.RelationshipActionRequests,
+			Target:     "HTTP",
+			Technology: "http",
+			Proto:      "http",
+		},
+		{
+			Packages:   []string{"google.golang.org/grpc"},
+			Functions:  []string{"Dial", "DialContext", "NewClient"},
+			Action:     servicefile.RelationshipActionRequests,
+			Target:     "gRPC",
+			Technology: "grpc",
+			Proto:      "grpc",
+		},
+		{
+			Packages:   []string{"github.com/rabbitmq/amqp091-go", "github.com/streadway/amqp"},
+			Functions:  []string{"Dial", "DialConfig", "DialTLS"},
+			Action:     servicefile.RelationshipActionUses,
+			Target:     "RabbitMQ",
+			Technology: "rabbitmq",
+			Proto:      "amqp",
+		},
+	}
+}
+
+// WithCallSites enables the experimental discovery of relationships from calls to client constructors,
+// such as sql.Open or redis.NewClient, by the services. Discovered relationships are marked Discovered
+// and only supplement annotated ones: they are left out when the service already has a relationship
+// with the same action and target. DefaultCallSiteRules are used when rules is nil.
+func WithCallSites(rules []CallSiteRule) Option {
+	return func(cp *CommentParser) {
+		if rules == nil {
+			rules = DefaultCallSiteRules()
+		}
+		cp.opts.CallSiteRules = rules
+	}
+}
+
+// callSite is a call to a client constructor.
+type callSite struct {
+	dir  string
+	rule CallSiteRule
+	span annotation.Span
+}
+
+func (cp *CommentParser) collectCallSites(found *annotations, dir string, f *ast.File) {
+	imports := make(map[string]string, len(f.Imports))
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		name := importName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	seen := make(map[int]bool)
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		for i, rule := range cp.opts.CallSiteRules {
+			if seen[i] || !slices.Contains(rule.Packages, imports[pkg.Name]) || !slices.Contains(rule.Functions, sel.Sel.Name) {
+				continue
+			}
+
+			seen[i] = true
+			found.callSites = append(found.callSites, callSite{
+				dir:  dir,
+				rule: rule,
+				span: annotation.Span{Pos: call.Pos(), End: call.End()},
+			})
+		}
+
+		return true
+	})
+}
+
+// resolveCallSites turns collected call sites into relationships of the service declared in the calling
+// package, or of the only service there is when that package declares none.
+func (cp *CommentParser) resolveCallSites() []annotation.Relationship {
+	relationships := make([]annotation.Relationship, 0, len(cp.callSites))
+
+	for _, c := range cp.callSites {
+		source, ok := annotation.ServiceInDir(cp.services, c.dir)
+		if !ok {
+			source, ok = annotation.OnlyService(cp.services)
+		}
+		if !ok {
+			continue
+		}
+
+		target := c.rule.Target
+		if target == "" {
+			target = c.rule.Technology
+		}
+
+		relationships = append(relationships, annotation.Relationship{
+			Service:    source,
+			Action:     string(c.rule.Action),
+			Target:     target,
+			Technology: c.rule.Technology,
+			Proto:      c.rule.Proto,
+			Dir:        c.dir,
+			Discovered: true,
+			Span:       c.span,
+		})
+	}
+
+	return relationships
+}
@@ -0,0 +1,159 @@
+package golang
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// GoComments writes the annotation comments describing the service file, annotations starting with prefix,
+// annotation.DefaultPrefix when empty, as WithPrefix sets it for parsing.
+// The service definition and each of its relationships are written as separate comment groups,
+// relationships using the explicit {prefix}{service_name}:{action} form so that the output
+// can be pasted into any file of the codebase.
+// An error is returned when the service file can't be written as comments parsed back into the same
+// service file, such as a service name containing spaces or a technology of a list containing a comma.
+func GoComments(sf *servicefile.ServiceFile, prefix string, w io.Writer) error {
+	if prefix == "" {
+		prefix = annotation.DefaultPrefix
+	}
+
+	if err := checkGoComments(sf); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	sorted := sf.Clone()
+	sorted.Sort()
+
+	fmt.Fprintf(bw, "// %sname %s\n", prefix, sf.Info.Name)
+	writeCommentAttribute(bw, "description", quoteCommentValue(sf.Info.Description))
+	writeCommentAttribute(bw, "alias", quoteCommentValue(sf.Info.Alias))
+	writeCommentAttribute(bw, "system", quoteCommentValue(sf.Info.System))
+	writeCommentAttribute(bw, "owner", quoteCommentValue(sf.Info.Owner))
+	writeCommentAttribute(bw, "tags", strings.Join(sorted.Info.Tags, ", "))
+	writeCommentAnnotations(bw, sf.Info.Annotations)
+
+	for _, rel := range sorted.Relationships {
+		fmt.Fprintf(bw, "\n// %s%s:%s", prefix, sf.Info.Name, rel.Action)
+		if rel.Name != "" {
+			fmt.Fprintf(bw, " %s", rel.Name)
+		}
+		if rel.SLA != "" {
+			fmt.Fprintf(bw, " sla=%s", rel.SLA)
+		}
+		if rel.TimeoutMS != 0 {
+			fmt.Fprintf(bw, " timeout=%dms", rel.TimeoutMS)
+		}
+		fmt.Fprintln(bw)
+
+		writeCommentAttribute(bw, "description", quoteCommentValue(rel.Description))
+		if technologies := rel.AllTechnologies(); len(technologies) == 1 {
+			writeCommentAttribute(bw, "technology", quoteCommentValue(technologies[0]))
+		} else {
+			writeCommentAttribute(bw, "technology", strings.Join(technologies, ", "))
+		}
+		writeCommentAttribute(bw, "proto", quoteCommentValue(rel.Proto))
+		if rel.Port != 0 {
+			writeCommentAttribute(bw, "port", strconv.Itoa(rel.Port))
+		}
+		if rel.Async {
+			writeCommentAttribute(bw, "async", "true")
+		}
+		writeCommentAttribute(bw, "env", strings.Join(rel.Environments, ", "))
+		if rel.Deprecated {
+			writeCommentAttribute(bw, "deprecated", "true")
+		}
+		writeCommentAnnotations(bw, rel.Annotations)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write comments: %w", err)
+	}
+
+	return nil
+}
+
+// checkGoComments reports the names and values of the service file that GoComments can't write
+// so that they are parsed back unchanged.
+func checkGoComments(sf *servicefile.ServiceFile) error {
+	if sf.Info.Name == "" || strings.ContainsAny(sf.Info.Name, " \t\n:") {
+		return fmt.Errorf("service name %q can't be written as comments: it must be a single word without colons", sf.Info.Name)
+	}
+
+	values := map[string]string{
+		"description": sf.Info.Description,
+		"alias":       sf.Info.Alias,
+		"system":      sf.Info.System,
+		"owner":       sf.Info.Owner,
+	}
+	lists := map[string][]string{"tags": sf.Info.Tags}
+
+	for _, rel := range sf.Relationships {
+		if rel.Action == "" || strings.ContainsAny(string(rel.Action), " \t\n:") {
+			return fmt.Errorf("action %q of service %s can't be written as comments: it must be a single word without colons", rel.Action, sf.Info.Name)
+		}
+		if strings.Contains(rel.Name, "\n") {
+			return fmt.Errorf("target %q of service %s can't be written as comments: it spans several lines", rel.Name, sf.Info.Name)
+		}
+
+		values["description of "+string(rel.Action)+" "+rel.Name] = rel.Description
+		values["proto of "+string(rel.Action)+" "+rel.Name] = rel.Proto
+		if technologies := rel.AllTechnologies(); len(technologies) == 1 {
+			values["technology of "+string(rel.Action)+" "+rel.Name] = technologies[0]
+		} else {
+			lists["technologies of "+string(rel.Action)+" "+rel.Name] = technologies
+		}
+		lists["environments of "+string(rel.Action)+" "+rel.Name] = rel.Environments
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		value := values[name]
+		if strings.Contains(value, "\n") || strings.HasPrefix(value, `"`) ||
+			strings.Contains(value, `"`) && strings.ContainsAny(value, ",:") {
+			return fmt.Errorf("%s of service %s can't be written as comments: %q would not be parsed back verbatim", name, sf.Info.Name, value)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(lists)) {
+		for _, item := range lists[name] {
+			if strings.ContainsAny(item, ",\n") {
+				return fmt.Errorf("%s of service %s can't be written as comments: %q would be split at its comma", name, sf.Info.Name, item)
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeCommentAnnotations(w io.Writer, annotations map[string]string) {
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		fmt.Fprintf(w, "// %s: %s\n", key, annotations[key])
+	}
+}
+
+func writeCommentAttribute(w io.Writer, key, value string) {
+	if value == "" {
+		return
+	}
+
+	fmt.Fprintf(w, "// %s: %s\n", key, value)
+}
+
+// quoteCommentValue returns value quoted when it contains commas or colons, so that it is parsed back verbatim
+// rather than split into several technologies. checkGoComments rejects the values that can't be quoted.
+func quoteCommentValue(value string) string {
+	if !strings.ContainsAny(value, ",:") {
+		return value
+	}
+
+	return `"` + value + `"`
+}
@@ -0,0 +1,242 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+func TestGoComments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		dir    string
+		prefix string
+	}{
+		{
+			name: "round trip implicit relationships",
+			dir:  "testdata/default",
+		},
+		{
+			name: "round trip explicit relationships",
+			dir:  "testdata/explicit",
+		},
+		{
+			name: "round trip relationship sla and timeout",
+			dir:  "testdata/sla/valid",
+		},
+		{
+			name: "round trip deprecated relationships",
+			dir:  "testdata/deprecated",
+		},
+		{
+			name: "round trip service alias",
+			dir:  "testdata/alias",
+		},
+		{
+			name:   "round trip custom prefix",
+			dir:    "testdata/prefix",
+			prefix: "arch:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := NewCommentParser(WithPrefix(tt.prefix)).Parse(tt.dir, true)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			tmpDir := t.TempDir()
+			for i, sf := range parsed {
+				var buf bytes.Buffer
+				fmt.Fprintf(&buf, "package generated\n\n")
+
+				if err := GoComments(sf, tt.prefix, &buf); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				path := filepath.Join(tmpDir, fmt.Sprintf("service%d.go", i))
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			reparsed, err := NewCommentParser(WithPrefix(tt.prefix)).Parse(tmpDir, true)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !compareServiceFiles(serviceFilesByName(reparsed), serviceFilesByName(parsed)) {
+				t.Errorf("GoComments() round trip = %+v, want %+v", reparsed, parsed)
+			}
+		})
+	}
+}
+
+func TestGoCommentsOutput(t *testing.T) {
+	t.Parallel()
+
+	sf := &servicefile.ServiceFile{
+		Version: servicefile.Version,
+		Info: servicefile.Info{
+			Name:        "Example",
+			Description: "Example service for exampling stuff.",
+			System:      "examples",
+		},
+		Relationships: []servicefile.Relationship{
+			{
+				Action:     servicefile.RelationshipActionUses,
+				Name:       "PostgreSQL",
+				Technology: "postgresql",
+				Proto:      "tcp",
+			},
+			{
+				Action:      servicefile.RelationshipActionReplies,
+				Description: "Provides APIs",
+				Technology:  "grpc-server",
+			},
+		},
+	}
+
+	expected := `// service:name Example
+// description: Example service for exampling stuff.
+// system: examples
+
+// service:Example:replies
+// description: Provides APIs
+// technology: grpc-server
+
+// service:Example:uses PostgreSQL
+// technology: postgresql
+// proto: tcp
+`
+
+	var buf bytes.Buffer
+	if err := GoComments(sf, "", &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if buf.String() != expected {
+		t.Errorf("GoComments() = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestGoCommentsQuotedValues(t *testing.T) {
+	t.Parallel()
+
+	sf := &servicefile.ServiceFile{
+		Version: servicefile.Version,
+		Info: servicefile.Info{
+			Name:        "Events",
+			Description: "Publishes events: orders, refunds",
+		},
+		Relationships: []servicefile.Relationship{
+			{
+				Action:     servicefile.RelationshipActionSends,
+				Name:       "Kafka",
+				Technology: "kafka, avro",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package events\n\n")
+
+	if err := GoComments(sf, "", &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	parser := NewCommentParser()
+	if err := parser.ParseSource("events.go", buf.String()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, err := parser.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || len(result[0].Relationships) != 1 {
+		t.Fatalf("Build() = %+v, want a single service with a single relationship", result)
+	}
+
+	if got := result[0].Info.Description; got != sf.Info.Description {
+		t.Errorf("Info.Description = %q, want %q", got, sf.Info.Description)
+	}
+
+	rel := result[0].Relationships[0]
+	if rel.Technology != "kafka, avro" || rel.Technologies != nil {
+		t.Errorf("Relationship technologies = %q %q, want the single technology %q", rel.Technology, rel.Technologies, "kafka, avro")
+	}
+}
+
+func TestGoCommentsErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		sf       *servicefile.ServiceFile
+		expected string
+	}{
+		{
+			name:     "service name with spaces",
+			sf:       &servicefile.ServiceFile{Info: servicefile.Info{Name: "Order Service"}},
+			expected: `service name "Order Service" can't be written as comments`,
+		},
+		{
+			name:     "service name with colon",
+			sf:       &servicefile.ServiceFile{Info: servicefile.Info{Name: "orders:v2"}},
+			expected: `service name "orders:v2" can't be written as comments`,
+		},
+		{
+			name: "technology of a list with comma",
+			sf: &servicefile.ServiceFile{
+				Info: servicefile.Info{Name: "Events"},
+				Relationships: []servicefile.Relationship{
+					{
+						Action:       servicefile.RelationshipActionSends,
+						Name:         "Kafka",
+						Technology:   "kafka, avro",
+						Technologies: []string{"kafka, avro", "protobuf"},
+					},
+				},
+			},
+			expected: `technologies of sends Kafka of service Events can't be written as comments: "kafka, avro" would be split at its comma`,
+		},
+		{
+			name: "quoted value with colon",
+			sf: &servicefile.ServiceFile{
+				Info: servicefile.Info{Name: "Events", Description: `Publishes "events": orders`},
+			},
+			expected: `description of service Events can't be written as comments`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := GoComments(tt.sf, "", &buf)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("GoComments() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func serviceFilesByName(files []*servicefile.ServiceFile) map[string]*servicefile.ServiceFile {
+	byName := make(map[string]*servicefile.ServiceFile, len(files))
+	for _, sf := range files {
+		byName[sf.Info.Name] = sf
+	}
+
+	return byName
+}
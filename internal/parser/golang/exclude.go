@@ -0,0 +1,80 @@
+package golang
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// WithExcludes makes Parse skip the files and directories matching any of the patterns.
+// Patterns use filepath.Match syntax and are matched against slash separated paths relative
+// to the parsed directory. A pattern starting with **/ matches at any depth, and a pattern
+// ending with / only matches directories, whose whole subtree is skipped.
+// Example: **/mocks/ and **/*.pb.go
+func WithExcludes(patterns ...string) Option {
+	return func(cp *CommentParser) {
+		cp.opts.Excludes = append(cp.opts.Excludes, patterns...)
+	}
+}
+
+// WithAllDirs makes Parse walk the vendor, node_modules, .git and hidden directories it skips by default,
+// for codebases vendoring annotated services.
+func WithAllDirs() Option {
+	return func(cp *CommentParser) {
+		cp.opts.AllDirs = true
+	}
+}
+
+// SkippedDir reports whether a directory is skipped by default: third-party code and hidden directories.
+// Tools walking the parsed directories, such as the watch command, use it to skip the same ones.
+func SkippedDir(name string) bool {
+	switch name {
+	case "vendor", "node_modules":
+		return true
+	default:
+		return strings.HasPrefix(name, ".")
+	}
+}
+
+// excluded reports whether path, found while walking root, matches an exclude pattern.
+func (cp *CommentParser) excluded(root, path string, isDir bool) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range cp.opts.Excludes {
+		if matchExclude(pattern, rel, isDir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchExclude(pattern, rel string, isDir bool) bool {
+	pattern = filepath.ToSlash(pattern)
+
+	pattern, dirOnly := strings.CutSuffix(pattern, "/")
+	if dirOnly && !isDir {
+		return false
+	}
+
+	pattern, anyDepth := strings.CutPrefix(pattern, "**/")
+
+	for {
+		if matched, err := filepath.Match(pattern, rel); err == nil && matched {
+			return true
+		}
+
+		if !anyDepth {
+			return false
+		}
+
+		_, rest, ok := strings.Cut(rel, "/")
+		if !ok {
+			return false
+		}
+		rel = rest
+	}
+}
@@ -1,369 +1,661 @@
 package golang
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/denchenko/servicefile/internal/annotation"
 	"github.com/denchenko/servicefile/pkg/servicefile"
 )
 
+// CommentParser extracts service files from annotations in Go source comments.
+//
+// A CommentParser accumulates annotations across calls, and is safe for concurrent use:
+// files are parsed independently and their annotations are merged under a lock,
+// so several goroutines may parse files with the same parser before service files are built.
 type CommentParser struct {
-	services      []service
-	relationships []relationship
+	mu sync.Mutex
+
+	services      []annotation.Service
+	relationships []annotation.Relationship
+	injections    []injection
+	blankImports  []blankImport
+	callSites     []callSite
+	warnings      []Warning
+	buildWarnings []Warning
+	roots         []string
+	fset          *token.FileSet
+
+	opts ParseOptions
 }
 
-func NewCommentParser() *CommentParser {
-	return &CommentParser{
-		services:      make([]service, 0),
-		relationships: make([]relationship, 0),
-	}
+// ParseOptions are the settings of a CommentParser. Each of them is also set by an Option,
+// see WithParseOptions to set them all at once. The zero value keeps every default behavior.
+type ParseOptions struct {
+	// FoldTargetCase compares targets and service names case-insensitively, see WithTargetCaseFolding.
+	FoldTargetCase bool
+	// CollectUnknown keeps unknown keys as annotations, see WithCollectUnknown.
+	CollectUnknown bool
+	// InjectionRules discover relationships from dependency injection calls, see WithInjectionRules.
+	InjectionRules []InjectionRule
+	// BlankImportTechnologies discover relationships from blank imports when not nil, see WithBlankImports.
+	BlankImportTechnologies map[string]string
+	// ImportTechnologies infer technologies from imports when not nil, see WithImportTechnologies.
+	ImportTechnologies map[string]string
+	// CallSiteRules discover relationships from client constructor calls, see WithCallSites.
+	CallSiteRules []CallSiteRule
+	// BuildContext skips the files it leaves out when not nil, see WithBuildContext.
+	BuildContext *build.Context
+	// Workers is the number of files parsed concurrently, GOMAXPROCS when zero, see WithWorkers.
+	Workers int
+	// Excludes are the patterns of the files and directories to skip, see WithExcludes.
+	Excludes []string
+	// AllDirs also walks the directories skipped by default, see WithAllDirs.
+	AllDirs bool
+	// IncludeTests also parses _test.go files, see WithTests.
+	IncludeTests bool
+	// StrictActions fails on unknown actions, see WithStrictActions.
+	StrictActions bool
+	// StrictServices fails on services declared more than once, see WithStrictServices.
+	StrictServices bool
+	// StrictSelfRelationships fails on relationships targeting their own service, see WithStrictSelfRelationships.
+	StrictSelfRelationships bool
+	// StrictProtos warns about protos that are not canonical, see WithStrictProtos.
+	StrictProtos bool
+	// MarkExternal sets External on relationships to targets that are not services, see WithExternalTargets.
+	MarkExternal bool
+	// FollowSymlinks walks the directories symbolic links point to, see WithFollowSymlinks.
+	FollowSymlinks bool
+	// Prefix is the marker starting annotations, annotation.DefaultPrefix when empty, see WithPrefix.
+	Prefix string
 }
 
-func (cp *CommentParser) Parse(dir string, recursive bool) ([]*servicefile.ServiceFile, error) {
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("failed to walk the path: %w", err)
-		}
+// Option configures a CommentParser.
+type Option func(*CommentParser)
 
-		if info.IsDir() && !recursive && path != dir {
-			return filepath.SkipDir
-		}
+// WithParseOptions replaces every setting of the parser by opts, for callers assembling them
+// from configuration. Options following it still apply.
+func WithParseOptions(opts ParseOptions) Option {
+	return func(cp *CommentParser) {
+		cp.opts = opts
+	}
+}
 
-		if info.IsDir() {
-			return nil
-		}
+// WithTargetCaseFolding makes the parser compare relationship targets and service names
+// case-insensitively, so that Kafka and kafka are treated as the same component.
+// The first seen casing is kept for output, declared service names taking precedence.
+func WithTargetCaseFolding() Option {
+	return func(cp *CommentParser) {
+		cp.opts.FoldTargetCase = true
+	}
+}
 
-		if !strings.HasSuffix(path, ".go") {
-			return nil
-		}
+// WithCollectUnknown makes the parser keep "key: value" lines of annotations that don't match
+// any known key as annotations of the service or relationship they belong to.
+func WithCollectUnknown() Option {
+	return func(cp *CommentParser) {
+		cp.opts.CollectUnknown = true
+	}
+}
 
-		if err := cp.parseFile(path); err != nil {
-			return fmt.Errorf("failed to parse %s: %w", path, err)
-		}
+// WithWorkers sets the number of files Parse parses concurrently, GOMAXPROCS by default.
+// A single worker parses files one after the other.
+func WithWorkers(n int) Option {
+	return func(cp *CommentParser) {
+		cp.opts.Workers = max(n, 1)
+	}
+}
 
-		return nil
-	})
+// WithTests makes Parse parse _test.go files, which are skipped by default
+// as their example comments could be taken for annotations.
+func WithTests() Option {
+	return func(cp *CommentParser) {
+		cp.opts.IncludeTests = true
+	}
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("error walking the path: %w", err)
+// WithFollowSymlinks makes Parse walk the directories symbolic links point to, which it skips by default.
+// Every real directory is walked once, so that links to parent directories don't make Parse loop.
+func WithFollowSymlinks() Option {
+	return func(cp *CommentParser) {
+		cp.opts.FollowSymlinks = true
 	}
+}
 
-	return cp.buildServiceFiles()
+// WithStrictActions makes Parse fail on relationships whose action is not one of the actions
+// of the specification, instead of keeping them with a warning.
+func WithStrictActions() Option {
+	return func(cp *CommentParser) {
+		cp.opts.StrictActions = true
+	}
 }
 
-type service struct {
-	name        string
-	description string
-	system      string
+// WithStrictServices makes Parse fail on services declared more than once, naming both declarations,
+// instead of merging their definitions with a warning, the first non-empty fields winning.
+func WithStrictServices() Option {
+	return func(cp *CommentParser) {
+		cp.opts.StrictServices = true
+	}
 }
 
-func (s service) String() string {
-	return fmt.Sprintf("name: %s, description: %s, system: %s",
-		s.name,
-		s.description,
-		s.system,
-	)
+// WithStrictSelfRelationships makes Parse fail on relationships targeting the service they belong to,
+// instead of keeping them with a warning.
+func WithStrictSelfRelationships() Option {
+	return func(cp *CommentParser) {
+		cp.opts.StrictSelfRelationships = true
+	}
 }
 
-type relationship struct {
-	serviceName string
-	action      string
-	targetName  string
-	technology  string
-	description string
-	proto       string
+// WithStrictProtos makes Parse warn about protos that are not one of servicefile.CanonicalProtos
+// once normalized. Protos are normalized and kept either way.
+func WithStrictProtos() Option {
+	return func(cp *CommentParser) {
+		cp.opts.StrictProtos = true
+	}
 }
 
-func (r relationship) String() string {
-	return fmt.Sprintf("service_name: %s, action: %s, target_name: %s, technology: %s, proto: %s, description: %s",
-		r.serviceName,
-		r.action,
-		r.targetName,
-		r.technology,
-		r.proto,
-		r.description,
-	)
+// WithExternalTargets makes Parse set External on relationships whose target is not one
+// of the parsed services, once every service is known.
+func WithExternalTargets() Option {
+	return func(cp *CommentParser) {
+		cp.opts.MarkExternal = true
+	}
 }
 
-func (cp *CommentParser) parseFile(path string) error {
-	fset := token.NewFileSet()
+// WithPrefix sets the marker starting service annotations, annotation.DefaultPrefix by default,
+// so that a codebase whose comments mention service: in prose can switch to another marker, such as arch:.
+func WithPrefix(prefix string) Option {
+	return func(cp *CommentParser) {
+		cp.opts.Prefix = prefix
+	}
+}
 
-	f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
-	if err != nil {
-		return fmt.Errorf("failed to parse %s: %w", path, err)
+func NewCommentParser(opts ...Option) *CommentParser {
+	cp := &CommentParser{
+		services:      make([]annotation.Service, 0),
+		relationships: make([]annotation.Relationship, 0),
+		fset:          token.NewFileSet(),
 	}
 
-	for _, cg := range f.Comments {
-		var commentText strings.Builder
-		for _, c := range cg.List {
-			commentText.WriteString(c.Text)
-			commentText.WriteString("\n")
-		}
-		cp.parseCommentGroup(commentText.String())
+	for _, opt := range opts {
+		opt(cp)
 	}
 
-	ast.Inspect(f, func(n ast.Node) bool {
-		x, ok := n.(*ast.TypeSpec)
-		if !ok {
-			return true
-		}
+	return cp
+}
 
-		if x.Doc == nil {
-			return true
-		}
+// Parse parses the Go files of dir, and of its subdirectories when recursive is set,
+// and builds the service files they describe.
+// Files are parsed concurrently by the configured number of workers, see WithWorkers,
+// and their annotations merged in path order so that results don't depend on scheduling.
+func (cp *CommentParser) Parse(dir string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	return cp.ParseDirs([]string{dir}, recursive)
+}
 
-		var commentText strings.Builder
-		for _, c := range x.Doc.List {
-			commentText.WriteString(c.Text)
-			commentText.WriteString("\n")
+// ParseDirs is Parse walking each of dirs and building a single result from all of their Go files,
+// as if they were parsed from a common root. Services declared in several directories are merged,
+// or reported, as services declared more than once in a single directory are, see WithStrictServices.
+// Files found under several of dirs, such as when one contains another, are parsed once.
+func (cp *CommentParser) ParseDirs(dirs []string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	return cp.parseDirs(context.Background(), dirs, recursive)
+}
+
+// ParseContext is Parse stopping as soon as ctx is done, in which case the error of ctx is returned.
+// The parser keeps the annotations of no file when parsing is canceled.
+func (cp *CommentParser) ParseContext(ctx context.Context, dir string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	return cp.parseDirs(ctx, []string{dir}, recursive)
+}
+
+func (cp *CommentParser) parseDirs(ctx context.Context, dirs []string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	srcs := make([]source, 0, len(dirs))
+	for _, dir := range dirs {
+		display := func(name string) string {
+			return filepath.Join(dir, filepath.FromSlash(name))
 		}
-		cp.parseCommentGroup(commentText.String())
 
-		return true
-	})
+		srcs = append(srcs, source{
+			fsys:    os.DirFS(dir),
+			root:    ".",
+			display: display,
+			realPath: func(name string) (string, error) {
+				return filepath.EvalSymlinks(display(name))
+			},
+		})
+	}
 
-	return nil
+	return cp.parse(ctx, srcs, recursive)
 }
 
-func (cp *CommentParser) parseCommentGroup(commentGroup string) {
-	if !strings.Contains(commentGroup, "service:") {
-		return
-	}
+// ParseFS is Parse reading the Go files of the root directory of fsys, such as an embed.FS or
+// a fstest.MapFS. Reported positions and service directories are the slash separated paths of fsys.
+// Symbolic links to directories are not followed, see WithFollowSymlinks.
+func (cp *CommentParser) ParseFS(fsys fs.FS, root string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	return cp.parse(context.Background(), []source{{
+		fsys: fsys,
+		root: root,
+		display: func(name string) string {
+			return name
+		},
+	}}, recursive)
+}
 
-	lines := strings.Split(commentGroup, "\n")
+// source is a tree of Go files to parse.
+type source struct {
+	fsys fs.FS
+	root string
+	// display returns the path a file of fsys is reported under.
+	display func(name string) string
+	// realPath returns the path of a directory of fsys with symbolic links resolved,
+	// nil when fsys has no symbolic links to follow.
+	realPath func(name string) (string, error)
+}
 
-	switch {
-	case strings.Contains(commentGroup, "service:name"):
-		cp.parseServiceDefinition(lines)
-	default:
-		cp.parseRelationshipDefinition(lines)
+// parse parses the Go files of srcs.
+func (cp *CommentParser) parse(ctx context.Context, srcs []source, recursive bool) ([]*servicefile.ServiceFile, error) {
+	type file struct {
+		src  source
+		name string
 	}
-}
 
-func (cp *CommentParser) parseServiceDefinition(lines []string) {
-	var s service
+	var files []file
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	roots := make([]string, 0, len(srcs))
+	seen := make(map[string]struct{})
 
-		comment := cp.extractCommentText(line)
-		if comment == "" {
-			continue
-		}
+	for _, src := range srcs {
+		roots = append(roots, src.display(src.root))
 
-		if strings.HasPrefix(comment, "service:name") {
-			parts := strings.SplitN(comment, " ", 2)
-			if len(parts) == 2 {
-				s.name = strings.TrimSpace(parts[1])
-			}
-			continue
+		names, err := cp.goFiles(ctx, src, recursive)
+		if err != nil {
+			return nil, fmt.Errorf("error walking the path: %w", err)
 		}
 
-		if strings.HasPrefix(comment, "description:") {
-			parts := strings.SplitN(comment, ":", 2)
-			if len(parts) == 2 {
-				s.description = strings.TrimSpace(parts[1])
+		for _, name := range names {
+			path := src.display(name)
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
 			}
-			continue
+			if _, exists := seen[path]; exists {
+				continue
+			}
+			seen[path] = struct{}{}
+
+			files = append(files, file{src: src, name: name})
 		}
+	}
 
-		if strings.HasPrefix(comment, "system:") {
-			parts := strings.SplitN(comment, ":", 2)
-			if len(parts) == 2 {
-				s.system = strings.TrimSpace(parts[1])
+	cp.mu.Lock()
+	cp.roots = roots
+	cp.mu.Unlock()
+
+	results := make([]annotations, len(files))
+	errs := make([]error, len(files))
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(cp.workers(), max(len(files), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if errs[i] = ctx.Err(); errs[i] != nil {
+					continue
+				}
+
+				var content []byte
+				if content, errs[i] = fs.ReadFile(files[i].src.fsys, files[i].name); errs[i] != nil {
+					continue
+				}
+				results[i], errs[i] = cp.parseFileAnnotations(files[i].src.display(files[i].name), content)
 			}
-			continue
+		}()
+	}
+
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, f := range files {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("error walking the path: failed to parse %s: %w", f.src.display(f.name), errs[i])
 		}
 	}
 
-	if s.name != "" {
-		cp.services = append(cp.services, s)
+	for _, found := range results {
+		cp.add(found)
+	}
+
+	return cp.buildServiceFiles()
+}
+
+// workers returns the number of files parsed concurrently.
+func (cp *CommentParser) workers() int {
+	if cp.opts.Workers > 0 {
+		return cp.opts.Workers
 	}
+
+	return runtime.GOMAXPROCS(0)
 }
 
-func (cp *CommentParser) parseRelationshipDefinition(lines []string) {
-	var r relationship
+// goFiles returns the sorted paths of the Go files of src that are not excluded.
+// Walking stops with the error of ctx once it is done.
+// With WithFollowSymlinks, symbolic links to directories are walked as directories, each real
+// directory being walked once so that links pointing at their parents don't loop.
+func (cp *CommentParser) goFiles(ctx context.Context, src source, recursive bool) ([]string, error) {
+	var names []string
+
+	follow := cp.opts.FollowSymlinks && src.realPath != nil
+	visited := make(map[string]struct{})
+
+	var walk fs.WalkDirFunc
+	walk = func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				pathErr.Path = src.display(pathErr.Path)
+			}
+			return fmt.Errorf("failed to walk the path: %w", err)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if follow && d.Type()&fs.ModeSymlink != 0 && recursive {
+			info, err := fs.Stat(src.fsys, name)
+			if err != nil {
+				// Dangling links are skipped, as non-Go files are.
+				return nil
+			}
+			if info.IsDir() {
+				return fs.WalkDir(src.fsys, name, walk)
+			}
+		}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+		if d.IsDir() && !recursive && name != src.root {
+			return fs.SkipDir
 		}
 
-		comment := cp.extractCommentText(line)
-		if comment == "" {
-			continue
+		if d.IsDir() && name != src.root && !cp.opts.AllDirs && SkippedDir(d.Name()) {
+			return fs.SkipDir
 		}
 
-		switch {
-		case strings.HasPrefix(comment, "service:"):
-			r.serviceName, r.action, r.targetName = cp.extractRelationshipInfo(comment)
-			continue
-		case strings.HasPrefix(comment, "technology:"):
-			parts := strings.SplitN(comment, ":", 2)
-			if len(parts) == 2 {
-				r.technology = strings.TrimSpace(parts[1])
+		if name != src.root && cp.excluded(src.root, name, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
 			}
-			continue
-		case strings.HasPrefix(comment, "description:"):
-			parts := strings.SplitN(comment, ":", 2)
-			if len(parts) == 2 {
-				r.description = strings.TrimSpace(parts[1])
+			return nil
+		}
+
+		if d.IsDir() && follow {
+			resolved, err := src.realPath(name)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", src.display(name), err)
 			}
-			continue
-		case strings.HasPrefix(comment, "proto:"):
-			parts := strings.SplitN(comment, ":", 2)
-			if len(parts) == 2 {
-				r.proto = strings.TrimSpace(parts[1])
+			if _, seen := visited[resolved]; seen {
+				return fs.SkipDir
 			}
-			continue
+			visited[resolved] = struct{}{}
 		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+
+		if !cp.opts.IncludeTests && strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+
+		names = append(names, name)
+
+		return nil
 	}
 
-	if r.action != "" {
-		cp.relationships = append(cp.relationships, r)
+	if err := fs.WalkDir(src.fsys, src.root, walk); err != nil {
+		return nil, err
 	}
+
+	sort.Strings(names)
+
+	return names, nil
 }
 
-func (cp *CommentParser) extractCommentText(line string) string {
-	comment := strings.TrimSpace(line)
-	comment = strings.TrimPrefix(comment, "//")
-	comment = strings.TrimPrefix(comment, "/*")
-	comment = strings.TrimSuffix(comment, "*/")
-	return strings.TrimSpace(comment)
+// RawRelationship is a relationship annotation as written in the source,
+// before it is attributed to a service.
+type RawRelationship struct {
+	Service     string
+	Action      string
+	Target      string
+	Technology  string
+	Description string
+	Proto       string
+
+	// Pos and End delimit the annotation, from the relationship line to its last attribute.
+	Pos token.Pos
+	End token.Pos
+	// Attributes holds the range of each annotation line keyed by attribute,
+	// "service" being the line declaring the relationship itself.
+	Attributes map[string]Range
 }
 
-// extractRelationshipInfo extracts the service name, action, and target name from a comment.
-// Format: service:{service_name}:{action} [target_service] or service:{action} [target_service]
-// Example: service:database:uses PostgreSQL
-// Example: service:uses PostgreSQL
-func (cp *CommentParser) extractRelationshipInfo(comment string) (serviceName, action, targetName string) {
-	parts := strings.SplitN(comment, " ", 2)
-	serviceActionPart := parts[0]
+// Range is a range of source text. Positions are resolved with CommentParser.FileSet.
+type Range struct {
+	Pos token.Pos
+	End token.Pos
+}
 
-	serviceActionParts := strings.Split(serviceActionPart, ":")
-	if len(serviceActionParts) >= 3 {
-		// Format: service:{service_name}:{action}
-		serviceName = serviceActionParts[1]
-		action = serviceActionParts[2]
-	} else if len(serviceActionParts) == 2 {
-		// Format: service:{action}
-		action = serviceActionParts[1]
-	}
+// FileSet returns the file set positions reported by the parser belong to.
+func (cp *CommentParser) FileSet() *token.FileSet {
+	return cp.fset
+}
+
+// RawRelationships returns the relationship annotations parsed so far in the order they were found.
+func (cp *CommentParser) RawRelationships() []RawRelationship {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	raw := make([]RawRelationship, 0, len(cp.relationships))
 
-	// Extract target name if present
-	if len(parts) > 1 {
-		targetName = strings.TrimSpace(parts[1])
+	for _, r := range cp.relationships {
+		attributes := make(map[string]Range, len(r.Attributes))
+		for key, sp := range r.Attributes {
+			attributes[key] = Range{Pos: sp.Pos, End: sp.End}
+		}
+
+		raw = append(raw, RawRelationship{
+			Service:     r.Service,
+			Action:      r.Action,
+			Target:      r.Target,
+			Technology:  r.Technology,
+			Description: r.Description,
+			Proto:       r.Proto,
+			Pos:         r.Span.Pos,
+			End:         r.Span.End,
+			Attributes:  attributes,
+		})
 	}
 
-	return serviceName, action, targetName
+	return raw
 }
 
-func (cp *CommentParser) buildServiceFiles() ([]*servicefile.ServiceFile, error) {
-	if err := cp.validateNoMixedUsage(); err != nil {
-		return nil, err
+// ParseSource parses the annotations of src, the content of a Go file named filename, as Parse would
+// parse the same content from a file, such as an unsaved editor buffer. Filename is used for positions
+// and, as the directory of the file, for attributing relationships to services.
+// Annotations accumulate with the ones of previous calls until Build materializes the service files.
+func (cp *CommentParser) ParseSource(filename, src string) error {
+	found, err := cp.parseFileAnnotations(filename, src)
+	if err != nil {
+		return err
 	}
 
-	serviceFiles := make(map[string]*servicefile.ServiceFile)
+	cp.add(found)
 
-	for _, s := range cp.services {
-		serviceFiles[s.name] = &servicefile.ServiceFile{
-			Version: servicefile.Version,
-			Info: servicefile.Info{
-				Name:        s.name,
-				Description: s.description,
-				System:      s.system,
-			},
-			Relationships: []servicefile.Relationship{},
-		}
+	return nil
+}
+
+// Build builds the service files described by the annotations parsed so far, see ParseSource.
+func (cp *CommentParser) Build() ([]*servicefile.ServiceFile, error) {
+	return cp.buildServiceFiles()
+}
+
+func (cp *CommentParser) parseFile(path string) error {
+	found, err := cp.parseFileAnnotations(path, nil)
+	if err != nil {
+		return err
 	}
 
-	for _, r := range cp.relationships {
-		serviceName, err := cp.determineServiceName(r, serviceFiles)
-		if err != nil {
-			return nil, fmt.Errorf("failed to determine service name: %w", err)
-		}
+	cp.add(found)
 
-		if _, exists := serviceFiles[serviceName]; !exists {
-			serviceFiles[serviceName] = &servicefile.ServiceFile{
-				Version: servicefile.Version,
-				Info: servicefile.Info{
-					Name: serviceName,
-				},
-				Relationships: []servicefile.Relationship{},
-			}
-		}
+	return nil
+}
 
-		relationship := servicefile.Relationship{
-			Action: servicefile.RelationshipAction(r.action),
-			Name:   r.targetName,
+// parseFileAnnotations returns the annotations of a single file, leaving the parser state untouched.
+// The file is read from path when src is nil. Files left out of the build context, if any, have no annotations.
+func (cp *CommentParser) parseFileAnnotations(path string, src any) (annotations, error) {
+	if cp.opts.BuildContext != nil {
+		match, content, err := cp.matchesBuildContext(path, src)
+		if err != nil || !match {
+			return annotations{}, err
 		}
+		src = content
+	}
 
-		if r.technology != "" {
-			relationship.Technology = r.technology
-		}
+	f, err := parser.ParseFile(cp.fset, path, src, parser.ParseComments)
+	if err != nil {
+		return annotations{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
 
-		if r.description != "" {
-			relationship.Description = r.description
-		}
+	var found annotations
 
-		if r.proto != "" {
-			relationship.Proto = r.proto
-		}
+	dir := filepath.Dir(path)
+
+	// f.Comments holds every comment group of the file, including the doc comments of the specs
+	// of grouped declarations, each parsed once.
+	for _, cg := range f.Comments {
+		cp.parseCommentLines(&found, dir, commentGroupLines(cg))
+	}
 
-		serviceFiles[serviceName].Relationships = append(serviceFiles[serviceName].Relationships, relationship)
+	if len(cp.opts.InjectionRules) > 0 {
+		cp.collectInjections(&found, dir, f)
 	}
 
-	if len(serviceFiles) == 0 {
-		return nil, fmt.Errorf("no services found")
+	if cp.opts.BlankImportTechnologies != nil {
+		cp.collectBlankImports(&found, dir, f)
 	}
 
-	result := make([]*servicefile.ServiceFile, 0, len(serviceFiles))
-	for _, sf := range serviceFiles {
-		sf.Sort()
-		result = append(result, sf)
+	if cp.opts.ImportTechnologies != nil {
+		cp.inferTechnologies(&found, f)
 	}
 
-	return result, nil
+	if len(cp.opts.CallSiteRules) > 0 {
+		cp.collectCallSites(&found, dir, f)
+	}
+
+	return found, nil
 }
 
-func (cp *CommentParser) validateNoMixedUsage() error {
-	var (
-		hasExplicit bool
-		hasImplicit bool
-	)
+// annotations holds what was found in a single file or comment group.
+type annotations struct {
+	annotation.Annotations
 
-	for _, r := range cp.relationships {
-		if r.serviceName != "" {
-			hasExplicit = true
-		} else {
-			hasImplicit = true
+	injections   []injection
+	blankImports []blankImport
+	callSites    []callSite
+}
+
+// add merges annotations found independently into the parser.
+func (cp *CommentParser) add(found annotations) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.services = append(cp.services, found.Services...)
+	cp.relationships = append(cp.relationships, found.Relationships...)
+	cp.injections = append(cp.injections, found.injections...)
+	cp.blankImports = append(cp.blankImports, found.blankImports...)
+	cp.callSites = append(cp.callSites, found.callSites...)
+	cp.warnings = append(cp.warnings, found.Warnings...)
+}
+
+// commentGroupLines splits the comments of a group into lines keeping track of where each line starts.
+func commentGroupLines(cg *ast.CommentGroup) []annotation.Line {
+	var lines []annotation.Line
+
+	for _, c := range cg.List {
+		pos := c.Slash
+		for _, text := range strings.Split(c.Text, "\n") {
+			lines = append(lines, annotation.Line{Text: text, Pos: pos})
+			pos += token.Pos(len(text) + 1)
 		}
 	}
 
-	if hasExplicit && hasImplicit {
-		return fmt.Errorf("mixed relationship definition patterns detected: some relationships use explicit patterns (service:name:action) while others use implicit patterns (service:action)")
-	}
+	return lines
+}
 
-	return nil
+func (cp *CommentParser) parseCommentGroup(dir, commentGroup string) {
+	var found annotations
+	found.Add(annotation.ParseText(dir, commentGroup, cp.annotationOptions()))
+	cp.add(found)
+}
+
+func (cp *CommentParser) parseCommentLines(found *annotations, dir string, lines []annotation.Line) {
+	found.Add(annotation.Parse(dir, lines, cp.annotationOptions()))
 }
 
-func (cp *CommentParser) determineServiceName(r relationship, serviceFiles map[string]*servicefile.ServiceFile) (string, error) {
-	if r.serviceName != "" {
-		return r.serviceName, nil
+// annotationOptions returns the options of the annotation grammar matching the parser options.
+func (cp *CommentParser) annotationOptions() annotation.Options {
+	return annotation.Options{
+		FileSet:                 cp.fset,
+		Prefix:                  cp.opts.Prefix,
+		Uncomment:               annotation.UncommentC,
+		CollectUnknown:          cp.opts.CollectUnknown,
+		FoldTargetCase:          cp.opts.FoldTargetCase,
+		StrictActions:           cp.opts.StrictActions,
+		StrictServices:          cp.opts.StrictServices,
+		StrictSelfRelationships: cp.opts.StrictSelfRelationships,
+		StrictProtos:            cp.opts.StrictProtos,
+		MarkExternal:            cp.opts.MarkExternal,
 	}
+}
+
+func (cp *CommentParser) buildServiceFiles() ([]*servicefile.ServiceFile, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	found := annotation.Annotations{Services: cp.services, Relationships: cp.relationships}
 
-	for name := range serviceFiles {
-		return name, nil
+	discovered := cp.resolveInjections()
+	discovered = append(discovered, cp.resolveBlankImports()...)
+	discovered = append(discovered, cp.resolveCallSites()...)
+
+	result, warnings, err := annotation.Build(found, discovered, cp.annotationOptions())
+	if err != nil {
+		return nil, err
 	}
 
-	return "", fmt.Errorf("no service name found for relationship: %s", r)
+	cp.buildWarnings = warnings
+
+	return result, nil
 }
@@ -1,8 +1,23 @@
 package golang
 
 import (
+	"context"
+	"errors"
+	"go/build"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
+	"github.com/denchenko/servicefile/internal/annotation"
 	"github.com/denchenko/servicefile/pkg/servicefile"
 )
 
@@ -13,6 +28,7 @@ func TestParse(t *testing.T) {
 		name           string
 		dir            string
 		recursive      bool
+		opts           []Option
 		expectedResult []*servicefile.ServiceFile
 		expectError    bool
 	}{
@@ -48,6 +64,7 @@ func TestParse(t *testing.T) {
 							Description: "Stores user data and authentication tokens",
 							Technology:  "postgresql",
 							Proto:       "tcp",
+							Port:        5432,
 						},
 					},
 				},
@@ -150,393 +167,2401 @@ func TestParse(t *testing.T) {
 			recursive:   true,
 			expectError: true,
 		},
+		{
+			name:      "parse targets differing by case without folding",
+			dir:       "testdata/casefolding",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Accepts and tracks customer orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionSends,
+							Name:        "Kafka",
+							Description: "Publishes order events",
+							Technology:  "kafka",
+						},
+						{
+							Action:      servicefile.RelationshipActionSends,
+							Name:        "kafka",
+							Description: "Publishes order events",
+							Technology:  "kafka",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse targets differing by case with folding",
+			dir:       "testdata/casefolding",
+			recursive: true,
+			opts:      []Option{WithTargetCaseFolding()},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Accepts and tracks customer orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionSends,
+							Name:        "Kafka",
+							Description: "Publishes order events",
+							Technology:  "kafka",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse dependency injection calls without injection rules",
+			dir:       "testdata/injection",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Accepts and tracks customer orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Stores orders",
+							Technology:  "postgresql",
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Billing",
+						Description: "Charges customers for their orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Notify",
+						Description: "Notifies customers about their orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse dependency injection calls with do injection rule",
+			dir:       "testdata/injection",
+			recursive: true,
+			opts:      []Option{WithInjectionRules(DoInjectionRule())},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Accepts and tracks customer orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Stores orders",
+							Technology:  "postgresql",
+						},
+						{
+							Action:     servicefile.RelationshipActionUses,
+							Name:       "Billing",
+							Discovered: true,
+						},
+						{
+							Action:     servicefile.RelationshipActionUses,
+							Name:       "Notify",
+							Discovered: true,
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Billing",
+						Description: "Charges customers for their orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Notify",
+						Description: "Notifies customers about their orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse dependency injection calls resolving the longest directory suffix",
+			dir:       "testdata/suffix",
+			recursive: true,
+			opts:      []Option{WithInjectionRules(DoInjectionRule())},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Accepts and tracks customer orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:     servicefile.RelationshipActionUses,
+							Name:       "Invoicing",
+							Discovered: true,
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Billing",
+						Description: "Charges customers for their orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Invoicing",
+						Description: "Issues invoices for the orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse package level relationships",
+			dir:       "testdata/package",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "API",
+						Description: "Serves the public API",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionSends,
+							Name:        "Jobs",
+							Description: "Enqueues background jobs",
+							Technology:  "rabbitmq",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Logger",
+							Description: "Ships structured logs",
+							Technology:  "loki",
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Worker",
+						Description: "Processes background jobs",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionReceives,
+							Name:        "Jobs",
+							Description: "Consumes background jobs",
+							Technology:  "rabbitmq",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Logger",
+							Description: "Ships structured logs",
+							Technology:  "loki",
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Other",
+						Description: "Lives in a package without shared relationships",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Redis",
+							Description: "Caches responses",
+							Technology:  "redis",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse annotations formatted as doc comment lists",
+			dir:       "testdata/list",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Inventory",
+						Description: "Tracks stock levels",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Warehouse",
+							Description: "Requests restocking",
+							Technology:  "warehouse-api",
+							Proto:       "http",
+						},
+						{
+							Action:      servicefile.RelationshipActionSends,
+							Name:        "Kafka",
+							Description: "Publishes stock changes",
+							Technology:  "kafka",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Redis",
+							Description: "Caches stock levels",
+							Technology:  "redis",
+							Proto:       "tcp",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse implicit relationships from sibling files",
+			dir:       "testdata/siblings",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Catalog",
+						Description: "Manages the product catalog",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionReplies,
+							Name:        "Storefront",
+							Description: "Serves product pages",
+							Technology:  "http",
+						},
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Pricing",
+							Description: "Fetches product prices",
+							Technology:  "grpc",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Stores products",
+							Technology:  "postgresql",
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Shipping",
+						Description: "Ships orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionSends,
+							Name:        "Carrier",
+							Description: "Books deliveries",
+							Technology:  "http",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse blank imports without blank import discovery",
+			dir:       "testdata/blankimport",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Accounts",
+						Description: "Manages customer accounts",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "pgx",
+							Description: "Stores accounts",
+							Technology:  "postgresql",
+							Proto:       "tcp",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse blank imports with blank import discovery",
+			dir:       "testdata/blankimport",
+			recursive: true,
+			opts:      []Option{WithBlankImports(nil)},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Accounts",
+						Description: "Manages customer accounts",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:     servicefile.RelationshipActionUses,
+							Name:       "automaxprocs",
+							Discovered: true,
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "pgx",
+							Description: "Stores accounts",
+							Technology:  "postgresql",
+							Proto:       "tcp",
+						},
+						{
+							Action:     servicefile.RelationshipActionUses,
+							Name:       "sqlite3",
+							Technology: "sqlite",
+							Discovered: true,
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse client constructor calls with call site discovery",
+			dir:       "testdata/callsites",
+			recursive: true,
+			opts:      []Option{WithCallSites(nil)},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Inventory",
+						Description: "Tracks the stock of products",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Redis",
+							Description: "Caches stock levels",
+						},
+						{
+							Action:     servicefile.RelationshipActionUses,
+							Name:       "Database",
+							Technology: "sql",
+							Proto:      "tcp",
+							Discovered: true,
+						},
+						{
+							Action:     servicefile.RelationshipActionSends,
+							Name:       "Kafka",
+							Technology: "kafka",
+							Proto:      "kafka",
+							Discovered: true,
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse client constructor calls without call site discovery",
+			dir:       "testdata/callsites",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Inventory",
+						Description: "Tracks the stock of products",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Redis",
+							Description: "Caches stock levels",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse relationship sla and timeout tokens",
+			dir:       "testdata/sla/valid",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Checkout",
+						Description: "Turns carts into orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Billing",
+							Description: "Charges customers",
+							Technology:  "grpc",
+							SLA:         "p99<200ms",
+							TimeoutMS:   500,
+						},
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Inventory",
+							Description: "Reserves stock",
+							Technology:  "http",
+							TimeoutMS:   1500,
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse asynchronous relationships",
+			dir:       "testdata/async",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Takes orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:     servicefile.RelationshipActionRequests,
+							Name:       "Billing",
+							Technology: "grpc",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Kafka",
+							Description: "Publishes placed orders",
+							Technology:  "kafka",
+							Async:       true,
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse relationship with invalid timeout",
+			dir:       "testdata/sla/invalid",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Checkout",
+						Description: "Turns carts into orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Billing",
+							Description: "Charges customers",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "parse several services declared in a single file",
+			dir:       "testdata/adapters",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "SMSAdapter",
+						Description: "Forwards notifications as text messages",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Twilio",
+							Description: "Sends text messages",
+							Technology:  "http",
+						},
+					},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "MailAdapter",
+						Description: "Forwards notifications as emails",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionReceives,
+							Name:        "Notifications",
+							Description: "Consumes email notifications",
+							Technology:  "kafka",
+						},
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "SendGrid",
+							Description: "Sends emails",
+							Technology:  "http",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse with excluded directories and files",
+			dir:       "testdata/exclude",
+			recursive: true,
+			opts:      []Option{WithExcludes("**/mocks/", "**/*.pb.go")},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Takes orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionReplies,
+							Name:        "Storefront",
+							Description: "Serves orders",
+							Technology:  "grpc",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Stores orders",
+							Technology:  "postgresql",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:        "parse with excluded files only",
+			dir:         "testdata/exclude",
+			recursive:   true,
+			opts:        []Option{WithExcludes("**/*.pb.go")},
+			expectError: true,
+		},
+		{
+			name:      "parse skipping vendor and hidden directories",
+			dir:       "testdata/skipdirs",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Gateway",
+						Description: "Routes public traffic",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse vendor and hidden directories",
+			dir:       "testdata/skipdirs",
+			recursive: true,
+			opts:      []Option{WithAllDirs()},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Gateway",
+						Description: "Routes public traffic",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Hidden",
+						Description: "Hidden directory that must not be parsed by default",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "NodeTool",
+						Description: "Node modules that must not be parsed by default",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "VendoredLib",
+						Description: "Third-party code that must not be parsed by default",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse skipping test files",
+			dir:       "testdata/tests",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Takes orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse test files",
+			dir:       "testdata/tests",
+			recursive: true,
+			opts:      []Option{WithTests()},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "FakeOrders",
+						Description: "Example service declared by a test",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Takes orders",
+					},
+					Relationships: []servicefile.Relationship{},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse unknown relationship action",
+			dir:       "testdata/actions",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Catalog",
+						Description: "Lists products",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      "usess",
+							Name:        "PostgreSQL",
+							Description: "Stores products",
+							Technology:  "postgresql",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:        "parse unknown relationship action in strict mode",
+			dir:         "testdata/actions",
+			recursive:   true,
+			opts:        []Option{WithStrictActions()},
+			expectError: true,
+		},
+		{
+			name:      "parse relationships annotated more than once",
+			dir:       "testdata/duplicate",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Orders",
+						Description: "Takes orders",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Stores orders",
+							Technology:  "postgresql",
+							Proto:       "tcp",
+						},
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Migrates the schema",
+							Technology:  "pgx",
+						},
+						{
+							Action:       servicefile.RelationshipActionUses,
+							Name:         "PostgreSQL",
+							Description:  "Stores orders of the staging environment",
+							Technology:   "postgresql",
+							Proto:        "tcp",
+							Environments: []string{"staging"},
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:      "parse multi-line descriptions",
+			dir:       "testdata/multiline",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Ledger",
+						Description: "Keeps the double-entry ledger of every account, reconciling it nightly against the bank statements and reporting discrepancies to finance.",
+						System:      "payments",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "PostgreSQL",
+							Description: "Stores ledger entries partitioned by month",
+							Technology:  "postgresql",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "parse annotation keys regardless of their case",
+			dir:       "testdata/keycase",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Search",
+						Description: "Indexes the product catalog",
+						System:      "storefront",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionUses,
+							Name:        "Elasticsearch",
+							Description: "Stores the product index",
+							Technology:  "elasticsearch",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "parse deprecated relationships",
+			dir:       "testdata/deprecated",
+			recursive: true,
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Login",
+						Description: "Signs users in",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Auth",
+							Description: "Verifies credentials",
+							Technology:  "grpc",
+						},
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "LegacyAuth",
+							Description: "Kept until every client sends v2 tokens, traffic is routed to Auth",
+							Technology:  "http",
+							Deprecated:  true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "parse annotations with a custom prefix",
+			dir:       "testdata/prefix",
+			recursive: true,
+			opts:      []Option{WithPrefix("arch:")},
+			expectedResult: []*servicefile.ServiceFile{
+				{
+					Version: servicefile.Version,
+					Info: servicefile.Info{
+						Name:        "Gateway",
+						Description: "Routes public traffic to the backend services",
+					},
+					Relationships: []servicefile.Relationship{
+						{
+							Action:      servicefile.RelationshipActionRequests,
+							Name:        "Orders",
+							Description: "Forwards order requests",
+							Technology:  "http",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	compareServiceFileSlices := func(actual, expected []*servicefile.ServiceFile) bool {
+		if len(actual) != len(expected) {
+			return false
+		}
+		// Compare by name
+		actualMap := make(map[string]*servicefile.ServiceFile)
+		for _, sf := range actual {
+			actualMap[sf.Info.Name] = sf
+		}
+		expectedMap := make(map[string]*servicefile.ServiceFile)
+		for _, sf := range expected {
+			expectedMap[sf.Info.Name] = sf
+		}
+		return compareServiceFiles(actualMap, expectedMap)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewCommentParser(tt.opts...)
+			result, err := parser.Parse(tt.dir, tt.recursive)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !compareServiceFileSlices(result, tt.expectedResult) {
+				t.Errorf("Parse() = %+v, want %+v", result, tt.expectedResult)
+				for _, v := range result {
+					t.Logf("actual: Name=%s, Desc=%s, Rels=%+v", v.Info.Name, v.Info.Description, v.Relationships)
+				}
+				for _, v := range tt.expectedResult {
+					t.Logf("expected: Name=%s, Desc=%s, Rels=%+v", v.Info.Name, v.Info.Description, v.Relationships)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                  string
+		filePath              string
+		expectedServices      []annotation.Service
+		expectedRelationships []annotation.Relationship
+		expectError           bool
+	}{
+		{
+			name:     "parse service file with comments",
+			filePath: "testdata/default/service/example/example.go",
+			expectedServices: []annotation.Service{
+				{
+					Name:        "Example",
+					Description: "Example service for exampling stuff.",
+				},
+			},
+			expectedRelationships: []annotation.Relationship{},
+			expectError:           false,
+		},
+		{
+			name:             "parse file with relationship comments",
+			filePath:         "testdata/default/database/postgres/postgres.go",
+			expectedServices: []annotation.Service{},
+			expectedRelationships: []annotation.Relationship{
+				{
+					Service:     "",
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Description: "Stores user data and authentication tokens",
+					Technology:  "postgresql",
+					Proto:       "tcp",
+					Port:        5432,
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:        "parse non-existent file",
+			filePath:    "testdata/nonexistent.go",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewCommentParser()
+			err := parser.parseFile(tt.filePath)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !compareServices(parser.services, tt.expectedServices) {
+				t.Errorf("parseFile() services = %+v, want %+v", parser.services, tt.expectedServices)
+			}
+
+			if !compareRelationships(parser.relationships, tt.expectedRelationships) {
+				t.Errorf("parseFile() relationships = %+v, want %+v", parser.relationships, tt.expectedRelationships)
+			}
+		})
+	}
+}
+
+func TestImportTechnologies(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join("testdata", "default", "database", "postgres", "postgres.go")
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The fixture states its technology: drop it and import the driver instead.
+	inferred := strings.Replace(string(src), "technology:postgresql\n", "", 1)
+	inferred = strings.Replace(inferred, `import "github.com/samber/do"`, "import (\n\t\"github.com/samber/do\"\n\t_ \"github.com/lib/pq\"\n)", 1)
+
+	technology := func(src string, opts ...Option) string {
+		t.Helper()
+
+		parser := NewCommentParser(opts...)
+		if err := parser.ParseSource(path, src); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(parser.relationships) != 1 {
+			t.Fatalf("ParseSource() relationships = %+v, want one relationship", parser.relationships)
+		}
+
+		return parser.relationships[0].Technology
+	}
+
+	if got := technology(inferred, WithImportTechnologies(nil)); got != "postgresql" {
+		t.Errorf("technology = %q, want postgresql inferred from github.com/lib/pq", got)
+	}
+
+	if got := technology(inferred); got != "" {
+		t.Errorf("technology without inference = %q, want none", got)
+	}
+
+	if got := technology(inferred, WithImportTechnologies(map[string]string{"github.com/samber": "injector"})); got != "injector" {
+		t.Errorf("technology with an overridden map = %q, want injector, the map replacing the defaults", got)
+	}
+
+	if got := technology(string(src), WithImportTechnologies(map[string]string{"github.com/samber/do": "injector"})); got != "postgresql" {
+		t.Errorf("technology = %q, want the annotated postgresql", got)
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	t.Parallel()
+
+	write := func(path, content string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "repo")
+	shared := filepath.Join(tmp, "shared", "billing")
+
+	write(filepath.Join(root, "orders", "orders.go"), "package orders\n\n// service:name Orders\n\n// service:requests Billing\n")
+	write(filepath.Join(shared, "billing.go"), "package billing\n\n// service:name Billing\n\n// service:uses PostgreSQL\n")
+
+	for link, target := range map[string]string{
+		filepath.Join(root, "billing"):         shared,
+		filepath.Join(root, "orders", "loop"):  root,
+		filepath.Join(root, "orders", "again"): filepath.Join(root, "orders"),
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+	}
+
+	names := func(result []*servicefile.ServiceFile) []string {
+		var names []string
+		for _, sf := range result {
+			names = append(names, sf.Info.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	result, err := NewCommentParser().Parse(root, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := names(result); !slices.Equal(got, []string{"Orders"}) {
+		t.Errorf("Parse() services = %v, want [Orders] without following links", got)
+	}
+
+	parser := NewCommentParser(WithFollowSymlinks())
+
+	result, err = parser.Parse(root, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := names(result); !slices.Equal(got, []string{"Billing", "Orders"}) {
+		t.Errorf("Parse() services = %v, want [Billing Orders]", got)
+	}
+	for _, sf := range result {
+		if len(sf.Relationships) != 1 {
+			t.Errorf("Parse() %s relationships = %+v, want a single one despite the looping links", sf.Info.Name, sf.Relationships)
+		}
+	}
+	if warnings := parser.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() = %+v, want none", warnings)
+	}
+}
+
+func TestSources(t *testing.T) {
+	t.Parallel()
+
+	result, err := NewCommentParser().Parse("testdata/deprecated", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Parse() = %+v, want the Login service", result)
+	}
+
+	path := filepath.Join("testdata", "deprecated", "login.go")
+	if want := (servicefile.Source{File: path, Line: 3}); result[0].Info.Source != want {
+		t.Errorf("Info.Source = %v, want %v", result[0].Info.Source, want)
+	}
+
+	sources := make(map[string]servicefile.Source)
+	for _, rel := range result[0].Relationships {
+		sources[rel.Name] = rel.Source
+	}
+
+	expected := map[string]servicefile.Source{
+		"Auth":       {File: path, Line: 9},
+		"LegacyAuth": {File: path, Line: 16},
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("relationship sources = %v, want %v", sources, expected)
+	}
+
+	discovered, err := NewCommentParser(WithCallSites(nil)).Parse("testdata/callsites", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sources = make(map[string]servicefile.Source)
+	for _, rel := range discovered[0].Relationships {
+		sources[rel.Name] = rel.Source
+	}
+	if want := (servicefile.Source{File: filepath.Join("testdata", "callsites", "inventory.go"), Line: 26}); sources["Database"] != want {
+		t.Errorf("discovered relationship source = %v, want %v", sources["Database"], want)
+	}
+}
+
+func TestBuildContext(t *testing.T) {
+	t.Parallel()
+
+	linux := build.Default
+	linux.GOOS = "linux"
+
+	darwin := build.Default
+	darwin.GOOS = "darwin"
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected map[string][]string
+	}{
+		{
+			name:     "every file parsed by default",
+			expected: map[string][]string{"Agent": {"Journald", "Metrics"}, "Generator": nil},
+		},
+		{
+			name:     "constraints satisfied",
+			opts:     []Option{WithBuildContext(&linux)},
+			expected: map[string][]string{"Agent": {"Journald", "Metrics"}},
+		},
+		{
+			name:     "constraints not satisfied",
+			opts:     []Option{WithBuildContext(&darwin)},
+			expected: map[string][]string{"Agent": {"Metrics"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := NewCommentParser(tt.opts...).Parse("testdata/buildtags", true)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			services := make(map[string][]string, len(result))
+			for _, sf := range result {
+				services[sf.Info.Name] = nil
+				for _, rel := range sf.Relationships {
+					services[sf.Info.Name] = append(services[sf.Info.Name], rel.Name)
+				}
+				slices.Sort(services[sf.Info.Name])
+			}
+
+			if !reflect.DeepEqual(services, tt.expected) {
+				t.Errorf("Parse() services = %v, want %v", services, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDirs(t *testing.T) {
+	t.Parallel()
+
+	byName := func(a, b *servicefile.ServiceFile) int {
+		return strings.Compare(a.Info.Name, b.Info.Name)
+	}
+
+	expected, err := NewCommentParser().Parse("testdata/explicit", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	slices.SortFunc(expected, byName)
+
+	services := filepath.Join("testdata", "explicit", "services")
+
+	tests := []struct {
+		name string
+		dirs []string
+	}{
+		{
+			name: "separate roots",
+			dirs: []string{filepath.Join(services, "auth"), filepath.Join(services, "notification"), filepath.Join(services, "user")},
+		},
+		{
+			name: "overlapping roots",
+			dirs: []string{"testdata/explicit", filepath.Join(services, "auth")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := NewCommentParser()
+
+			result, err := parser.ParseDirs(tt.dirs, true)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			slices.SortFunc(result, byName)
+
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("ParseDirs() = %+v, want %+v as parsed by Parse", result, expected)
+			}
+
+			if warnings := parser.Warnings(); len(warnings) != 0 {
+				t.Errorf("Warnings() = %+v, want none", warnings)
+			}
+		})
+	}
+
+	tmp := t.TempDir()
+	for _, root := range []string{"cmd", "pkg"} {
+		if err := os.MkdirAll(filepath.Join(tmp, root), 0o755); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		src := "package payments\n\n// service:name Payments\n// description: Declared in " + root + "\n"
+		if err := os.WriteFile(filepath.Join(tmp, root, "payments.go"), []byte(src), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	dirs := []string{filepath.Join(tmp, "cmd"), filepath.Join(tmp, "pkg")}
+
+	parser := NewCommentParser()
+
+	result, err := parser.ParseDirs(dirs, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 1 || result[0].Info.Description != "Declared in cmd" {
+		t.Errorf("ParseDirs() = %+v, want Payments merged, the first root winning", result)
+	}
+	if warnings := parser.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0].Message, "definitions are merged") {
+		t.Errorf("Warnings() = %+v, want Payments reported as declared more than once", warnings)
+	}
+
+	if _, err := NewCommentParser(WithStrictServices()).ParseDirs(dirs, true); err == nil || !strings.Contains(err.Error(), `service "Payments" is declared more than once`) {
+		t.Errorf("ParseDirs() in strict mode error = %v, want Payments declared more than once", err)
+	}
+}
+
+func TestWithParseOptions(t *testing.T) {
+	t.Parallel()
+
+	expected, err := NewCommentParser(WithTargetCaseFolding(), WithExternalTargets(), WithWorkers(1)).Parse("testdata/casefolding", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	opts := ParseOptions{FoldTargetCase: true, MarkExternal: true, Workers: 1}
+
+	result, err := NewCommentParser(WithParseOptions(opts)).Parse("testdata/casefolding", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	byName := func(a, b *servicefile.ServiceFile) int {
+		return strings.Compare(a.Info.Name, b.Info.Name)
+	}
+	slices.SortFunc(expected, byName)
+	slices.SortFunc(result, byName)
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want %+v as parsed with the equivalent options", result, expected)
+	}
+
+	cp := NewCommentParser(WithStrictActions(), WithParseOptions(opts), WithPrefix("arch:"))
+	if want := (ParseOptions{FoldTargetCase: true, MarkExternal: true, Workers: 1, Prefix: "arch:"}); !reflect.DeepEqual(cp.opts, want) {
+		t.Errorf("options = %+v, want %+v", cp.opts, want)
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "explicit")
+
+	expected, err := NewCommentParser().Parse(dir, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	parser := NewCommentParser()
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return parser.ParseSource(path, string(src))
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, err := parser.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	byName := func(a, b *servicefile.ServiceFile) int {
+		return strings.Compare(a.Info.Name, b.Info.Name)
+	}
+	slices.SortFunc(expected, byName)
+	slices.SortFunc(result, byName)
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Build() = %+v, want %+v as parsed by Parse", result, expected)
+	}
+
+	if err := NewCommentParser().ParseSource("broken.go", "package broken\n\nfunc {"); err == nil {
+		t.Errorf("ParseSource() error = nil, want a syntax error")
+	}
+}
+
+func TestParseSourceFormattedMultilineDescriptions(t *testing.T) {
+	t.Parallel()
+
+	src := `package ledger
+
+/*
+service:name Ledger
+description: Keeps the double-entry ledger of every account,
+  reconciling it nightly against the bank statements
+system: payments
+*/
+type Ledger struct{}
+
+// service:uses PostgreSQL
+// description: Stores ledger entries
+//   partitioned by month
+// technology:postgresql
+type Store struct{}
+`
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(formatted) == src {
+		t.Fatalf("format.Source() left the continuation lines untouched, want them rewritten as code blocks")
+	}
+
+	for name, src := range map[string]string{"source": src, "formatted source": string(formatted)} {
+		parser := NewCommentParser()
+		if err := parser.ParseSource("ledger.go", src); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		result, err := parser.Build()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(result) != 1 || len(result[0].Relationships) != 1 {
+			t.Fatalf("Build() of the %s = %+v, want Ledger using PostgreSQL", name, result)
+		}
+
+		if got, want := result[0].Info.Description, "Keeps the double-entry ledger of every account, reconciling it nightly against the bank statements"; got != want {
+			t.Errorf("Info.Description of the %s = %q, want %q", name, got, want)
+		}
+
+		if got, want := result[0].Info.System, "payments"; got != want {
+			t.Errorf("Info.System of the %s = %q, want %q", name, got, want)
+		}
+
+		rel := result[0].Relationships[0]
+		if got, want := rel.Description, "Stores ledger entries partitioned by month"; got != want {
+			t.Errorf("Relationship.Description of the %s = %q, want %q", name, got, want)
+		}
+
+		if got, want := rel.Technology, "postgresql"; got != want {
+			t.Errorf("Relationship.Technology of the %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseCommentGroup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                  string
+		commentGroup          string
+		expectedServices      []annotation.Service
+		expectedRelationships []annotation.Relationship
+	}{
+		{
+			name: "parse service name and description",
+			commentGroup: `/*
+service:name Example
+description: Example service for exampling stuff.
+*/`,
+			expectedServices: []annotation.Service{
+				{
+					Name:        "Example",
+					Description: "Example service for exampling stuff.",
+				},
+			},
+			expectedRelationships: []annotation.Relationship{},
+		},
+		{
+			name: "parse service name, description, and system",
+			commentGroup: `/*
+service:name UserService
+description: Handles user authentication and profiles
+system: e-commerce-platform
+*/`,
+			expectedServices: []annotation.Service{
+				{
+					Name:        "UserService",
+					Description: "Handles user authentication and profiles",
+					System:      "e-commerce-platform",
+				},
+			},
+			expectedRelationships: []annotation.Relationship{},
+		},
+		{
+			name: "parse relationship with all fields",
+			commentGroup: `/*
+service:uses PostgreSQL
+description: Stores user data and authentication tokens
+technology:postgresql
+proto:tcp
+port: 5432
+*/`,
+			expectedServices: []annotation.Service{},
+			expectedRelationships: []annotation.Relationship{
+				{
+					Service:     "",
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Description: "Stores user data and authentication tokens",
+					Technology:  "postgresql",
+					Proto:       "tcp",
+					Port:        5432,
+				},
+			},
+		},
+		{
+			name:                  "parse empty comment group",
+			commentGroup:          `/* */`,
+			expectedServices:      []annotation.Service{},
+			expectedRelationships: []annotation.Relationship{},
+		},
+		{
+			name: "parse comments starting with //",
+			commentGroup: `// service:name Example
+// description: Example service for exampling stuff.`,
+			expectedServices: []annotation.Service{
+				{
+					Name:        "Example",
+					Description: "Example service for exampling stuff.",
+				},
+			},
+			expectedRelationships: []annotation.Relationship{},
+		},
+		{
+			name: "parse mixed comments: regular golang comments first, then service comments with /* */",
+			commentGroup: `// User represents a user in the system
+// This struct contains all user-related fields
+/*
+service:uses PostgreSQL
+description: Stores user data and authentication tokens
+technology:postgresql
+proto:tcp
+*/`,
+			expectedServices: []annotation.Service{},
+			expectedRelationships: []annotation.Relationship{
+				{
+					Service:     "",
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Description: "Stores user data and authentication tokens",
+					Technology:  "postgresql",
+					Proto:       "tcp",
+				},
+			},
+		},
+		{
+			name: "parse mixed comments: regular golang comments first, then service comments with //",
+			commentGroup: `// User represents a user in the system
+// This struct contains all user-related fields
+// service:uses PostgreSQL
+// description: Stores user data and authentication tokens
+// technology:postgresql
+// proto:tcp`,
+			expectedServices: []annotation.Service{},
+			expectedRelationships: []annotation.Relationship{
+				{
+					Service:     "",
+					Action:      "uses",
+					Target:      "PostgreSQL",
+					Description: "Stores user data and authentication tokens",
+					Technology:  "postgresql",
+					Proto:       "tcp",
+				},
+			},
+		},
+		{
+			name: "parse mixed comments: service comments with /* */ first, then regular golang comments",
+			commentGroup: `/*
+service:name Example
+description: Example service for exampling stuff.
+*/
+// User represents a user in the system
+// This struct contains all user-related fields`,
+			expectedServices: []annotation.Service{
+				{
+					Name:        "Example",
+					Description: "Example service for exampling stuff.",
+				},
+			},
+			expectedRelationships: []annotation.Relationship{},
+		},
+		{
+			name: "parse mixed comments: service comments with // first, then regular golang comments",
+			commentGroup: `// service:name Example
+// description: Example service for exampling stuff.
+//
+// User represents a user in the system
+// This struct contains all user-related fields`,
+			expectedServices: []annotation.Service{
+				{
+					Name:        "Example",
+					Description: "Example service for exampling stuff.",
+				},
+			},
+			expectedRelationships: []annotation.Relationship{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewCommentParser()
+			parser.parseCommentGroup("", tt.commentGroup)
+
+			if !compareServices(parser.services, tt.expectedServices) {
+				t.Errorf("parseCommentGroup() services = %+v, want %+v", parser.services, tt.expectedServices)
+			}
+
+			if !compareRelationships(parser.relationships, tt.expectedRelationships) {
+				t.Errorf("parseCommentGroup() relationships = %+v, want %+v", parser.relationships, tt.expectedRelationships)
+			}
+		})
+	}
+}
+
+// compareServiceFiles compares two service file maps for equality
+func compareServiceFiles(actual, expected map[string]*servicefile.ServiceFile) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+
+	for serviceName, expectedService := range expected {
+		actualService, exists := actual[serviceName]
+		if !exists {
+			return false
+		}
+
+		if actualService.Info.Name != expectedService.Info.Name {
+			return false
+		}
+
+		if actualService.Info.Description != expectedService.Info.Description {
+			return false
+		}
+
+		if actualService.Info.System != expectedService.Info.System {
+			return false
+		}
+
+		if actualService.Info.Alias != expectedService.Info.Alias {
+			return false
+		}
+
+		if len(actualService.Relationships) != len(expectedService.Relationships) {
+			return false
+		}
+
+		// Compare relationships (order doesn't matter for this test)
+		for _, expectedRel := range expectedService.Relationships {
+			found := false
+			for _, actualRel := range actualService.Relationships {
+				if actualRel.Action == expectedRel.Action &&
+					actualRel.Name == expectedRel.Name &&
+					actualRel.Description == expectedRel.Description &&
+					actualRel.Technology == expectedRel.Technology &&
+					actualRel.Proto == expectedRel.Proto &&
+					actualRel.Port == expectedRel.Port &&
+					actualRel.Async == expectedRel.Async &&
+					actualRel.Discovered == expectedRel.Discovered &&
+					actualRel.SLA == expectedRel.SLA &&
+					actualRel.TimeoutMS == expectedRel.TimeoutMS &&
+					actualRel.Deprecated == expectedRel.Deprecated &&
+					slices.Equal(actualRel.Environments, expectedRel.Environments) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
 	}
 
-	compareServiceFileSlices := func(actual, expected []*servicefile.ServiceFile) bool {
-		if len(actual) != len(expected) {
+	return true
+}
+
+// compareServices compares two service slices for equality
+func compareServices(actual, expected []annotation.Service) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+
+	// Compare services (order doesn't matter for this test)
+	for _, expectedService := range expected {
+		found := false
+		for _, actualService := range actual {
+			if actualService.Name == expectedService.Name &&
+				actualService.Description == expectedService.Description &&
+				actualService.System == expectedService.System {
+				found = true
+				break
+			}
+		}
+		if !found {
 			return false
 		}
-		// Compare by name
-		actualMap := make(map[string]*servicefile.ServiceFile)
-		for _, sf := range actual {
-			actualMap[sf.Info.Name] = sf
+	}
+
+	return true
+}
+
+// compareRelationships compares two relationship slices for equality
+func compareRelationships(actual, expected []annotation.Relationship) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+
+	// Compare relationships (order doesn't matter for this test)
+	for _, expectedRel := range expected {
+		found := false
+		for _, actualRel := range actual {
+			if actualRel.Service == expectedRel.Service &&
+				actualRel.Action == expectedRel.Action &&
+				actualRel.Target == expectedRel.Target &&
+				actualRel.Technology == expectedRel.Technology &&
+				actualRel.Description == expectedRel.Description &&
+				actualRel.Proto == expectedRel.Proto &&
+				actualRel.Port == expectedRel.Port &&
+				actualRel.Async == expectedRel.Async {
+				found = true
+				break
+			}
 		}
-		expectedMap := make(map[string]*servicefile.ServiceFile)
-		for _, sf := range expected {
-			expectedMap[sf.Info.Name] = sf
+		if !found {
+			return false
 		}
-		return compareServiceFiles(actualMap, expectedMap)
+	}
+
+	return true
+}
+
+func TestRawRelationships(t *testing.T) {
+	t.Parallel()
+
+	type position struct {
+		line   int
+		column int
+	}
+
+	tests := []struct {
+		name               string
+		filePath           string
+		expectedPos        position
+		expectedEnd        position
+		expectedAttributes map[string][2]position
+	}{
+		{
+			name:        "relationship in block comment",
+			filePath:    "testdata/default/database/postgres/postgres.go",
+			expectedPos: position{line: 6, column: 1},
+			expectedEnd: position{line: 10, column: 11},
+			expectedAttributes: map[string][2]position{
+				"service":     {{line: 6, column: 1}, {line: 6, column: 24}},
+				"description": {{line: 7, column: 1}, {line: 7, column: 56}},
+				"technology":  {{line: 8, column: 1}, {line: 8, column: 22}},
+				"proto":       {{line: 9, column: 1}, {line: 9, column: 10}},
+				"port":        {{line: 10, column: 1}, {line: 10, column: 11}},
+			},
+		},
+		{
+			name:        "relationship in line comments",
+			filePath:    "testdata/explicit/services/auth/auth.go",
+			expectedPos: position{line: 6, column: 4},
+			expectedEnd: position{line: 8, column: 18},
+			expectedAttributes: map[string][2]position{
+				"service":     {{line: 6, column: 4}, {line: 6, column: 29}},
+				"description": {{line: 7, column: 4}, {line: 7, column: 66}},
+				"technology":  {{line: 8, column: 4}, {line: 8, column: 18}},
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			parser := NewCommentParser()
-			result, err := parser.Parse(tt.dir, tt.recursive)
+			if err := parser.parseFile(tt.filePath); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("Expected error but got none")
+			raw := parser.RawRelationships()
+			if len(raw) == 0 {
+				t.Fatalf("Expected relationships but got none")
+			}
+
+			fset := parser.FileSet()
+			positionOf := func(pos token.Pos) position {
+				p := fset.Position(pos)
+				return position{line: p.Line, column: p.Column}
+			}
+
+			if got := positionOf(raw[0].Pos); got != tt.expectedPos {
+				t.Errorf("RawRelationships() pos = %+v, want %+v", got, tt.expectedPos)
+			}
+
+			if got := positionOf(raw[0].End); got != tt.expectedEnd {
+				t.Errorf("RawRelationships() end = %+v, want %+v", got, tt.expectedEnd)
+			}
+
+			if len(raw[0].Attributes) != len(tt.expectedAttributes) {
+				t.Errorf("RawRelationships() attributes = %+v, want %+v", raw[0].Attributes, tt.expectedAttributes)
+			}
+
+			for key, expected := range tt.expectedAttributes {
+				got := [2]position{positionOf(raw[0].Attributes[key].Pos), positionOf(raw[0].Attributes[key].End)}
+				if got != expected {
+					t.Errorf("RawRelationships() attribute %s = %+v, want %+v", key, got, expected)
 				}
-				return
+			}
+		})
+	}
+}
+
+func TestParseFileConcurrently(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{
+		"testdata/explicit/services/auth/auth.go",
+		"testdata/explicit/services/notification/notification.go",
+		"testdata/explicit/services/user/user.go",
+	}
+
+	const rounds = 50
+
+	parser := NewCommentParser()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, rounds*len(paths))
+
+	for range rounds {
+		for _, path := range paths {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := parser.parseFile(path); err != nil {
+					errs <- err
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(parser.services) != rounds*len(paths) {
+		t.Errorf("parseFile() services = %d, want %d", len(parser.services), rounds*len(paths))
+	}
+
+	if len(parser.relationships) != 2*rounds*len(paths) {
+		t.Errorf("parseFile() relationships = %d, want %d", len(parser.relationships), 2*rounds*len(paths))
+	}
+
+	result, err := parser.buildServiceFiles()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result) != len(paths) {
+		t.Errorf("buildServiceFiles() = %d service files, want %d", len(result), len(paths))
+	}
+}
+
+func TestCollectUnknownOfDuplicateRelationships(t *testing.T) {
+	t.Parallel()
+
+	result, err := NewCommentParser(WithCollectUnknown()).Parse("testdata/duplicate", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || len(result[0].Relationships) != 3 {
+		t.Fatalf("Parse() = %+v, want Orders with three relationships", result)
+	}
+
+	var found bool
+	for _, rel := range result[0].Relationships {
+		if rel.Technology != "postgresql" || len(rel.Environments) > 0 {
+			continue
+		}
+		found = true
+
+		if want := map[string]string{"runbook": "wiki/replicas"}; !maps.Equal(rel.Annotations, want) {
+			t.Errorf("Annotations = %v, want %v merged from the duplicate", rel.Annotations, want)
+		}
+		if want := "Stores orders"; rel.Description != want {
+			t.Errorf("Description = %q, want %q", rel.Description, want)
+		}
+	}
+
+	if !found {
+		t.Errorf("Parse() = %+v, want the relationship annotated more than once", result)
+	}
+}
+
+func TestCollectUnknown(t *testing.T) {
+	t.Parallel()
+
+	commentGroups := []string{
+		`/*
+service:name Billing
+description: Charges customers
+lifecycle: production
+cost-center: 4200
+*/`,
+		`/*
+service:uses PostgreSQL
+description: Stores invoices
+technology:postgresql
+slo: 99.9%
+Note this sentence is not an annotation: it contains spaces before the colon
+*/`,
+	}
+
+	tests := []struct {
+		name                            string
+		opts                            []Option
+		expectedServiceAnnotations      map[string]string
+		expectedRelationshipAnnotations map[string]string
+	}{
+		{
+			name: "ignore unknown keys by default",
+		},
+		{
+			name: "collect unknown keys",
+			opts: []Option{WithCollectUnknown()},
+			expectedServiceAnnotations: map[string]string{
+				"lifecycle":   "production",
+				"cost-center": "4200",
+			},
+			expectedRelationshipAnnotations: map[string]string{
+				"slo": "99.9%",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewCommentParser(tt.opts...)
+			for _, cg := range commentGroups {
+				parser.parseCommentGroup("", cg)
 			}
 
+			result, err := parser.buildServiceFiles()
 			if err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(result) != 1 || len(result[0].Relationships) != 1 {
+				t.Fatalf("buildServiceFiles() = %+v, want a single service with a single relationship", result)
+			}
+
+			if !maps.Equal(result[0].Info.Annotations, tt.expectedServiceAnnotations) {
+				t.Errorf("service annotations = %v, want %v", result[0].Info.Annotations, tt.expectedServiceAnnotations)
 			}
 
-			if !compareServiceFileSlices(result, tt.expectedResult) {
-				t.Errorf("Parse() = %+v, want %+v", result, tt.expectedResult)
-				for _, v := range result {
-					t.Logf("actual: Name=%s, Desc=%s, Rels=%+v", v.Info.Name, v.Info.Description, v.Relationships)
-				}
-				for _, v := range tt.expectedResult {
-					t.Logf("expected: Name=%s, Desc=%s, Rels=%+v", v.Info.Name, v.Info.Description, v.Relationships)
-				}
-			}
-		})
+			if !maps.Equal(result[0].Relationships[0].Annotations, tt.expectedRelationshipAnnotations) {
+				t.Errorf("relationship annotations = %v, want %v", result[0].Relationships[0].Annotations, tt.expectedRelationshipAnnotations)
+			}
+
+			if result[0].Relationships[0].Technology != "postgresql" {
+				t.Errorf("relationship technology = %q, want %q", result[0].Relationships[0].Technology, "postgresql")
+			}
+		})
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"services/orders/orders.go": {Data: []byte(`package orders
+
+// service:name Orders
+// description: Takes orders
+
+// service:uses PostgreSQL
+// technology: postgresql
+// technolgy: postgres
+type Store struct{}
+`)},
+		"services/orders/orders_test.go": {Data: []byte("package orders\n\n// service:uses Redis\n")},
+		"services/orders/README.md":      {Data: []byte("service:uses Kafka\n")},
+		"vendor/billing/billing.go":      {Data: []byte("package billing\n\n// service:name Billing\n")},
+	}
+
+	parser := NewCommentParser()
+
+	result, err := parser.ParseFS(fsys, "services", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []*servicefile.ServiceFile{
+		{
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        "Orders",
+				Description: "Takes orders",
+				Source:      servicefile.Source{File: "services/orders/orders.go", Line: 3},
+			},
+			Relationships: []servicefile.Relationship{
+				{
+					Action:     servicefile.RelationshipActionUses,
+					Name:       "PostgreSQL",
+					Technology: "postgresql",
+					Source:     servicefile.Source{File: "services/orders/orders.go", Line: 6},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ParseFS() = %+v, want %+v", result, expected)
+	}
+
+	warnings := parser.Warnings()
+	if len(warnings) != 1 || warnings[0].Path != "services/orders/orders.go" || warnings[0].Line != 8 {
+		t.Errorf("Warnings() = %+v, want the unknown key of services/orders/orders.go:8", warnings)
+	}
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	parser := NewCommentParser()
+
+	result, err := parser.ParseContext(ctx, "testdata/default", true)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ParseContext() error = %v, want %v", err, context.Canceled)
+	}
+	if result != nil {
+		t.Errorf("ParseContext() = %+v, want nil", result)
+	}
+
+	result, err = parser.ParseContext(context.Background(), "testdata/default", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 1 {
+		t.Errorf("ParseContext() = %+v, want the services of testdata/default after a canceled parse", result)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser()
+
+	result, err := parser.Parse("testdata/malformed", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || len(result[0].Relationships) != 2 {
+		t.Fatalf("Parse() = %+v, want the Malformed service using Memcached and Redis", result)
+	}
+
+	path := filepath.Join("testdata", "malformed", "malformed.go")
+	expected := []Warning{
+		{
+			Path:    path,
+			Line:    6,
+			Text:    "// service:\n// description: Relationship missing its action",
+			Message: "relationship without an action is ignored",
+		},
+		{
+			Path:    path,
+			Line:    9,
+			Text:    "// service:name\n// description: Service missing its name",
+			Message: "service definition without a name is ignored",
+		},
+		{
+			Path:    path,
+			Line:    18,
+			Text:    "// service:uses Memcached\n// technolgy: memcached",
+			Message: `unknown key "technolgy" is ignored, expected one of technology, description, proto, port, async, sync, env, deprecated`,
+		},
+	}
+
+	warnings := parser.Warnings()
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Warnings() = %+v, want %+v", warnings, expected)
+	}
+
+	if got, want := warnings[0].String(), path+":6: relationship without an action is ignored"; got != want {
+		t.Errorf("Warning.String() = %q, want %q", got, want)
+	}
+}
+
+func TestUnknownActionWarning(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser()
+
+	if _, err := parser.Parse("testdata/actions", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Warning{
+		{
+			Path:    filepath.Join("testdata", "actions", "catalog.go"),
+			Line:    5,
+			Text:    "service:usess PostgreSQL",
+			Message: `unknown relationship action "usess"`,
+		},
+	}
+
+	if warnings := parser.Warnings(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Warnings() = %+v, want %+v", warnings, expected)
+	}
+
+	_, err := NewCommentParser(WithStrictActions()).Parse("testdata/actions", true)
+	if err == nil || !strings.Contains(err.Error(), `catalog.go:5: unknown relationship action "usess"`) {
+		t.Errorf("Parse() in strict mode error = %v, want unknown relationship action", err)
+	}
+}
+
+func TestInvalidTimeoutWarning(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser()
+
+	if _, err := parser.Parse("testdata/sla/invalid", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Warning{
+		{
+			Path:    filepath.Join("testdata", "sla", "invalid", "checkout.go"),
+			Line:    6,
+			Text:    "// service:requests Billing timeout=soon\n// description: Charges customers",
+			Message: `invalid timeout "soon" is ignored: expected a duration such as 500ms or a number of milliseconds`,
+		},
+	}
+
+	if warnings := parser.Warnings(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Warnings() = %+v, want %+v", warnings, expected)
 	}
 }
 
-func TestParseFile(t *testing.T) {
+func TestInvalidValueWarnings(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
-		name                  string
-		filePath              string
-		expectedServices      []service
-		expectedRelationships []relationship
-		expectError           bool
+		name     string
+		line     string
+		expected string
 	}{
 		{
-			name:     "parse service file with comments",
-			filePath: "testdata/default/service/example/example.go",
-			expectedServices: []service{
-				{
-					name:        "Example",
-					description: "Example service for exampling stuff.",
-				},
-			},
-			expectedRelationships: []relationship{},
-			expectError:           false,
+			name:     "port",
+			line:     `// port: "70000"`,
+			expected: `invalid port "70000" is ignored: must be a number between 1 and 65535`,
 		},
 		{
-			name:             "parse file with relationship comments",
-			filePath:         "testdata/default/database/postgres/postgres.go",
-			expectedServices: []service{},
-			expectedRelationships: []relationship{
-				{
-					serviceName: "",
-					action:      "uses",
-					targetName:  "PostgreSQL",
-					description: "Stores user data and authentication tokens",
-					technology:  "postgresql",
-					proto:       "tcp",
-				},
-			},
-			expectError: false,
+			name:     "async",
+			line:     `// async: "maybe"`,
+			expected: `invalid async "maybe" is ignored: must be true or false`,
 		},
 		{
-			name:        "parse non-existent file",
-			filePath:    "testdata/nonexistent.go",
-			expectError: true,
+			name:     "deprecated",
+			line:     `// deprecated: soon`,
+			expected: `invalid deprecated "soon" is ignored: must be true or false`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			parser := NewCommentParser()
-			err := parser.parseFile(tt.filePath)
+			t.Parallel()
 
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("Expected error but got none")
-				}
-				return
-			}
+			src := "package orders\n\n// service:name Orders\n\n// service:uses PostgreSQL\n// technology: postgresql\n" + tt.line + "\n"
 
-			if err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
+			parser := NewCommentParser()
+			if err := parser.ParseSource("orders.go", src); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
 			}
 
-			if !compareServices(parser.services, tt.expectedServices) {
-				t.Errorf("parseFile() services = %+v, want %+v", parser.services, tt.expectedServices)
+			warnings := parser.Warnings()
+			if len(warnings) != 1 {
+				t.Fatalf("Warnings() = %+v, want a single warning", warnings)
 			}
 
-			if !compareRelationships(parser.relationships, tt.expectedRelationships) {
-				t.Errorf("parseFile() relationships = %+v, want %+v", parser.relationships, tt.expectedRelationships)
+			if got, want := warnings[0].String(), "orders.go:7: "+tt.expected; got != want {
+				t.Errorf("Warning = %q, want %q", got, want)
 			}
 		})
 	}
 }
 
-func TestParseCommentGroup(t *testing.T) {
+func TestRedeclaredService(t *testing.T) {
 	t.Parallel()
 
-	tests := []struct {
-		name                  string
-		commentGroup          string
-		expectedServices      []service
-		expectedRelationships []relationship
-	}{
-		{
-			name: "parse service name and description",
-			commentGroup: `/*
-service:name Example
-description: Example service for exampling stuff.
-*/`,
-			expectedServices: []service{
-				{
-					name:        "Example",
-					description: "Example service for exampling stuff.",
-				},
-			},
-			expectedRelationships: []relationship{},
-		},
-		{
-			name: "parse service name, description, and system",
-			commentGroup: `/*
-service:name UserService
-description: Handles user authentication and profiles
-system: e-commerce-platform
-*/`,
-			expectedServices: []service{
-				{
-					name:        "UserService",
-					description: "Handles user authentication and profiles",
-					system:      "e-commerce-platform",
-				},
-			},
-			expectedRelationships: []relationship{},
-		},
+	parser := NewCommentParser()
+
+	result, err := parser.Parse("testdata/redeclared", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []*servicefile.ServiceFile{
 		{
-			name: "parse relationship with all fields",
-			commentGroup: `/*
-service:uses PostgreSQL
-description: Stores user data and authentication tokens
-technology:postgresql
-proto:tcp
-*/`,
-			expectedServices: []service{},
-			expectedRelationships: []relationship{
-				{
-					serviceName: "",
-					action:      "uses",
-					targetName:  "PostgreSQL",
-					description: "Stores user data and authentication tokens",
-					technology:  "postgresql",
-					proto:       "tcp",
-				},
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        "Payments",
+				Description: "Moves money between accounts",
+				System:      "finance",
+				Owner:       "payments-team",
+				Source:      servicefile.Source{File: filepath.Join("testdata", "redeclared", "payments.go"), Line: 3},
 			},
-		},
-		{
-			name:                  "parse empty comment group",
-			commentGroup:          `/* */`,
-			expectedServices:      []service{},
-			expectedRelationships: []relationship{},
-		},
-		{
-			name: "parse comments starting with //",
-			commentGroup: `// service:name Example
-// description: Example service for exampling stuff.`,
-			expectedServices: []service{
+			Relationships: []servicefile.Relationship{
 				{
-					name:        "Example",
-					description: "Example service for exampling stuff.",
+					Action:     servicefile.RelationshipActionSends,
+					Name:       "Kafka",
+					Technology: "kafka",
+					Source:     servicefile.Source{File: filepath.Join("testdata", "redeclared", "refunds.go"), Line: 7},
 				},
-			},
-			expectedRelationships: []relationship{},
-		},
-		{
-			name: "parse mixed comments: regular golang comments first, then service comments with /* */",
-			commentGroup: `// User represents a user in the system
-// This struct contains all user-related fields
-/*
-service:uses PostgreSQL
-description: Stores user data and authentication tokens
-technology:postgresql
-proto:tcp
-*/`,
-			expectedServices: []service{},
-			expectedRelationships: []relationship{
 				{
-					serviceName: "",
-					action:      "uses",
-					targetName:  "PostgreSQL",
-					description: "Stores user data and authentication tokens",
-					technology:  "postgresql",
-					proto:       "tcp",
+					Action:     servicefile.RelationshipActionUses,
+					Name:       "PostgreSQL",
+					Technology: "postgresql",
+					Source:     servicefile.Source{File: filepath.Join("testdata", "redeclared", "payments.go"), Line: 7},
 				},
 			},
 		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want %+v", result, expected)
+	}
+
+	first := filepath.Join("testdata", "redeclared", "payments.go")
+	second := filepath.Join("testdata", "redeclared", "refunds.go")
+
+	expectedWarnings := []Warning{
 		{
-			name: "parse mixed comments: regular golang comments first, then service comments with //",
-			commentGroup: `// User represents a user in the system
-// This struct contains all user-related fields
-// service:uses PostgreSQL
-// description: Stores user data and authentication tokens
-// technology:postgresql
-// proto:tcp`,
-			expectedServices: []service{},
-			expectedRelationships: []relationship{
-				{
-					serviceName: "",
-					action:      "uses",
-					targetName:  "PostgreSQL",
-					description: "Stores user data and authentication tokens",
-					technology:  "postgresql",
-					proto:       "tcp",
-				},
-			},
+			Path:    second,
+			Line:    3,
+			Text:    "service:name Payments",
+			Message: `service "Payments" is already declared at ` + first + ":3, definitions are merged",
 		},
+	}
+
+	if warnings := parser.Warnings(); !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("Warnings() = %+v, want %+v", warnings, expectedWarnings)
+	}
+
+	if _, err := parser.Build(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if warnings := parser.Warnings(); !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("Warnings() after building again = %+v, want %+v", warnings, expectedWarnings)
+	}
+
+	_, err = NewCommentParser(WithStrictServices()).Parse("testdata/redeclared", true)
+	want := `service "Payments" is declared more than once: at ` + first + ":3 and at " + second + ":3"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Parse() in strict mode error = %v, want %q", err, want)
+	}
+}
+
+func TestGroupedTypeDeclaration(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser(WithStrictServices())
+
+	result, err := parser.Parse("testdata/grouped", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path := filepath.Join("testdata", "grouped", "orders.go")
+
+	expected := []*servicefile.ServiceFile{
 		{
-			name: "parse mixed comments: service comments with /* */ first, then regular golang comments",
-			commentGroup: `/*
-service:name Example
-description: Example service for exampling stuff.
-*/
-// User represents a user in the system
-// This struct contains all user-related fields`,
-			expectedServices: []service{
-				{
-					name:        "Example",
-					description: "Example service for exampling stuff.",
-				},
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        "Orders",
+				Description: "Takes orders",
+				System:      "shop",
+				Source:      servicefile.Source{File: path, Line: 4},
 			},
-			expectedRelationships: []relationship{},
-		},
-		{
-			name: "parse mixed comments: service comments with // first, then regular golang comments",
-			commentGroup: `// service:name Example
-// description: Example service for exampling stuff.
-// User represents a user in the system
-// This struct contains all user-related fields`,
-			expectedServices: []service{
+			Relationships: []servicefile.Relationship{
 				{
-					name:        "Example",
-					description: "Example service for exampling stuff.",
+					Action:     servicefile.RelationshipActionUses,
+					Name:       "PostgreSQL",
+					Technology: "postgresql",
+					Source:     servicefile.Source{File: path, Line: 9},
 				},
 			},
-			expectedRelationships: []relationship{},
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			parser := NewCommentParser()
-			parser.parseCommentGroup(tt.commentGroup)
-
-			if !compareServices(parser.services, tt.expectedServices) {
-				t.Errorf("parseCommentGroup() services = %+v, want %+v", parser.services, tt.expectedServices)
-			}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want %+v", result, expected)
+	}
 
-			if !compareRelationships(parser.relationships, tt.expectedRelationships) {
-				t.Errorf("parseCommentGroup() relationships = %+v, want %+v", parser.relationships, tt.expectedRelationships)
-			}
-		})
+	if warnings := parser.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0].Message, `unknown key "protocl"`) {
+		t.Errorf("Warnings() = %+v, want a single unknown key warning", warnings)
 	}
 }
 
-// compareServiceFiles compares two service file maps for equality
-func compareServiceFiles(actual, expected map[string]*servicefile.ServiceFile) bool {
-	if len(actual) != len(expected) {
-		return false
+func TestSelfRelationship(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser()
+
+	result, err := parser.Parse("testdata/selfloop", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	for serviceName, expectedService := range expected {
-		actualService, exists := actual[serviceName]
-		if !exists {
-			return false
-		}
+	if len(result) != 1 || len(result[0].Relationships) != 2 {
+		t.Fatalf("Parse() = %+v, want the self relationship kept", result)
+	}
 
-		if actualService.Info.Name != expectedService.Info.Name {
-			return false
-		}
+	expected := []Warning{
+		{
+			Path:    filepath.Join("testdata", "selfloop", "catalog.go"),
+			Line:    9,
+			Text:    "service:requests Catalog",
+			Message: `relationship targets its own service "Catalog"`,
+		},
+	}
 
-		if actualService.Info.Description != expectedService.Info.Description {
-			return false
-		}
+	if warnings := parser.Warnings(); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Warnings() = %+v, want %+v", warnings, expected)
+	}
 
-		if actualService.Info.System != expectedService.Info.System {
-			return false
-		}
+	_, err = NewCommentParser(WithStrictSelfRelationships()).Parse("testdata/selfloop", true)
+	if err == nil || !strings.Contains(err.Error(), `catalog.go:9: relationship targets its own service "Catalog"`) {
+		t.Errorf("Parse() in strict mode error = %v, want relationship targets its own service", err)
+	}
+}
 
-		if len(actualService.Relationships) != len(expectedService.Relationships) {
-			return false
+func TestParseWorkers(t *testing.T) {
+	t.Parallel()
+
+	type located struct {
+		relationship RawRelationship
+		position     string
+	}
+
+	parse := func(workers int) ([]*servicefile.ServiceFile, []located) {
+		parser := NewCommentParser(WithWorkers(workers))
+
+		result, err := parser.Parse("testdata/explicit", true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
 		}
 
-		// Compare relationships (order doesn't matter for this test)
-		for _, expectedRel := range expectedService.Relationships {
-			found := false
-			for _, actualRel := range actualService.Relationships {
-				if actualRel.Action == expectedRel.Action &&
-					actualRel.Name == expectedRel.Name &&
-					actualRel.Description == expectedRel.Description &&
-					actualRel.Technology == expectedRel.Technology &&
-					actualRel.Proto == expectedRel.Proto {
-					found = true
-					break
-				}
-			}
-			if !found {
-				return false
-			}
+		var raw []located
+		for _, r := range parser.RawRelationships() {
+			position := parser.FileSet().Position(r.Pos).String()
+			r.Pos, r.End, r.Attributes = token.NoPos, token.NoPos, nil
+			raw = append(raw, located{relationship: r, position: position})
 		}
+
+		return result, raw
 	}
 
-	return true
-}
+	sequentialResult, sequentialRaw := parse(1)
 
-// compareServices compares two service slices for equality
-func compareServices(actual, expected []service) bool {
-	if len(actual) != len(expected) {
-		return false
-	}
+	for range 10 {
+		concurrentResult, concurrentRaw := parse(8)
 
-	// Compare services (order doesn't matter for this test)
-	for _, expectedService := range expected {
-		found := false
-		for _, actualService := range actual {
-			if actualService.name == expectedService.name &&
-				actualService.description == expectedService.description &&
-				actualService.system == expectedService.system {
-				found = true
-				break
-			}
+		if !compareServiceFiles(serviceFilesByName(concurrentResult), serviceFilesByName(sequentialResult)) {
+			t.Errorf("Parse() with 8 workers = %+v, want %+v", concurrentResult, sequentialResult)
 		}
-		if !found {
-			return false
+
+		if !reflect.DeepEqual(concurrentRaw, sequentialRaw) {
+			t.Errorf("RawRelationships() with 8 workers = %+v, want %+v", concurrentRaw, sequentialRaw)
 		}
 	}
-
-	return true
 }
 
-// compareRelationships compares two relationship slices for equality
-func compareRelationships(actual, expected []relationship) bool {
-	if len(actual) != len(expected) {
-		return false
+func TestMatchExclude(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		rel     string
+		isDir   bool
+		want    bool
+	}{
+		{pattern: "**/mocks/", rel: "mocks", isDir: true, want: true},
+		{pattern: "**/mocks/", rel: "orders/mocks", isDir: true, want: true},
+		{pattern: "**/mocks/", rel: "orders/mocks", isDir: false, want: false},
+		{pattern: "**/mocks/", rel: "orders/mocksy", isDir: true, want: false},
+		{pattern: "**/*.pb.go", rel: "api/v1/orders.pb.go", want: true},
+		{pattern: "**/*.pb.go", rel: "orders.pb.go", want: true},
+		{pattern: "**/*.pb.go", rel: "api/orders.go", want: false},
+		{pattern: "*.pb.go", rel: "api/orders.pb.go", want: false},
+		{pattern: "api/*.go", rel: "api/orders.go", want: true},
+		{pattern: "internal", rel: "internal", isDir: true, want: true},
 	}
 
-	// Compare relationships (order doesn't matter for this test)
-	for _, expectedRel := range expected {
-		found := false
-		for _, actualRel := range actual {
-			if actualRel.serviceName == expectedRel.serviceName &&
-				actualRel.action == expectedRel.action &&
-				actualRel.targetName == expectedRel.targetName &&
-				actualRel.technology == expectedRel.technology &&
-				actualRel.description == expectedRel.description &&
-				actualRel.proto == expectedRel.proto {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
+	for _, tt := range tests {
+		if got := matchExclude(tt.pattern, tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("matchExclude(%q, %q, %v) = %v, want %v", tt.pattern, tt.rel, tt.isDir, got, tt.want)
 		}
 	}
-
-	return true
 }
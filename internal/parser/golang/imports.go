@@ -0,0 +1,89 @@
+package golang
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// DefaultImportTechnologies returns the technologies implied by the import of common client packages,
+// keyed by import path.
+func DefaultImportTechnologies() map[string]string {
+	return map[string]string{
+		"github.com/lib/pq":                          "postgresql",
+		"github.com/jackc/pgx":                       "postgresql",
+		"github.com/go-sql-driver/mysql":             "mysql",
+		"github.com/redis/go-redis":                  "redis",
+		"github.com/go-redis/redis":                  "redis",
+		"github.com/segmentio/kafka-go":              "kafka",
+		"github.com/IBM/sarama":                      "kafka",
+		"github.com/Shopify/sarama":                  "kafka",
+		"github.com/confluentinc/confluent-kafka-go": "kafka",
+		"github.com/rabbitmq/amqp091-go":             "rabbitmq",
+		"github.com/streadway/amqp":                  "rabbitmq",
+		"github.com/nats-io/nats.go":                 "nats",
+		"go.mongodb.org/mongo-driver":                "mongodb",
+		"github.com/elastic/go-elasticsearch":        "elasticsearch",
+		"google.golang.org/grpc":                     "grpc",
+	}
+}
+
+// WithImportTechnologies makes the parser infer the technology of relationships annotated without one
+// from the imports of their file: when the imports of the file imply a single technology, it is used.
+// Technologies are looked up in technologies by import path, a path also matching the packages below it,
+// DefaultImportTechnologies being used when technologies is nil. Annotated technologies always win.
+func WithImportTechnologies(technologies map[string]string) Option {
+	return func(cp *CommentParser) {
+		if technologies == nil {
+			technologies = DefaultImportTechnologies()
+		}
+		cp.opts.ImportTechnologies = technologies
+	}
+}
+
+// inferTechnologies sets the technology implied by the imports of f on the relationships found in f
+// without technology. Nothing is inferred when the imports imply no technology or several ones.
+func (cp *CommentParser) inferTechnologies(found *annotations, f *ast.File) {
+	var inferred string
+
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		technology := cp.importTechnology(importPath)
+		switch {
+		case technology == "" || technology == inferred:
+			continue
+		case inferred != "":
+			return
+		}
+
+		inferred = technology
+	}
+
+	if inferred == "" {
+		return
+	}
+
+	for i := range found.Relationships {
+		if found.Relationships[i].Technology == "" {
+			found.Relationships[i].Technology = inferred
+		}
+	}
+}
+
+// importTechnology returns the technology of the longest import path of importTechnologies
+// that is importPath or one of its parents, if any.
+func (cp *CommentParser) importTechnology(importPath string) string {
+	var match, technology string
+
+	for p, tech := range cp.opts.ImportTechnologies {
+		if (importPath == p || strings.HasPrefix(importPath, p+"/")) && len(p) > len(match) {
+			match, technology = p, tech
+		}
+	}
+
+	return technology
+}
@@ -0,0 +1,246 @@
+package golang
+
+import (
+	"go/ast"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// InjectionRule describes how a dependency injection container registers and resolves components.
+// Calls matching the rule are turned into relationships between the service declared in the
+// calling package and the service declared in the package of the referenced component.
+type InjectionRule struct {
+	// Packages are the import paths of the container package.
+	Packages []string
+	// Invoke lists the functions resolving a component given as the type parameter.
+	Invoke []string
+	// Provide lists the functions registering a component given as the last argument.
+	Provide []string
+	// Action is the action of the discovered relationships.
+	Action servicefile.RelationshipAction
+}
+
+// DoInjectionRule returns the rule matching the github.com/samber/do container.
+func DoInjectionRule() InjectionRule {
+	return InjectionRule{
+		Packages: []string{"github.com/samber/do", "github.com/samber/do/v2"},
+		Invoke:   []string{"Invoke", "InvokeNamed", "MustInvoke", "MustInvokeNamed"},
+		Provide:  []string{"Provide", "ProvideNamed", "ProvideValue", "ProvideNamedValue", "Override", "OverrideNamed", "OverrideValue", "OverrideNamedValue"},
+		Action:   servicefile.RelationshipActionUses,
+	}
+}
+
+// WithInjectionRules enables discovery of relationships from dependency injection container calls.
+func WithInjectionRules(rules ...InjectionRule) Option {
+	return func(cp *CommentParser) {
+		cp.opts.InjectionRules = append(cp.opts.InjectionRules, rules...)
+	}
+}
+
+// injection is a reference to a component of another package found in a container call.
+type injection struct {
+	dir        string
+	importPath string
+	action     servicefile.RelationshipAction
+	span       annotation.Span
+}
+
+func (cp *CommentParser) collectInjections(found *annotations, dir string, f *ast.File) {
+	imports := make(map[string]string, len(f.Imports))
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		name := importName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		fun, typeArgs := unpackIndex(call.Fun)
+
+		sel, ok := fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		for _, rule := range cp.opts.InjectionRules {
+			if !slices.Contains(rule.Packages, imports[pkg.Name]) {
+				continue
+			}
+
+			var component ast.Expr
+			switch {
+			case slices.Contains(rule.Invoke, sel.Sel.Name) && len(typeArgs) > 0:
+				component = typeArgs[0]
+			case slices.Contains(rule.Provide, sel.Sel.Name) && len(typeArgs) > 0:
+				component = typeArgs[0]
+			case slices.Contains(rule.Provide, sel.Sel.Name) && len(call.Args) > 0:
+				component = call.Args[len(call.Args)-1]
+			default:
+				continue
+			}
+
+			importPath := imports[componentPackage(component)]
+			if importPath == "" {
+				continue
+			}
+
+			found.injections = append(found.injections, injection{
+				dir:        dir,
+				importPath: importPath,
+				action:     rule.Action,
+				span:       annotation.Span{Pos: call.Pos(), End: call.End()},
+			})
+		}
+
+		return true
+	})
+}
+
+// resolveInjections turns collected injections into relationships between services.
+// Injections are skipped when either side has no service declared in its package.
+func (cp *CommentParser) resolveInjections() []annotation.Relationship {
+	relationships := make([]annotation.Relationship, 0, len(cp.injections))
+
+	for _, inj := range cp.injections {
+		source, ok := annotation.ServiceInDir(cp.services, inj.dir)
+		if !ok {
+			continue
+		}
+
+		target, ok := cp.serviceForImport(inj.importPath)
+		if !ok || target == source {
+			continue
+		}
+
+		relationships = append(relationships, annotation.Relationship{
+			Service:    source,
+			Action:     string(inj.action),
+			Target:     target,
+			Dir:        inj.dir,
+			Discovered: true,
+			Span:       inj.span,
+		})
+	}
+
+	return relationships
+}
+
+// serviceForImport returns the service declared in the directory that importPath most likely points to,
+// that is the directory whose path relative to a parsed root is the longest suffix of importPath.
+func (cp *CommentParser) serviceForImport(importPath string) (string, bool) {
+	var (
+		best    string
+		bestLen int
+	)
+
+	for _, s := range cp.services {
+		for _, rel := range cp.relativeDirs(s.Dir) {
+			rel = filepath.ToSlash(rel)
+			if rel == "." || (importPath != rel && !strings.HasSuffix(importPath, "/"+rel)) {
+				continue
+			}
+
+			if len(rel) > bestLen {
+				best, bestLen = s.Dir, len(rel)
+			}
+		}
+	}
+
+	if best == "" {
+		return "", false
+	}
+
+	return annotation.ServiceInDir(cp.services, best)
+}
+
+// relativeDirs returns the paths of dir relative to the parsed roots containing it,
+// dir itself when no root was parsed.
+func (cp *CommentParser) relativeDirs(dir string) []string {
+	if len(cp.roots) == 0 {
+		return []string{dir}
+	}
+
+	var rels []string
+	for _, root := range cp.roots {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rels = append(rels, rel)
+	}
+
+	return rels
+}
+
+// unpackIndex splits an instantiated generic function into the function and its type arguments.
+func unpackIndex(expr ast.Expr) (ast.Expr, []ast.Expr) {
+	switch x := expr.(type) {
+	case *ast.IndexExpr:
+		return x.X, []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		return x.X, x.Indices
+	default:
+		return expr, nil
+	}
+}
+
+// componentPackage returns the package identifier qualifying a type, value or constructor expression.
+func componentPackage(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.StarExpr:
+		return componentPackage(x.X)
+	case *ast.UnaryExpr:
+		return componentPackage(x.X)
+	case *ast.CompositeLit:
+		return componentPackage(x.Type)
+	case *ast.CallExpr:
+		fun, _ := unpackIndex(x.Fun)
+		return componentPackage(fun)
+	case *ast.IndexExpr:
+		return componentPackage(x.X)
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); ok {
+			return pkg.Name
+		}
+	}
+
+	return ""
+}
+
+// importName returns the default name a package is imported under.
+func importName(importPath string) string {
+	name := path.Base(importPath)
+
+	if len(name) > 1 && name[0] == 'v' {
+		if _, err := strconv.Atoi(name[1:]); err == nil {
+			name = path.Base(path.Dir(importPath))
+		}
+	}
+
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+
+	return name
+}
@@ -0,0 +1,7 @@
+// service:name Catalog
+// description: Lists products
+package catalog
+
+// service:usess PostgreSQL
+// description: Stores products
+// technology:postgresql
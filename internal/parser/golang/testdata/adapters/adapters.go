@@ -0,0 +1,29 @@
+// Package adapters hosts small adapters deployed as separate services.
+package adapters
+
+// service:name SMSAdapter
+// description: Forwards notifications as text messages
+
+// SMSClient talks to the SMS provider.
+//
+// service:requests Twilio
+// description: Sends text messages
+// technology:http
+type SMSClient struct{}
+
+// service:name MailAdapter
+// description: Forwards notifications as emails
+
+// MailClient talks to the mail provider.
+//
+// service:requests SendGrid
+// description: Sends emails
+// technology:http
+type MailClient struct{}
+
+// MailQueue buffers outgoing emails.
+//
+// service:receives Notifications
+// description: Consumes email notifications
+// technology:kafka
+type MailQueue struct{}
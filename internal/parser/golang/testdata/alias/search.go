@@ -0,0 +1,11 @@
+package search
+
+// service:name SearchService
+// alias: search
+// description: Finds products in the catalog
+// system: shop
+type Service struct{}
+
+// service:uses Elasticsearch
+// technology: elasticsearch
+type index struct{}
@@ -0,0 +1,19 @@
+package orders
+
+// service:name Orders
+// description: Takes orders
+
+// Publisher announces placed orders.
+//
+// service:uses Kafka
+// description: Publishes placed orders
+// technology: kafka
+// async: true
+type Publisher struct{}
+
+// Client charges orders.
+//
+// service:requests Billing
+// technology: grpc
+// sync: true
+type Client struct{}
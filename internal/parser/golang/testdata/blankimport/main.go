@@ -0,0 +1,22 @@
+// service:name Accounts
+// description: Manages customer accounts
+package main
+
+import (
+	"database/sql"
+	_ "embed"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+	_ "go.uber.org/automaxprocs"
+)
+
+// Store persists accounts.
+//
+// service:uses pgx
+// description: Stores accounts
+// technology:postgresql
+// proto:tcp
+type Store struct {
+	db *sql.DB
+}
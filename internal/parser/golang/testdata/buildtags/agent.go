@@ -0,0 +1,7 @@
+// service:name Agent
+// description: Collects host metrics
+package agent
+
+// service:sends Metrics
+// technology:kafka
+type Reporter struct{}
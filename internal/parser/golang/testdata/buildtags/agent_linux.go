@@ -0,0 +1,5 @@
+package agent
+
+// service:uses Journald
+// description: Reads the system logs
+type Journal struct{}
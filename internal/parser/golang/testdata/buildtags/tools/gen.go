@@ -0,0 +1,7 @@
+//go:build ignore
+
+// service:name Generator
+// description: Generates the metric definitions, run with go run gen.go
+package main
+
+func main() {}
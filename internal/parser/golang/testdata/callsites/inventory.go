@@ -0,0 +1,36 @@
+// service:name Inventory
+// description: Tracks the stock of products
+package inventory
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/redis/go-redis/v9"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// Cache keeps stock levels close to the service.
+//
+// service:uses Redis
+// description: Caches stock levels
+type Cache struct {
+	client *redis.Client
+}
+
+func NewCache() *Cache {
+	return &Cache{client: redis.NewClient(&redis.Options{Addr: "localhost:6379"})}
+}
+
+func OpenStore(dsn string) (*sql.DB, error) {
+	return sql.Open("postgres", dsn)
+}
+
+func NewPublisher() *kafka.Writer {
+	return kafka.NewWriter(kafka.WriterConfig{Topic: "stock"})
+}
+
+func Healthy(client *http.Client) bool {
+	_, err := client.Get("http://localhost/healthz")
+	return err == nil
+}
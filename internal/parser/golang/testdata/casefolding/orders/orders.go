@@ -0,0 +1,14 @@
+package orders
+
+/*
+service:name Orders
+description: Accepts and tracks customer orders
+*/
+type Service struct{}
+
+/*
+service:sends Kafka
+description: Publishes order events
+technology:kafka
+*/
+type Publisher struct{}
@@ -0,0 +1,8 @@
+package publisher
+
+/*
+service:sends kafka
+description: Publishes order events
+technology:kafka
+*/
+type Producer struct{}
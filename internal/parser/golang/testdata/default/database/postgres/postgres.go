@@ -7,6 +7,7 @@ service:uses PostgreSQL
 description: Stores user data and authentication tokens
 technology:postgresql
 proto:tcp
+port: 5432
 */
 type Connection struct{}
 
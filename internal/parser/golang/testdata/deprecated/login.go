@@ -0,0 +1,20 @@
+package deprecated
+
+// service:name Login
+// description: Signs users in
+type Login struct{}
+
+// Verifier checks credentials against the identity provider.
+//
+// service:requests Auth
+// description: Verifies credentials
+// technology: grpc
+type Verifier struct{}
+
+// LegacyVerifier checks credentials against the old identity provider.
+//
+// service:requests LegacyAuth
+// description: Kept until every client sends v2 tokens, traffic is routed to Auth
+// technology: http
+// deprecated: true
+type LegacyVerifier struct{}
@@ -0,0 +1,8 @@
+package orders
+
+// service:name Orders
+// description: Takes orders
+
+// service:Orders:uses PostgreSQL
+// technology:postgresql
+// proto:tcp
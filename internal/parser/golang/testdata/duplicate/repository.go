@@ -0,0 +1,22 @@
+package orders
+
+// service:Orders:uses PostgreSQL
+// description: Stores orders
+// technology:postgresql
+// proto:tcp
+
+// service:Orders:uses PostgreSQL
+// description: Reads reporting replicas
+// technology:postgresql
+// proto:tcp
+// runbook: wiki/replicas
+
+// service:Orders:uses PostgreSQL
+// description: Migrates the schema
+// technology:pgx
+
+// service:Orders:uses PostgreSQL
+// description: Stores orders of the staging environment
+// technology:postgresql
+// proto:tcp
+// env: staging
@@ -0,0 +1,5 @@
+package api
+
+// service:replies Storefront
+// description: Serves orders
+// technology:grpc
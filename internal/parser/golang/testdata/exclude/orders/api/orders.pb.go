@@ -0,0 +1,4 @@
+package api
+
+// service:requests Generated
+// description: Generated code that must not be parsed
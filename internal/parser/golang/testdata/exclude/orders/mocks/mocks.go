@@ -0,0 +1,4 @@
+package mocks
+
+// service:name MockOrders
+// description: Generated mock that must not be parsed
@@ -0,0 +1,8 @@
+package orders
+
+// service:name Orders
+// description: Takes orders
+
+// service:uses PostgreSQL
+// description: Stores orders
+// technology:postgresql
@@ -0,0 +1,13 @@
+package orders
+
+type (
+	// service:name Orders
+	// description: Takes orders
+	// system: shop
+	Orders struct{}
+
+	// service:Orders:uses PostgreSQL
+	// technology:postgresql
+	// protocl: sql
+	store struct{}
+)
@@ -0,0 +1,13 @@
+package billing
+
+import "github.com/samber/do"
+
+/*
+service:name Billing
+description: Charges customers for their orders
+*/
+type Client struct{}
+
+func NewClient(i *do.Injector) (*Client, error) {
+	return &Client{}, nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/samber/do"
+
+	"example.com/injection/billing"
+	"example.com/injection/notify"
+	"example.com/injection/orders"
+)
+
+func main() {
+	i := do.New()
+
+	do.Provide(i, billing.NewClient)
+	do.Provide(i, notify.NewSender)
+	do.Provide(i, orders.NewService)
+
+	_ = do.MustInvoke[*orders.Service](i)
+}
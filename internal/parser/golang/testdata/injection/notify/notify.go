@@ -0,0 +1,13 @@
+package notify
+
+import "github.com/samber/do"
+
+/*
+service:name Notify
+description: Notifies customers about their orders
+*/
+type Sender struct{}
+
+func NewSender(i *do.Injector) (*Sender, error) {
+	return &Sender{}, nil
+}
@@ -0,0 +1,28 @@
+package orders
+
+import (
+	"github.com/samber/do"
+
+	"example.com/injection/billing"
+	"example.com/injection/notify"
+)
+
+/*
+service:name Orders
+description: Accepts and tracks customer orders
+*/
+type Service struct {
+	billing *billing.Client
+	sender  *notify.Sender
+}
+
+// service:Orders:uses PostgreSQL
+// description: Stores orders
+// technology:postgresql
+
+func NewService(i *do.Injector) (*Service, error) {
+	return &Service{
+		billing: do.MustInvoke[*billing.Client](i),
+		sender:  do.MustInvoke[*notify.Sender](i),
+	}, nil
+}
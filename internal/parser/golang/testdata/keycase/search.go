@@ -0,0 +1,13 @@
+package keycase
+
+// service:name Search
+// Description: Indexes the product catalog
+// System: storefront
+type Search struct{}
+
+// Index keeps the searchable copy of the catalog.
+//
+// service:uses Elasticsearch
+// Description: Stores the product index
+// technology: elasticsearch
+type Index struct{}
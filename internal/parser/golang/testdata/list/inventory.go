@@ -0,0 +1,28 @@
+// Package inventory describes itself with doc comment lists, as formatted by gofmt.
+//
+//   - service:name Inventory
+//   - description: Tracks stock levels
+package inventory
+
+// Store keeps stock levels close to the service.
+//
+//   - service:uses Redis
+//   - description: Caches stock levels
+//   - technology: redis
+//   - proto: tcp
+type Store struct{}
+
+// Publisher announces stock changes.
+//
+//  1. service:sends Kafka
+//  2. description: Publishes stock changes
+//  3. technology: kafka
+type Publisher struct{}
+
+/*
+ * service:requests Warehouse
+ * description: Requests restocking
+ * technology: warehouse-api
+ * proto: http
+ */
+type Restocker struct{}
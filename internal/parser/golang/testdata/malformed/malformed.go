@@ -0,0 +1,18 @@
+package malformed
+
+// service:name Malformed
+// description: Has a few broken annotations
+
+// service:
+// description: Relationship missing its action
+
+// service:name
+// description: Service missing its name
+
+// The service: keyword in prose is not an annotation.
+
+// service:uses Redis
+// technology:redis
+
+// service:uses Memcached
+// technolgy: memcached
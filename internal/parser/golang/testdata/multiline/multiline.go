@@ -0,0 +1,22 @@
+package multiline
+
+/*
+service:name Ledger
+description: Keeps the double-entry ledger of every account,
+
+	reconciling it nightly against the bank statements
+	and reporting discrepancies to finance.
+
+system: payments
+*/
+type Ledger struct{}
+
+// Store persists ledger entries.
+//
+// service:uses PostgreSQL
+// description: Stores ledger entries
+// partitioned by month
+// technology:postgresql
+//
+// Entries are never updated, only appended.
+type Store struct{}
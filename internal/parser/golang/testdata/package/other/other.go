@@ -0,0 +1,8 @@
+package other
+
+// service:name Other
+// description: Lives in a package without shared relationships
+
+// service:Other:uses Redis
+// description: Caches responses
+// technology:redis
@@ -0,0 +1,8 @@
+package shared
+
+// service:name API
+// description: Serves the public API
+
+// service:API:sends Jobs
+// description: Enqueues background jobs
+// technology:rabbitmq
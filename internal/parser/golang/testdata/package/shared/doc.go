@@ -0,0 +1,6 @@
+// Package shared hosts the API and the worker processing its jobs.
+//
+// service:all:uses Logger
+// description: Ships structured logs
+// technology:loki
+package shared
@@ -0,0 +1,8 @@
+package shared
+
+// service:name Worker
+// description: Processes background jobs
+
+// service:Worker:receives Jobs
+// description: Consumes background jobs
+// technology:rabbitmq
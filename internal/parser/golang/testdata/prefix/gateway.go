@@ -0,0 +1,18 @@
+package gateway
+
+/*
+arch:name Gateway
+description: Routes public traffic to the backend services
+*/
+type Gateway struct{}
+
+// Forward sends the request to the orders service: the only backend for now.
+//
+// arch:requests Orders
+// technology: http
+// description: Forwards order requests
+func (g *Gateway) Forward() {}
+
+// service:uses Redis
+// technology: redis
+func (g *Gateway) Cache() {}
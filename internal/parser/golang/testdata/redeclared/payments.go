@@ -0,0 +1,8 @@
+package payments
+
+// service:name Payments
+// description: Moves money between accounts
+// system: finance
+
+// service:Payments:uses PostgreSQL
+// technology:postgresql
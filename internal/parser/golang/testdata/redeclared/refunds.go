@@ -0,0 +1,8 @@
+package payments
+
+// service:name Payments
+// description: Refunds customers
+// owner: payments-team
+
+// service:Payments:sends Kafka
+// technology:kafka
@@ -0,0 +1,10 @@
+package catalog
+
+// service:name Catalog
+// description: Lists the products on sale
+
+// service:uses PostgreSQL
+// technology:postgresql
+
+// service:requests Catalog
+// description: Meant for the pricing service, which isn't declared here
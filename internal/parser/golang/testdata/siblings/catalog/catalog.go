@@ -0,0 +1,5 @@
+// Package catalog declares its service once, relationships live next to the code using them.
+package catalog
+
+// service:name Catalog
+// description: Manages the product catalog
@@ -0,0 +1,8 @@
+package catalog
+
+// PricingClient fetches prices.
+//
+// service:requests Pricing
+// description: Fetches product prices
+// technology:grpc
+type PricingClient struct{}
@@ -0,0 +1,8 @@
+package catalog
+
+// Handler serves catalog requests.
+//
+// service:replies Storefront
+// description: Serves product pages
+// technology:http
+type Handler struct{}
@@ -0,0 +1,8 @@
+package catalog
+
+// Repository stores products.
+//
+// service:uses PostgreSQL
+// description: Stores products
+// technology:postgresql
+type Repository struct{}
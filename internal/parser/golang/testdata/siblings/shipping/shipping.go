@@ -0,0 +1,8 @@
+package shipping
+
+// service:name Shipping
+// description: Ships orders
+
+// service:sends Carrier
+// description: Books deliveries
+// technology:http
@@ -0,0 +1,3 @@
+// service:name Hidden
+// description: Hidden directory that must not be parsed by default
+package hidden
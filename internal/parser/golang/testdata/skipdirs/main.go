@@ -0,0 +1,3 @@
+// service:name Gateway
+// description: Routes public traffic
+package main
@@ -0,0 +1,7 @@
+package checkout
+
+// service:name Checkout
+// description: Turns carts into orders
+
+// service:requests Billing timeout=soon
+// description: Charges customers
@@ -0,0 +1,18 @@
+package checkout
+
+// service:name Checkout
+// description: Turns carts into orders
+
+// Client charges carts.
+//
+// service:requests Billing sla=p99<200ms timeout=500ms
+// description: Charges customers
+// technology:grpc
+type Client struct{}
+
+// Stock reserves items.
+//
+// service:requests Inventory timeout=1500
+// description: Reserves stock
+// technology:http
+type Stock struct{}
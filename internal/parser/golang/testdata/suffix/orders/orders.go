@@ -0,0 +1,21 @@
+package orders
+
+import (
+	"github.com/samber/do"
+
+	"example.com/suffix/x/billing"
+)
+
+/*
+service:name Orders
+description: Accepts and tracks customer orders
+*/
+type Service struct {
+	billing *billing.Client
+}
+
+func NewService(i *do.Injector) (*Service, error) {
+	return &Service{
+		billing: do.MustInvoke[*billing.Client](i),
+	}, nil
+}
@@ -0,0 +1,13 @@
+package billing
+
+import "github.com/samber/do"
+
+/*
+service:name Invoicing
+description: Issues invoices for the orders
+*/
+type Client struct{}
+
+func NewClient(i *do.Injector) (*Client, error) {
+	return &Client{}, nil
+}
@@ -0,0 +1,3 @@
+// service:name Orders
+// description: Takes orders
+package orders
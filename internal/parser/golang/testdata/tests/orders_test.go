@@ -0,0 +1,4 @@
+package orders
+
+// service:name FakeOrders
+// description: Example service declared by a test
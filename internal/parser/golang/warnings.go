@@ -0,0 +1,30 @@
+package golang
+
+import (
+	"sort"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+)
+
+// Warning is an annotation that looks like a service annotation but was ignored.
+type Warning = annotation.Warning
+
+// Warnings returns the warnings collected so far, sorted by location: the warnings of the annotations
+// parsed and the ones of the last Build.
+func (cp *CommentParser) Warnings() []Warning {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	warnings := make([]Warning, 0, len(cp.warnings)+len(cp.buildWarnings))
+	warnings = append(warnings, cp.warnings...)
+	warnings = append(warnings, cp.buildWarnings...)
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Path != warnings[j].Path {
+			return warnings[i].Path < warnings[j].Path
+		}
+		return warnings[i].Line < warnings[j].Line
+	})
+
+	return warnings
+}
@@ -0,0 +1,154 @@
+package openapi
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProto is the proto of relationships that don't set one.
+const DefaultProto = "http"
+
+// Parser extracts service files from OpenAPI 3 documents.
+// The service is described by the info object of the document, its system being read from
+// the x-servicefile-system extension of info, and its relationships are read from the
+// x-servicefile-relationships extension of the document, an array of relationships
+// with the keys of the service file specification.
+//
+// Example:
+//
+//	x-servicefile-relationships:
+//	  - action: requests
+//	    name: Billing
+//	    description: Charges orders
+//	    technology: rest
+type Parser struct{}
+
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// document is the part of an OpenAPI document describing a service.
+type document struct {
+	OpenAPI string `yaml:"openapi"`
+	Info    struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+		System      string `yaml:"x-servicefile-system"`
+	} `yaml:"info"`
+	Relationships []servicefile.Relationship `yaml:"x-servicefile-relationships"`
+}
+
+// Parse parses the OpenAPI documents of dir, and of its subdirectories when recursive is set,
+// and builds the service files they describe. Documents are the files named openapi or ending
+// with .openapi, with a .yaml, .yml or .json extension.
+func (p *Parser) Parse(dir string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	var (
+		result []*servicefile.ServiceFile
+		paths  = make(map[string]string)
+	)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk the path: %w", err)
+		}
+
+		if d.IsDir() {
+			if path != dir && (!recursive || strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isDocument(d.Name()) {
+			return nil
+		}
+
+		sf, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+
+		if other, exists := paths[sf.Info.Name]; exists {
+			return fmt.Errorf("service %q is defined in both %s and %s", sf.Info.Name, other, path)
+		}
+		paths[sf.Info.Name] = path
+
+		result = append(result, sf)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking the path: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no services found")
+	}
+
+	return result, nil
+}
+
+// isDocument reports whether the file name is the one of an OpenAPI document.
+func isDocument(name string) bool {
+	ext := filepath.Ext(name)
+	if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		return false
+	}
+
+	base := strings.TrimSuffix(name, ext)
+
+	return base == "openapi" || strings.HasSuffix(base, ".openapi")
+}
+
+func parseFile(path string) (*servicefile.ServiceFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// JSON documents are valid YAML.
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s: unsupported OpenAPI version %q, expected 3.x", path, doc.OpenAPI)
+	}
+
+	if doc.Info.Title == "" {
+		return nil, fmt.Errorf("%s: info has no title", path)
+	}
+
+	sf := &servicefile.ServiceFile{
+		Version: servicefile.Version,
+		Info: servicefile.Info{
+			Name:        doc.Info.Title,
+			Description: doc.Info.Description,
+			System:      doc.Info.System,
+		},
+		Relationships: []servicefile.Relationship{},
+	}
+
+	for i, rel := range doc.Relationships {
+		if !rel.Action.IsValid() {
+			return nil, fmt.Errorf("%s: relationship %d has unknown action %q", path, i, rel.Action)
+		}
+
+		if rel.Proto == "" {
+			rel.Proto = DefaultProto
+		}
+
+		sf.Relationships = append(sf.Relationships, rel)
+	}
+
+	sf.Deduplicate()
+
+	return sf, nil
+}
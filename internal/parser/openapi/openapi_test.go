@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	result, err := NewParser().Parse("testdata", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Info.Name < result[j].Info.Name
+	})
+
+	expected := []*servicefile.ServiceFile{
+		{
+			Version:       servicefile.Version,
+			Info:          servicefile.Info{Name: "Billing"},
+			Relationships: []servicefile.Relationship{},
+		},
+		{
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        "Orders",
+				Description: "Takes orders",
+				System:      "shop",
+			},
+			Relationships: []servicefile.Relationship{
+				{
+					Action:      servicefile.RelationshipActionRequests,
+					Name:        "Billing",
+					Description: "Charges orders",
+					Technology:  "rest",
+					Proto:       "http",
+				},
+				{
+					Action:     servicefile.RelationshipActionSends,
+					Name:       "Kafka",
+					Technology: "kafka",
+					Proto:      "kafka",
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want %+v", result, expected)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		document      string
+		expectedError string
+	}{
+		{
+			name:          "swagger document",
+			document:      "swagger: \"2.0\"\ninfo:\n  title: Orders\n",
+			expectedError: `unsupported OpenAPI version ""`,
+		},
+		{
+			name:          "missing title",
+			document:      "openapi: 3.0.0\ninfo:\n  version: 1.0.0\n",
+			expectedError: "info has no title",
+		},
+		{
+			name:          "unknown action",
+			document:      "openapi: 3.0.0\ninfo:\n  title: Orders\nx-servicefile-relationships:\n  - action: calls\n    name: Billing\n",
+			expectedError: `relationship 0 has unknown action "calls"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte(tt.document), 0o644); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			_, err := NewParser().Parse(dir, true)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.expectedError)
+			}
+		})
+	}
+}
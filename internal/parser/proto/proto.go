@@ -0,0 +1,236 @@
+package proto
+
+import (
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// DefaultProto is the proto of relationships annotated on rpc declarations that don't set one.
+const DefaultProto = "grpc"
+
+// CommentParser extracts service files from annotations in the leading comments of
+// protobuf service and rpc declarations. Annotations are the ones of the Go comment parser:
+// a service declaration usually carries a service definition, and its rpc declarations
+// the relationships of the service, whose proto defaults to DefaultProto.
+type CommentParser struct {
+	found annotation.Annotations
+	fset  *token.FileSet
+
+	foldTargetCase bool
+	collectUnknown bool
+	strictActions  bool
+	strictServices bool
+	prefix         string
+}
+
+// Option configures a CommentParser.
+type Option func(*CommentParser)
+
+// WithTargetCaseFolding makes the parser compare relationship targets and service names
+// case-insensitively, so that Kafka and kafka are treated as the same component.
+// The first seen casing is kept for output, declared service names taking precedence.
+func WithTargetCaseFolding() Option {
+	return func(cp *CommentParser) {
+		cp.foldTargetCase = true
+	}
+}
+
+// WithCollectUnknown makes the parser keep "key: value" lines of annotations that don't match
+// any known key as annotations of the service or relationship they belong to.
+func WithCollectUnknown() Option {
+	return func(cp *CommentParser) {
+		cp.collectUnknown = true
+	}
+}
+
+// WithStrictActions makes Parse fail on relationships whose action is not one of the actions
+// of the specification, instead of keeping them with a warning.
+func WithStrictActions() Option {
+	return func(cp *CommentParser) {
+		cp.strictActions = true
+	}
+}
+
+// WithStrictServices makes Parse fail on services declared more than once, naming both declarations,
+// instead of merging their definitions with a warning, the first non-empty fields winning.
+func WithStrictServices() Option {
+	return func(cp *CommentParser) {
+		cp.strictServices = true
+	}
+}
+
+// WithPrefix sets the marker starting service annotations, annotation.DefaultPrefix by default,
+// so that a codebase whose comments mention service: in prose can switch to another marker, such as arch:.
+func WithPrefix(prefix string) Option {
+	return func(cp *CommentParser) {
+		cp.prefix = prefix
+	}
+}
+
+func NewCommentParser(opts ...Option) *CommentParser {
+	cp := &CommentParser{
+		fset: token.NewFileSet(),
+	}
+
+	for _, opt := range opts {
+		opt(cp)
+	}
+
+	return cp
+}
+
+// Warning is an annotation that looks like a service annotation but was ignored.
+type Warning = annotation.Warning
+
+// Warnings returns the warnings collected so far, sorted by location.
+func (cp *CommentParser) Warnings() []Warning {
+	warnings := make([]Warning, len(cp.found.Warnings))
+	copy(warnings, cp.found.Warnings)
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Path != warnings[j].Path {
+			return warnings[i].Path < warnings[j].Path
+		}
+		return warnings[i].Line < warnings[j].Line
+	})
+
+	return warnings
+}
+
+// Parse parses the protobuf files of dir, and of its subdirectories when recursive is set,
+// and builds the service files they describe.
+func (cp *CommentParser) Parse(dir string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk the path: %w", err)
+		}
+
+		if d.IsDir() {
+			if path != dir && (!recursive || skippedDir(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".proto") {
+			return nil
+		}
+
+		return cp.parseFile(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking the path: %w", err)
+	}
+
+	result, warnings, err := annotation.Build(cp.found, nil, cp.annotationOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	cp.found.Warnings = append(cp.found.Warnings, warnings...)
+
+	return result, nil
+}
+
+// skippedDir reports whether a directory is skipped: third-party code and hidden directories.
+func skippedDir(name string) bool {
+	switch name {
+	case "vendor", "node_modules":
+		return true
+	default:
+		return strings.HasPrefix(name, ".")
+	}
+}
+
+func (cp *CommentParser) parseFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	file := cp.fset.AddFile(path, -1, len(src))
+	file.SetLinesForContent(src)
+
+	dir := filepath.Dir(path)
+	for _, c := range leadingComments(file, string(src)) {
+		found := annotation.Parse(dir, c.lines, cp.annotationOptions())
+
+		if c.rpc {
+			for i := range found.Relationships {
+				if found.Relationships[i].Proto == "" {
+					found.Relationships[i].Proto = DefaultProto
+				}
+			}
+		}
+
+		cp.found.Add(found)
+	}
+
+	return nil
+}
+
+// annotationOptions returns the options of the annotation grammar matching the parser options.
+func (cp *CommentParser) annotationOptions() annotation.Options {
+	return annotation.Options{
+		FileSet:        cp.fset,
+		Prefix:         cp.prefix,
+		Uncomment:      annotation.UncommentC,
+		CollectUnknown: cp.collectUnknown,
+		FoldTargetCase: cp.foldTargetCase,
+		StrictActions:  cp.strictActions,
+		StrictServices: cp.strictServices,
+	}
+}
+
+var declaration = regexp.MustCompile(`^\s*(service|rpc)\s+\w+`)
+
+// leadingComment is the comment directly above a service or rpc declaration.
+type leadingComment struct {
+	lines []annotation.Line
+	rpc   bool
+}
+
+// leadingComments returns the comments of src directly above service and rpc declarations.
+// As in protobuf descriptors, a comment separated from the declaration by a blank line is detached
+// and ignored, and so are comments above other declarations.
+func leadingComments(file *token.File, src string) []leadingComment {
+	var (
+		result  []leadingComment
+		pending []annotation.Line
+		block   bool
+	)
+
+	for offset := 0; offset < len(src); {
+		line, _, _ := strings.Cut(src[offset:], "\n")
+		trimmed := strings.TrimSpace(line)
+		current := annotation.Line{Text: line, Pos: file.Pos(offset)}
+		offset += len(line) + 1
+
+		switch {
+		case block:
+			pending = append(pending, current)
+			block = !strings.Contains(line, "*/")
+		case strings.HasPrefix(trimmed, "//"):
+			pending = append(pending, current)
+		case strings.HasPrefix(trimmed, "/*"):
+			pending = append(pending, current)
+			block = !strings.Contains(trimmed[2:], "*/")
+		default:
+			if m := declaration.FindStringSubmatch(line); m != nil && len(pending) > 0 {
+				result = append(result, leadingComment{lines: pending, rpc: m[1] == "rpc"})
+			}
+			pending = nil
+		}
+	}
+
+	return result
+}
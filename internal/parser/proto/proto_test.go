@@ -0,0 +1,57 @@
+package proto
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser()
+
+	result, err := parser.Parse("testdata", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []*servicefile.ServiceFile{
+		{
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        "Orders",
+				Description: "Takes orders",
+				System:      "shop",
+				Source:      servicefile.Source{File: filepath.Join("testdata", "orders", "orders.proto"), Line: 5},
+			},
+			Relationships: []servicefile.Relationship{
+				{
+					Action:      servicefile.RelationshipActionRequests,
+					Name:        "Billing",
+					Description: "Charges the order",
+					Proto:       "grpc",
+					Source:      servicefile.Source{File: filepath.Join("testdata", "orders", "orders.proto"), Line: 13},
+				},
+				{
+					Action:      servicefile.RelationshipActionSends,
+					Name:        "Kafka",
+					Description: "Publishes cancellations",
+					Technology:  "kafka",
+					Proto:       "kafka",
+					Source:      servicefile.Source{File: filepath.Join("testdata", "orders", "orders.proto"), Line: 18},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want %+v", result, expected)
+	}
+
+	if warnings := parser.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() = %+v, want none", warnings)
+	}
+}
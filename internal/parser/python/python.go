@@ -0,0 +1,257 @@
+package python
+
+import (
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/denchenko/servicefile/internal/annotation"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+// CommentParser extracts service files from annotations in Python docstrings.
+// Annotations are the ones of the Go comment parser, written in module and class docstrings,
+// and the same annotations produce the same service files.
+type CommentParser struct {
+	found annotation.Annotations
+	fset  *token.FileSet
+
+	foldTargetCase bool
+	collectUnknown bool
+	strictActions  bool
+	strictServices bool
+	prefix         string
+}
+
+// Option configures a CommentParser.
+type Option func(*CommentParser)
+
+// WithTargetCaseFolding makes the parser compare relationship targets and service names
+// case-insensitively, so that Kafka and kafka are treated as the same component.
+// The first seen casing is kept for output, declared service names taking precedence.
+func WithTargetCaseFolding() Option {
+	return func(cp *CommentParser) {
+		cp.foldTargetCase = true
+	}
+}
+
+// WithCollectUnknown makes the parser keep "key: value" lines of annotations that don't match
+// any known key as annotations of the service or relationship they belong to.
+func WithCollectUnknown() Option {
+	return func(cp *CommentParser) {
+		cp.collectUnknown = true
+	}
+}
+
+// WithStrictActions makes Parse fail on relationships whose action is not one of the actions
+// of the specification, instead of keeping them with a warning.
+func WithStrictActions() Option {
+	return func(cp *CommentParser) {
+		cp.strictActions = true
+	}
+}
+
+// WithStrictServices makes Parse fail on services declared more than once, naming both declarations,
+// instead of merging their definitions with a warning, the first non-empty fields winning.
+func WithStrictServices() Option {
+	return func(cp *CommentParser) {
+		cp.strictServices = true
+	}
+}
+
+// WithPrefix sets the marker starting service annotations, annotation.DefaultPrefix by default,
+// so that a codebase whose comments mention service: in prose can switch to another marker, such as arch:.
+func WithPrefix(prefix string) Option {
+	return func(cp *CommentParser) {
+		cp.prefix = prefix
+	}
+}
+
+func NewCommentParser(opts ...Option) *CommentParser {
+	cp := &CommentParser{
+		fset: token.NewFileSet(),
+	}
+
+	for _, opt := range opts {
+		opt(cp)
+	}
+
+	return cp
+}
+
+// Warning is an annotation that looks like a service annotation but was ignored.
+type Warning = annotation.Warning
+
+// Warnings returns the warnings collected so far, sorted by location.
+func (cp *CommentParser) Warnings() []Warning {
+	warnings := make([]Warning, len(cp.found.Warnings))
+	copy(warnings, cp.found.Warnings)
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Path != warnings[j].Path {
+			return warnings[i].Path < warnings[j].Path
+		}
+		return warnings[i].Line < warnings[j].Line
+	})
+
+	return warnings
+}
+
+// Parse parses the Python files of dir, and of its subdirectories when recursive is set,
+// and builds the service files they describe.
+func (cp *CommentParser) Parse(dir string, recursive bool) ([]*servicefile.ServiceFile, error) {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk the path: %w", err)
+		}
+
+		if d.IsDir() {
+			if path != dir && (!recursive || skippedDir(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".py") {
+			return nil
+		}
+
+		return cp.parseFile(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking the path: %w", err)
+	}
+
+	result, warnings, err := annotation.Build(cp.found, nil, cp.annotationOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	cp.found.Warnings = append(cp.found.Warnings, warnings...)
+
+	return result, nil
+}
+
+// skippedDir reports whether a directory is skipped: virtual environments, caches and hidden directories.
+func skippedDir(name string) bool {
+	switch name {
+	case "venv", "site-packages", "node_modules", "__pycache__":
+		return true
+	default:
+		return strings.HasPrefix(name, ".")
+	}
+}
+
+func (cp *CommentParser) parseFile(path string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	file := cp.fset.AddFile(path, -1, len(src))
+	file.SetLinesForContent(src)
+
+	dir := filepath.Dir(path)
+	for _, lines := range docstrings(file, string(src)) {
+		cp.found.Add(annotation.Parse(dir, lines, cp.annotationOptions()))
+	}
+
+	return nil
+}
+
+// annotationOptions returns the options of the annotation grammar matching the parser options.
+func (cp *CommentParser) annotationOptions() annotation.Options {
+	return annotation.Options{
+		FileSet:        cp.fset,
+		Prefix:         cp.prefix,
+		CollectUnknown: cp.collectUnknown,
+		FoldTargetCase: cp.foldTargetCase,
+		StrictActions:  cp.strictActions,
+		StrictServices: cp.strictServices,
+	}
+}
+
+var (
+	classStatement = regexp.MustCompile(`^\s*class\s+\w+.*:\s*(#.*)?$`)
+	docstringStart = regexp.MustCompile(`^\s*[rRuU]?("""|''')`)
+)
+
+// docstrings returns the lines of the module docstring and of the class docstrings of src,
+// that is the triple-quoted strings starting the module and the body of classes.
+// Lines following the opening quotes of other triple-quoted strings are skipped,
+// so that their content is not taken for code.
+func docstrings(file *token.File, src string) [][]annotation.Line {
+	var (
+		result   [][]annotation.Line
+		expected = true
+		offset   int
+	)
+
+	for offset < len(src) {
+		line, _, _ := strings.Cut(src[offset:], "\n")
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			offset += len(line) + 1
+			continue
+		}
+
+		if m := docstringStart.FindStringSubmatchIndex(line); m != nil {
+			lines, end := stringLines(file, src, offset+m[3], src[offset+m[2]:offset+m[3]])
+			if expected {
+				result = append(result, lines)
+			}
+			expected = false
+			offset = end
+			continue
+		}
+
+		if quote := openQuote(line); quote != "" {
+			_, end := stringLines(file, src, offset+strings.LastIndex(line, quote)+len(quote), quote)
+			expected = false
+			offset = end
+			continue
+		}
+
+		expected = classStatement.MatchString(line)
+		offset += len(line) + 1
+	}
+
+	return result
+}
+
+// stringLines returns the lines of the triple-quoted string starting at start, after its opening quote,
+// and the offset of the line following its closing quote.
+func stringLines(file *token.File, src string, start int, quote string) ([]annotation.Line, int) {
+	var lines []annotation.Line
+
+	for offset := start; offset < len(src); {
+		line, _, _ := strings.Cut(src[offset:], "\n")
+
+		if i := strings.Index(line, quote); i >= 0 {
+			lines = append(lines, annotation.Line{Text: line[:i], Pos: file.Pos(offset)})
+			return lines, offset + len(line) + 1
+		}
+
+		lines = append(lines, annotation.Line{Text: line, Pos: file.Pos(offset)})
+		offset += len(line) + 1
+	}
+
+	return lines, len(src)
+}
+
+// openQuote returns the quote of a triple-quoted string left open at the end of line.
+func openQuote(line string) string {
+	for _, quote := range []string{`"""`, `'''`} {
+		if strings.Count(line, quote)%2 == 1 {
+			return quote
+		}
+	}
+
+	return ""
+}
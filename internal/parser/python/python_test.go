@@ -0,0 +1,98 @@
+package python
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/denchenko/servicefile/internal/parser/golang"
+	"github.com/denchenko/servicefile/pkg/servicefile"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	parser := NewCommentParser()
+
+	result, err := parser.Parse("testdata/strings", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []*servicefile.ServiceFile{
+		{
+			Version: servicefile.Version,
+			Info: servicefile.Info{
+				Name:        "Strings",
+				Description: "Ignores annotations outside of docstrings",
+				Source:      servicefile.Source{File: filepath.Join("testdata", "strings", "strings.py"), Line: 1},
+			},
+			Relationships: []servicefile.Relationship{
+				{
+					Action:     servicefile.RelationshipActionUses,
+					Name:       "Redis",
+					Technology: "redis",
+					Source:     servicefile.Source{File: filepath.Join("testdata", "strings", "strings.py"), Line: 19},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want %+v", result, expected)
+	}
+
+	path := filepath.Join("testdata", "strings", "strings.py")
+	warnings := []Warning{
+		{
+			Path:    path,
+			Line:    25,
+			Text:    "service:\n    description: Relationship missing its action\n    ",
+			Message: "relationship without an action is ignored",
+		},
+	}
+
+	if got := parser.Warnings(); !reflect.DeepEqual(got, warnings) {
+		t.Errorf("Warnings() = %+v, want %+v", got, warnings)
+	}
+}
+
+func TestParseMatchesGoParser(t *testing.T) {
+	t.Parallel()
+
+	result, err := NewCommentParser().Parse("testdata/siblings", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected, err := golang.NewCommentParser().Parse("../golang/testdata/siblings", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sortByName(result)
+	sortByName(expected)
+	clearSources(result)
+	clearSources(expected)
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Parse() = %+v, want the Go parser output %+v", result, expected)
+	}
+}
+
+// clearSources removes the sources of files, which differ between parsers of different languages.
+func clearSources(files []*servicefile.ServiceFile) {
+	for _, sf := range files {
+		sf.Info.Source = servicefile.Source{}
+		for i := range sf.Relationships {
+			sf.Relationships[i].Source = servicefile.Source{}
+		}
+	}
+}
+
+func sortByName(files []*servicefile.ServiceFile) {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Info.Name < files[j].Info.Name
+	})
+}
@@ -0,0 +1,5 @@
+"""Catalog declares its service once, relationships live next to the code using them.
+
+service:name Catalog
+description: Manages the product catalog
+"""
@@ -0,0 +1,13 @@
+import grpc
+
+
+class PricingClient:
+    """Fetches prices.
+
+    service:requests Pricing
+    description: Fetches product prices
+    technology:grpc
+    """
+
+    def __init__(self, channel: grpc.Channel) -> None:
+        self.channel = channel
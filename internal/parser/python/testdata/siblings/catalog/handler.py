@@ -0,0 +1,7 @@
+class Handler:
+    """Serves catalog requests.
+
+    service:replies Storefront
+    description: Serves product pages
+    technology:http
+    """
@@ -0,0 +1,7 @@
+class Repository:
+    '''Stores products.
+
+    - service:uses PostgreSQL
+    - description: Stores products
+    - technology:postgresql
+    '''
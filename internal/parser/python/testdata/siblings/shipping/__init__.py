@@ -0,0 +1,3 @@
+"""service:name Shipping
+description: Ships orders
+"""
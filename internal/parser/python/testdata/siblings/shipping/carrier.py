@@ -0,0 +1,10 @@
+#!/usr/bin/env python3
+# -*- coding: utf-8 -*-
+"""Books deliveries with the carrier.
+
+service:sends Carrier
+description: Books deliveries
+technology:http
+"""
+
+import requests
@@ -0,0 +1,27 @@
+"""service:name Strings
+description: Ignores annotations outside of docstrings
+"""
+
+TEMPLATE = """
+class Fake:
+    '''service:uses Fake'''
+"""
+
+
+def handler():
+    """service:uses Function
+
+    Function docstrings are not parsed.
+    """
+
+
+class Client(object):  # talks to Redis
+    r"""service:uses Redis
+    technology:redis
+    """
+
+
+class Broken:
+    """service:
+    description: Relationship missing its action
+    """
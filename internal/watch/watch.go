@@ -0,0 +1,120 @@
+// Package watch reruns a function when the Go files of a directory change.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/denchenko/servicefile/internal/parser/golang"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the delay Watch waits for changes to settle before running again.
+const DefaultDebounce = 200 * time.Millisecond
+
+// Options configures Watch.
+type Options struct {
+	// Recursive makes Watch monitor the subdirectories of the directory too, including
+	// the ones created while watching. Hidden, vendor and node_modules directories are left out.
+	Recursive bool
+	// Debounce is the delay without changes waited for before running again, DefaultDebounce when zero.
+	// Successive changes within the delay, such as an editor saving several files, trigger a single run.
+	Debounce time.Duration
+}
+
+// Watch calls run once, then again each time a .go file of dir is created, written, removed or renamed,
+// until ctx is done. Failures of run are up to run to report: Watch only returns when ctx is done,
+// with a nil error, or when the directory can no longer be watched.
+func Watch(ctx context.Context, dir string, opts Options, run func()) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := add(watcher, dir, dir, opts.Recursive); err != nil {
+		return err
+	}
+
+	run()
+
+	timer := time.NewTimer(opts.Debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if opts.Recursive && event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := add(watcher, dir, event.Name, true); err != nil {
+						return err
+					}
+					// The directory may have been moved in or filled before being watched,
+					// its Go files then going unnoticed.
+					timer.Reset(opts.Debounce)
+					continue
+				}
+			}
+
+			if filepath.Ext(event.Name) != ".go" || event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			timer.Reset(opts.Debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		case <-timer.C:
+			run()
+		}
+	}
+}
+
+// add watches path and, when recursive, its subdirectories.
+func add(watcher *fsnotify.Watcher, root, path string, recursive bool) error {
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if p != root && golang.SkippedDir(d.Name()) {
+			return filepath.SkipDir
+		}
+
+		if err := watcher.Add(p); err != nil {
+			return err
+		}
+
+		if !recursive {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "orders")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan struct{}, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- Watch(ctx, dir, Options{Recursive: true, Debounce: 50 * time.Millisecond}, func() {
+			runs <- struct{}{}
+		})
+	}()
+
+	expectRun := func(what string) {
+		t.Helper()
+
+		select {
+		case <-runs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Watch() did not run after %s", what)
+		}
+	}
+
+	expectNoRun := func(what string) {
+		t.Helper()
+
+		select {
+		case <-runs:
+			t.Fatalf("Watch() ran after %s", what)
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+
+	write := func(path string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte("package orders\n"), 0644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expectRun("starting")
+
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		write(filepath.Join(sub, name))
+	}
+	expectRun("writing Go files")
+	expectNoRun("a single batch of changes")
+
+	write(filepath.Join(dir, "README.md"))
+	expectNoRun("writing a file that is not a Go file")
+
+	created := filepath.Join(sub, "billing")
+	if err := os.Mkdir(created, 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectRun("creating a directory")
+	write(filepath.Join(created, "billing.go"))
+	expectRun("writing a Go file in a new directory")
+
+	moved := filepath.Join(t.TempDir(), "shipping")
+	if err := os.Mkdir(moved, 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	write(filepath.Join(moved, "shipping.go"))
+	if err := os.Rename(moved, filepath.Join(dir, "shipping")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectRun("moving in a package directory containing a Go file")
+	expectNoRun("a single directory moved in")
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch() did not return after cancellation")
+	}
+}
+
+func TestWatchMissingDirectory(t *testing.T) {
+	t.Parallel()
+
+	err := Watch(context.Background(), filepath.Join(t.TempDir(), "missing"), Options{}, func() {
+		t.Errorf("run called for a missing directory")
+	})
+	if err == nil {
+		t.Errorf("Watch() error = nil, want an error")
+	}
+}
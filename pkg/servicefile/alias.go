@@ -0,0 +1,55 @@
+package servicefile
+
+// ResolveAliases returns the service files with the relationship targets referring to a service
+// by its alias, see Info.Alias, renamed to the name of the service.
+// Aliases of several services, or equal to the name of another service, are not resolved.
+// The returned service files are copies, files are left untouched.
+func ResolveAliases(files []*ServiceFile) []*ServiceFile {
+	names := aliasNames(files)
+
+	result := make([]*ServiceFile, 0, len(files))
+	for _, sf := range files {
+		resolved := *sf
+		resolved.Relationships = make([]Relationship, 0, len(sf.Relationships))
+		for _, rel := range sf.Relationships {
+			if name, ok := names[rel.Name]; ok {
+				rel.Name = name
+			}
+			resolved.Relationships = append(resolved.Relationships, rel)
+		}
+
+		result = append(result, &resolved)
+	}
+
+	return result
+}
+
+// aliasNames maps the aliases of the services of files that can be resolved to the names of the services.
+func aliasNames(files []*ServiceFile) map[string]string {
+	services := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+	}
+
+	names := make(map[string]string)
+	ambiguous := make(map[string]struct{})
+	for _, sf := range files {
+		alias := sf.Info.Alias
+		if alias == "" {
+			continue
+		}
+		if _, service := services[alias]; service {
+			continue
+		}
+		if name, exists := names[alias]; exists && name != sf.Info.Name {
+			ambiguous[alias] = struct{}{}
+		}
+		names[alias] = sf.Info.Name
+	}
+
+	for alias := range ambiguous {
+		delete(names, alias)
+	}
+
+	return names
+}
@@ -0,0 +1,59 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveAliases(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "PaymentsAuthorizationService", Alias: "Payments"},
+		},
+		{
+			Info: Info{Name: "Orders", Alias: "Shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "Payments"},
+				{Action: RelationshipActionRequests, Name: "Catalog"},
+				{Action: RelationshipActionUses, Name: "Cart"},
+				{Action: RelationshipActionReplies},
+			},
+		},
+		{
+			Info: Info{Name: "Catalog", Alias: "Orders"},
+		},
+		{
+			Info: Info{Name: "Storefront", Alias: "Cart"},
+		},
+		{
+			Info: Info{Name: "Basket", Alias: "Cart"},
+		},
+	}
+
+	resolved := ResolveAliases(files)
+
+	expected := []Relationship{
+		{Action: RelationshipActionRequests, Name: "PaymentsAuthorizationService"},
+		{Action: RelationshipActionRequests, Name: "Catalog"},
+		{Action: RelationshipActionUses, Name: "Cart"},
+		{Action: RelationshipActionReplies},
+	}
+	assert.Equal(t, expected, resolved[1].Relationships)
+	assert.Equal(t, "Payments", files[1].Relationships[0].Name, "files must be left untouched")
+
+	graph := NewGraph(files)
+	assert.False(t, graph.IsExternal("PaymentsAuthorizationService"))
+	assert.Empty(t, graph.InboundOf("Payments"))
+	assert.Len(t, graph.InboundOf("PaymentsAuthorizationService"), 1)
+	assert.True(t, graph.IsExternal("Cart"), "aliases of several services are not resolved")
+}
+
+func TestDisplayName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Payments", Info{Name: "PaymentsAuthorizationService", Alias: "Payments"}.DisplayName())
+	assert.Equal(t, "Orders", Info{Name: "Orders"}.DisplayName())
+}
@@ -0,0 +1,144 @@
+package servicefile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	backstageAPIVersion       = "backstage.io/v1alpha1"
+	backstageDefaultOwner     = "unknown"
+	backstageDefaultLifecycle = "production"
+)
+
+type backstageEntity struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   backstageMetadata `yaml:"metadata"`
+	Spec       backstageSpec     `yaml:"spec"`
+}
+
+type backstageMetadata struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+}
+
+type backstageSpec struct {
+	Type         string   `yaml:"type"`
+	Lifecycle    string   `yaml:"lifecycle"`
+	Owner        string   `yaml:"owner"`
+	System       string   `yaml:"system,omitempty"`
+	DependsOn    []string `yaml:"dependsOn,omitempty"`
+	ConsumesAPIs []string `yaml:"consumesApis,omitempty"`
+}
+
+// Backstage writes the service file as a Backstage catalog-info.yaml Component entity.
+// Relationship targets are all treated as external, see BackstageCatalog to resolve
+// targets to other services.
+func Backstage(sf *ServiceFile, w io.Writer) error {
+	return BackstageCatalog([]*ServiceFile{sf}, w)
+}
+
+// BackstageCatalog writes a Backstage Component entity for each service file, as a multi-document YAML.
+// The owner of a component is the owner of the service, falling back to its owner annotation,
+// and its lifecycle is taken from the lifecycle annotation of the service.
+// A service depends on the targets it uses, requests or sends to, targets that are services of the catalog
+// being component references, requested externals API references and other externals resource references.
+// Targets referring to a service by its alias are references to the service.
+func BackstageCatalog(files []*ServiceFile, w io.Writer) error {
+	known := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		known[sf.Info.Name] = struct{}{}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	for _, sf := range sortedServiceFiles(ResolveAliases(files)) {
+		if err := enc.Encode(newBackstageEntity(sf, known)); err != nil {
+			return fmt.Errorf("failed to encode backstage entity for %s: %w", sf.Info.Name, err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode backstage entities: %w", err)
+	}
+
+	return nil
+}
+
+func newBackstageEntity(sf *ServiceFile, known map[string]struct{}) backstageEntity {
+	entity := backstageEntity{
+		APIVersion: backstageAPIVersion,
+		Kind:       "Component",
+		Metadata: backstageMetadata{
+			Name:        backstageName(sf.Info.Name),
+			Description: sf.Info.Description,
+		},
+		Spec: backstageSpec{
+			Type:      "service",
+			Lifecycle: backstageDefaultLifecycle,
+			Owner:     backstageDefaultOwner,
+		},
+	}
+
+	if owner := sf.Info.Owner; owner != "" {
+		entity.Spec.Owner = owner
+	} else if owner := sf.Info.Annotations["owner"]; owner != "" {
+		entity.Spec.Owner = owner
+	}
+
+	if lifecycle := sf.Info.Annotations["lifecycle"]; lifecycle != "" {
+		entity.Spec.Lifecycle = lifecycle
+	}
+
+	if sf.Info.System != "" {
+		entity.Spec.System = backstageName(sf.Info.System)
+	}
+
+	dependsOn := make(map[string]struct{})
+	consumesAPIs := make(map[string]struct{})
+
+	for _, rel := range sf.Relationships {
+		if rel.Name == "" || rel.Name == sf.Info.Name {
+			continue
+		}
+
+		if from, _ := dependencyEdge(sf.Info.Name, rel); from != sf.Info.Name {
+			continue
+		}
+
+		name := backstageName(rel.Name)
+
+		switch _, internal := known[rel.Name]; {
+		case internal:
+			dependsOn["component:"+name] = struct{}{}
+		case rel.Action == RelationshipActionRequests:
+			consumesAPIs["api:"+name] = struct{}{}
+		default:
+			dependsOn["resource:"+name] = struct{}{}
+		}
+	}
+
+	entity.Spec.DependsOn = sortedKeys(dependsOn)
+	entity.Spec.ConsumesAPIs = sortedKeys(consumesAPIs)
+
+	return entity
+}
+
+// backstageName turns a name into a valid Backstage entity name, made of letters, digits, - _ and . only.
+func backstageName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, strings.TrimSpace(name))
+
+	return strings.Trim(name, "-_.")
+}
@@ -0,0 +1,100 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBackstage(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info: Info{
+			Name:        "Order Service",
+			Description: "Accepts customer orders",
+			System:      "shop",
+			Annotations: map[string]string{"owner": "team-orders"},
+		},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"},
+			{Action: RelationshipActionRequests, Name: "Stripe API", Technology: "http"},
+			{Action: RelationshipActionReplies, Name: "gateway", Technology: "grpc"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Backstage(sf, &buf))
+
+	var entity map[string]any
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &entity))
+
+	assert.Equal(t, map[string]any{
+		"apiVersion": "backstage.io/v1alpha1",
+		"kind":       "Component",
+		"metadata": map[string]any{
+			"name":        "Order-Service",
+			"description": "Accepts customer orders",
+		},
+		"spec": map[string]any{
+			"type":         "service",
+			"lifecycle":    "production",
+			"owner":        "team-orders",
+			"system":       "shop",
+			"dependsOn":    []any{"resource:PostgreSQL"},
+			"consumesApis": []any{"api:Stripe-API"},
+		},
+	}, entity)
+}
+
+func TestBackstageCatalog(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka"},
+			},
+		},
+		{
+			Version: Version,
+			Info: Info{
+				Name:        "billing",
+				Owner:       "team-billing",
+				Annotations: map[string]string{"owner": "legacy-team", "lifecycle": "experimental"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, BackstageCatalog(files, &buf))
+
+	assert.Equal(t, `apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: billing
+spec:
+  type: service
+  lifecycle: experimental
+  owner: team-billing
+---
+apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: orders
+spec:
+  type: service
+  lifecycle: production
+  owner: unknown
+  dependsOn:
+    - component:billing
+    - resource:Kafka
+`, buf.String())
+}
@@ -0,0 +1,120 @@
+package servicefile
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CanonicalizeOptions configures Canonicalize.
+type CanonicalizeOptions struct {
+	// FoldCase makes relationship targets match services and other targets case-insensitively.
+	// Services keep their casing, other targets the first casing seen.
+	FoldCase bool
+}
+
+// Canonicalize returns a normalized copy of files, ready for export:
+// names and values are trimmed, technologies lower-cased, protos normalized, targets referring to a service
+// by one of its aliases renamed to the service, duplicate relationships removed, relationships sorted
+// and service files sorted by name. Aliases are the alias of services and their former names,
+// see RenamedFromAnnotation.
+// An error is returned if two service files describe the same service once normalized.
+// Canonicalizing the result again returns an identical catalog.
+func Canonicalize(files []*ServiceFile, opts CanonicalizeOptions) ([]*ServiceFile, error) {
+	result := make([]*ServiceFile, 0, len(files))
+	for _, sf := range files {
+		result = append(result, trimmedCopy(sf))
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Info.Name < result[j].Info.Name
+	})
+
+	key := func(name string) string {
+		if opts.FoldCase {
+			return strings.ToLower(name)
+		}
+		return name
+	}
+
+	names := make(map[string]string, len(result))
+	for _, sf := range result {
+		if _, exists := names[key(sf.Info.Name)]; exists {
+			return nil, fmt.Errorf("service %q is defined more than once", sf.Info.Name)
+		}
+		names[key(sf.Info.Name)] = sf.Info.Name
+	}
+
+	for _, sf := range result {
+		for _, alias := range serviceAliases(sf) {
+			if _, exists := names[key(alias)]; !exists {
+				names[key(alias)] = sf.Info.Name
+			}
+		}
+	}
+
+	for _, sf := range result {
+		sf.Sort()
+
+		for i, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			if name, ok := names[key(rel.Name)]; ok {
+				sf.Relationships[i].Name = name
+				continue
+			}
+
+			names[key(rel.Name)] = rel.Name
+		}
+
+		sf.Deduplicate()
+	}
+
+	return result, nil
+}
+
+// trimmedCopy returns a copy of the service file with trimmed names and values,
+// lower-cased technologies and normalized protos.
+func trimmedCopy(sf *ServiceFile) *ServiceFile {
+	c := &ServiceFile{
+		Version: sf.Version,
+		Info: Info{
+			Name:        strings.TrimSpace(sf.Info.Name),
+			Alias:       strings.TrimSpace(sf.Info.Alias),
+			Description: strings.TrimSpace(sf.Info.Description),
+			System:      strings.TrimSpace(sf.Info.System),
+			Owner:       strings.TrimSpace(sf.Info.Owner),
+			Annotations: maps.Clone(sf.Info.Annotations),
+			Source:      sf.Info.Source,
+		},
+		Relationships: make([]Relationship, 0, len(sf.Relationships)),
+	}
+
+	for _, tag := range sf.Info.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			c.Info.Tags = append(c.Info.Tags, tag)
+		}
+	}
+
+	for _, rel := range sf.Relationships {
+		rel.Action = RelationshipAction(strings.ToLower(strings.TrimSpace(string(rel.Action))))
+		rel.Name = strings.TrimSpace(rel.Name)
+		rel.Description = strings.TrimSpace(rel.Description)
+		rel.Technology = strings.ToLower(strings.TrimSpace(rel.Technology))
+		rel.Technologies = slices.Clone(rel.Technologies)
+		for i, technology := range rel.Technologies {
+			rel.Technologies[i] = strings.ToLower(strings.TrimSpace(technology))
+		}
+		rel.NormalizeProto()
+		rel.SLA = strings.TrimSpace(rel.SLA)
+		rel.Annotations = maps.Clone(rel.Annotations)
+
+		c.Relationships = append(c.Relationships, rel)
+	}
+
+	return c
+}
@@ -0,0 +1,76 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: " orders ", Description: "Takes orders "},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "kafka", Technology: "Kafka", Proto: "TCP"},
+				{Action: RelationshipActionRequests, Name: "pay", Technology: "gRPC"},
+				{Action: RelationshipActionUses, Name: "Kafka ", Technology: "kafka", Proto: "tcp"},
+			},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "payments", Annotations: map[string]string{RenamedFromAnnotation: "pay, billing"}},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "Orders", Technology: "grpc"},
+				{Action: RelationshipActionSends, Name: "KAFKA"},
+			},
+		},
+	}
+
+	got, err := Canonicalize(files, CanonicalizeOptions{FoldCase: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "orders", Description: "Takes orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "payments", Technology: "grpc"},
+				{Action: RelationshipActionUses, Name: "Kafka", Technology: "kafka", Proto: "tcp"},
+			},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "payments", Annotations: map[string]string{RenamedFromAnnotation: "pay, billing"}},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders", Technology: "grpc"},
+				{Action: RelationshipActionSends, Name: "Kafka"},
+			},
+		},
+	}, got)
+
+	again, err := Canonicalize(got, CanonicalizeOptions{FoldCase: true})
+	require.NoError(t, err)
+	assert.Equal(t, got, again)
+
+	assert.Equal(t, "kafka", files[0].Relationships[0].Name, "input must be left untouched")
+}
+
+func TestCanonicalizeDuplicateServices(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Version: Version, Info: Info{Name: "orders"}},
+		{Version: Version, Info: Info{Name: "Orders"}},
+	}
+
+	_, err := Canonicalize(files, CanonicalizeOptions{})
+	require.NoError(t, err)
+
+	_, err = Canonicalize(files, CanonicalizeOptions{FoldCase: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `service "orders" is defined more than once`)
+}
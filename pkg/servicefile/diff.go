@@ -0,0 +1,290 @@
+package servicefile
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FingerprintDiff compares two sets of service files by their Hash and returns the names of
+// services only present in newFiles, only present in oldFiles, and present in both with a different content.
+func FingerprintDiff(oldFiles, newFiles []*ServiceFile) (added, removed, changed []string) {
+	oldHashes := hashesByName(oldFiles)
+	newHashes := hashesByName(newFiles)
+
+	added, removed, changed = []string{}, []string{}, []string{}
+
+	for name, newHash := range newHashes {
+		oldHash, exists := oldHashes[name]
+		switch {
+		case !exists:
+			added = append(added, name)
+		case oldHash != newHash:
+			changed = append(changed, name)
+		}
+	}
+
+	for name := range oldHashes {
+		if _, exists := newHashes[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+
+	return added, removed, changed
+}
+
+func hashesByName(files []*ServiceFile) map[string]string {
+	hashes := make(map[string]string, len(files))
+	for _, sf := range files {
+		hashes[sf.Info.Name] = sf.Hash()
+	}
+
+	return hashes
+}
+
+// DiffResult is the architectural difference between two sets of service files.
+type DiffResult struct {
+	// AddedServices and RemovedServices are the sorted names of services only present in the new
+	// and only present in the old set.
+	AddedServices   []string
+	RemovedServices []string
+	// Services lists the services present in both sets whose relationships differ, sorted by name.
+	Services []ServiceDiff
+}
+
+// ServiceDiff is the difference between the relationships of a service in two sets of service files.
+// Relationships are matched by action and name, so that a relationship whose details changed,
+// like its description, is reported as changed rather than removed and added.
+type ServiceDiff struct {
+	Name    string
+	Added   []Relationship
+	Removed []Relationship
+	Changed []RelationshipChange
+}
+
+// RelationshipChange is a relationship whose details differ between two sets of service files.
+type RelationshipChange struct {
+	Old Relationship
+	New Relationship
+}
+
+// Diff compares two sets of service files by service name.
+func Diff(oldFiles, newFiles []*ServiceFile) *DiffResult {
+	oldByName := serviceFilesByName(oldFiles)
+	newByName := serviceFilesByName(newFiles)
+
+	result := &DiffResult{
+		AddedServices:   []string{},
+		RemovedServices: []string{},
+		Services:        []ServiceDiff{},
+	}
+
+	for _, name := range sortedKeys(keySet(newByName)) {
+		oldFile, exists := oldByName[name]
+		if !exists {
+			result.AddedServices = append(result.AddedServices, name)
+			continue
+		}
+
+		if d := diffRelationships(name, oldFile.Relationships, newByName[name].Relationships); !d.empty() {
+			result.Services = append(result.Services, d)
+		}
+	}
+
+	for _, name := range sortedKeys(keySet(oldByName)) {
+		if _, exists := newByName[name]; !exists {
+			result.RemovedServices = append(result.RemovedServices, name)
+		}
+	}
+
+	return result
+}
+
+// Empty reports whether both sets of service files describe the same architecture.
+func (d *DiffResult) Empty() bool {
+	return len(d.AddedServices) == 0 && len(d.RemovedServices) == 0 && len(d.Services) == 0
+}
+
+// String returns the difference for humans, one line per added (+), removed (-) or changed (~) element.
+func (d *DiffResult) String() string {
+	var b strings.Builder
+
+	for _, name := range d.AddedServices {
+		fmt.Fprintf(&b, "+ service %s\n", name)
+	}
+
+	for _, name := range d.RemovedServices {
+		fmt.Fprintf(&b, "- service %s\n", name)
+	}
+
+	for _, s := range d.Services {
+		fmt.Fprintf(&b, "~ service %s\n", s.Name)
+
+		for _, rel := range s.Added {
+			fmt.Fprintf(&b, "  + %s\n", describeRelationship(rel))
+		}
+
+		for _, rel := range s.Removed {
+			fmt.Fprintf(&b, "  - %s\n", describeRelationship(rel))
+		}
+
+		for _, c := range s.Changed {
+			fmt.Fprintf(&b, "  ~ %s %s: %s\n", c.New.Action, c.New.Name, strings.Join(changedFields(c.Old, c.New), ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// describeRelationship returns the action and name of a relationship, followed by its technology.
+func describeRelationship(rel Relationship) string {
+	description := strings.TrimSpace(string(rel.Action) + " " + rel.Name)
+	if technology := relationshipTechnology(rel); technology != "" {
+		description += " (" + technology + ")"
+	}
+
+	return description
+}
+
+func (d ServiceDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// relationshipKey identifies the relationships Diff matches with each other.
+type relationshipKey struct {
+	action RelationshipAction
+	name   string
+}
+
+func diffRelationships(name string, oldRels, newRels []Relationship) ServiceDiff {
+	oldGroups := groupRelationships(oldRels)
+	newGroups := groupRelationships(newRels)
+
+	keys := make([]relationshipKey, 0, len(oldGroups)+len(newGroups))
+	for k := range newGroups {
+		keys = append(keys, k)
+	}
+	for k := range oldGroups {
+		if _, exists := newGroups[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].action != keys[j].action {
+			return keys[i].action < keys[j].action
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	d := ServiceDiff{Name: name}
+
+	for _, k := range keys {
+		olds, news := unmatched(oldGroups[k], newGroups[k])
+
+		n := min(len(olds), len(news))
+		for i := range n {
+			d.Changed = append(d.Changed, RelationshipChange{Old: olds[i], New: news[i]})
+		}
+
+		d.Removed = append(d.Removed, olds[n:]...)
+		d.Added = append(d.Added, news[n:]...)
+	}
+
+	return d
+}
+
+// groupRelationships returns the relationships sorted and grouped by action and name.
+func groupRelationships(rels []Relationship) map[relationshipKey][]Relationship {
+	sf := ServiceFile{Relationships: append([]Relationship(nil), rels...)}
+	sf.Sort()
+
+	groups := make(map[relationshipKey][]Relationship)
+	for _, rel := range sf.Relationships {
+		k := relationshipKey{action: rel.Action, name: rel.Name}
+		groups[k] = append(groups[k], rel)
+	}
+
+	return groups
+}
+
+// unmatched returns the relationships of olds and news that have no identical counterpart in the other slice.
+func unmatched(olds, news []Relationship) (removed, added []Relationship) {
+	added = append([]Relationship(nil), news...)
+
+	for _, old := range olds {
+		i := slices.IndexFunc(added, old.Equal)
+		if i < 0 {
+			removed = append(removed, old)
+			continue
+		}
+		added = slices.Delete(added, i, i+1)
+	}
+
+	return removed, added
+}
+
+// changedFields describes the details that differ between two relationships with the same action and name.
+func changedFields(oldRel, newRel Relationship) []string {
+	var changes []string
+
+	field := func(name, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, fmt.Sprintf("%s %q -> %q", name, oldValue, newValue))
+		}
+	}
+
+	field("description", oldRel.Description, newRel.Description)
+	field("technology", relationshipTechnology(oldRel), relationshipTechnology(newRel))
+	field("proto", oldRel.Proto, newRel.Proto)
+
+	if oldRel.Port != newRel.Port {
+		changes = append(changes, fmt.Sprintf("port %d -> %d", oldRel.Port, newRel.Port))
+	}
+
+	flag := func(name string, oldValue, newValue bool) {
+		if oldValue != newValue {
+			changes = append(changes, fmt.Sprintf("%s %t -> %t", name, oldValue, newValue))
+		}
+	}
+
+	flag("async", oldRel.Async, newRel.Async)
+	field("env", strings.Join(oldRel.Environments, ", "), strings.Join(newRel.Environments, ", "))
+	flag("deprecated", oldRel.Deprecated, newRel.Deprecated)
+	flag("external", oldRel.External, newRel.External)
+	flag("discovered", oldRel.Discovered, newRel.Discovered)
+	field("sla", oldRel.SLA, newRel.SLA)
+
+	if oldRel.TimeoutMS != newRel.TimeoutMS {
+		changes = append(changes, fmt.Sprintf("timeout %dms -> %dms", oldRel.TimeoutMS, newRel.TimeoutMS))
+	}
+
+	if !maps.Equal(oldRel.Annotations, newRel.Annotations) {
+		changes = append(changes, "annotations")
+	}
+
+	return changes
+}
+
+func serviceFilesByName(files []*ServiceFile) map[string]*ServiceFile {
+	byName := make(map[string]*ServiceFile, len(files))
+	for _, sf := range files {
+		byName[sf.Info.Name] = sf
+	}
+
+	return byName
+}
+
+func keySet[V any](m map[string]V) map[string]struct{} {
+	set := make(map[string]struct{}, len(m))
+	for key := range m {
+		set[key] = struct{}{}
+	}
+
+	return set
+}
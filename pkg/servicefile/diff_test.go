@@ -0,0 +1,164 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintDiff(t *testing.T) {
+	t.Parallel()
+
+	oldFiles := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "api"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"},
+				{Action: RelationshipActionRequests, Name: "auth", Technology: "grpc"},
+			},
+		},
+		{
+			Version:       Version,
+			Info:          Info{Name: "auth", Description: "Authenticates users"},
+			Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "api", Technology: "grpc"}},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "legacy"},
+		},
+	}
+
+	newFiles := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "api"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth", Technology: "grpc"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"},
+			},
+		},
+		{
+			Version:       Version,
+			Info:          Info{Name: "auth", Description: "Authenticates users and services"},
+			Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "api", Technology: "grpc"}},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "billing"},
+		},
+	}
+
+	added, removed, changed := FingerprintDiff(oldFiles, newFiles)
+
+	assert.Equal(t, []string{"billing"}, added)
+	assert.Equal(t, []string{"legacy"}, removed)
+	assert.Equal(t, []string{"auth"}, changed)
+}
+
+func TestFingerprintDiffEqual(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{{Version: Version, Info: Info{Name: "api"}}}
+
+	added, removed, changed := FingerprintDiff(files, files)
+
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+	assert.Empty(t, changed)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	oldFiles := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders"},
+				{Action: RelationshipActionUses, Name: "Redis", Technology: "redis"},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+			},
+		},
+		{Info: Info{Name: "billing"}, Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "orders"}}},
+		{Info: Info{Name: "legacy"}},
+	}
+
+	newFiles := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", Description: "Only relationships are compared"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders and carts"},
+				{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka"},
+			},
+		},
+		{Info: Info{Name: "billing"}, Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "orders"}}},
+		{Info: Info{Name: "shipping"}},
+	}
+
+	d := Diff(oldFiles, newFiles)
+
+	assert.Equal(t, &DiffResult{
+		AddedServices:   []string{"shipping"},
+		RemovedServices: []string{"legacy"},
+		Services: []ServiceDiff{
+			{
+				Name:    "orders",
+				Added:   []Relationship{{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka"}},
+				Removed: []Relationship{{Action: RelationshipActionUses, Name: "Redis", Technology: "redis"}},
+				Changed: []RelationshipChange{
+					{
+						Old: Relationship{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders"},
+						New: Relationship{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders and carts"},
+					},
+				},
+			},
+		},
+	}, d)
+
+	assert.False(t, d.Empty())
+	assert.Equal(t, `+ service shipping
+- service legacy
+~ service orders
+  + sends Kafka (kafka)
+  - uses Redis (redis)
+  ~ uses PostgreSQL: description "Stores orders" -> "Stores orders and carts"
+`, d.String())
+
+	same := Diff(oldFiles, oldFiles)
+	assert.True(t, same.Empty())
+	assert.Empty(t, same.String())
+}
+
+func TestDiffChangedFields(t *testing.T) {
+	t.Parallel()
+
+	oldFiles := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Port: 5432},
+				{Action: RelationshipActionRequests, Name: "billing"},
+				{Action: RelationshipActionSends, Name: "Kafka", Environments: []string{"prod"}},
+			},
+		},
+	}
+
+	newFiles := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Port: 6432},
+				{Action: RelationshipActionRequests, Name: "billing", Deprecated: true, Async: true},
+				{Action: RelationshipActionSends, Name: "Kafka", Environments: []string{"prod", "staging"}},
+			},
+		},
+	}
+
+	assert.Equal(t, `~ service orders
+  ~ requests billing: async false -> true, deprecated false -> true
+  ~ sends Kafka: env "prod" -> "prod, staging"
+  ~ uses PostgreSQL: port 5432 -> 6432
+`, Diff(oldFiles, newFiles).String())
+}
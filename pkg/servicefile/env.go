@@ -0,0 +1,118 @@
+package servicefile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadWithEnv reads and parses a ServiceFile like Load, resolving variables
+// from env before falling back to the process environment.
+func LoadWithEnv(path string, env map[string]string) (*ServiceFile, error) {
+	var sf ServiceFile
+	if err := decodeYAMLFile(path, env, &sf); err != nil {
+		return nil, err
+	}
+
+	return &sf, nil
+}
+
+// decodeYAMLFile decodes the YAML file at path into v, expanding the variables of its string values
+// from env before falling back to the process environment.
+func decodeYAMLFile(path string, env map[string]string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("failed to parse file %s: %w", path, err)
+	}
+
+	lookup := func(name string) (string, bool) {
+		if value, ok := env[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}
+
+	if err := interpolateNode(&root, lookup); err != nil {
+		return fmt.Errorf("failed to interpolate file %s: %w", path, err)
+	}
+
+	if err := root.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// interpolateNode expands variables in every string scalar of the YAML tree.
+func interpolateNode(node *yaml.Node, lookup func(string) (string, bool)) error {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!str" {
+		value, err := interpolate(node.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				// Let a plain scalar resolve to the type of its expanded value.
+				node.Tag = ""
+			}
+		}
+
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := interpolateNode(child, lookup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// interpolate expands ${VAR} and ${VAR:-default} references in s.
+// A reference to a variable that is not set is an error unless it has a default.
+func interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		end += start
+
+		b.WriteString(s[:start])
+
+		name, fallback, hasDefault := strings.Cut(s[start+2:end], ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable reference in %q", s)
+		}
+
+		value, ok := lookup(name)
+		switch {
+		case ok:
+			b.WriteString(value)
+		case hasDefault:
+			b.WriteString(fallback)
+		default:
+			return "", fmt.Errorf("variable %s is not set", name)
+		}
+
+		s = s[end+1:]
+	}
+}
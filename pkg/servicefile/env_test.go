@@ -0,0 +1,131 @@
+package servicefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWithEnv(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		yamlContent string
+		env         map[string]string
+		want        *ServiceFile
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "resolve variables",
+			yamlContent: `
+servicefile: "0.1.0"
+info:
+    name: "${SERVICE_NAME}"
+    description: "Runs in ${ENVIRONMENT}"
+relationships:
+  - action: "uses"
+    name: "${DB_HOST}"
+    technology: postgresql
+`,
+			env: map[string]string{
+				"SERVICE_NAME": "orders",
+				"ENVIRONMENT":  "staging",
+				"DB_HOST":      "orders-db.staging",
+			},
+			want: &ServiceFile{
+				Version: "0.1.0",
+				Info: Info{
+					Name:        "orders",
+					Description: "Runs in staging",
+				},
+				Relationships: []Relationship{
+					{Action: "uses", Name: "orders-db.staging", Technology: "postgresql"},
+				},
+			},
+		},
+		{
+			name: "default values",
+			yamlContent: `
+servicefile: ${VERSION:-0.1.0}
+info:
+    name: orders
+    system: ${SYSTEM:-}
+relationships:
+  - action: "uses"
+    name: "${DB_HOST:-localhost}"
+    technology: postgresql
+`,
+			env: map[string]string{
+				"DB_HOST": "orders-db.prod",
+			},
+			want: &ServiceFile{
+				Version: "0.1.0",
+				Info: Info{
+					Name: "orders",
+				},
+				Relationships: []Relationship{
+					{Action: "uses", Name: "orders-db.prod", Technology: "postgresql"},
+				},
+			},
+		},
+		{
+			name: "missing variable",
+			yamlContent: `
+servicefile: "0.1.0"
+info:
+    name: orders
+    description: "Runs in ${SERVICEFILE_TEST_MISSING_ENVIRONMENT}"
+`,
+			wantErr:     true,
+			errContains: "line 5: variable SERVICEFILE_TEST_MISSING_ENVIRONMENT is not set",
+		},
+		{
+			name: "unterminated reference",
+			yamlContent: `
+servicefile: "0.1.0"
+info:
+    name: "${SERVICE_NAME"
+`,
+			wantErr:     true,
+			errContains: "unterminated variable reference",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			tmpFile := filepath.Join(tmpDir, "servicefile.yaml")
+
+			err := os.WriteFile(tmpFile, []byte(tt.yamlContent), 0644)
+			require.NoError(t, err)
+
+			got, err := LoadWithEnv(tmpFile, tt.env)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				assert.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadExpandsProcessEnvironment(t *testing.T) {
+	t.Setenv("SERVICEFILE_TEST_NAME", "from-environment")
+
+	tmpFile := filepath.Join(t.TempDir(), "servicefile.yaml")
+	err := os.WriteFile(tmpFile, []byte("servicefile: 0.1.0\ninfo:\n    name: ${SERVICEFILE_TEST_NAME}\n"), 0644)
+	require.NoError(t, err)
+
+	got, err := Load(tmpFile)
+	require.NoError(t, err)
+	assert.Equal(t, "from-environment", got.Info.Name)
+}
@@ -0,0 +1,273 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// WriteFiles writes each service file as YAML to dir, creating it as needed.
+// Files are named after the service name, as {name}.servicefile.yaml, see WriteServiceFiles.
+// An error is returned if two services would be written to the same file.
+func WriteFiles(files []*ServiceFile, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	return WriteServiceFiles(files, dir, "{name}.servicefile.yaml")
+}
+
+// WriteServiceFiles writes each service file to dir with WriteFile, at the path given by pattern
+// relative to dir, where {name} stands for the service name and {system} for its system,
+// such as {name}.servicefile.yaml, {name}/servicefile.json or {system}/{name}.yaml.
+// Names are turned into filesystem-safe tokens, see pathToken, so that a service named
+// "billing/api" is written to billing-api.yaml rather than to a subdirectory.
+// An error is returned if two services would be written to the same file, or if a service
+// would be written outside of dir.
+func WriteServiceFiles(files []*ServiceFile, dir string, pattern string) error {
+	if !strings.Contains(pattern, "{name}") {
+		return fmt.Errorf("pattern %q does not contain {name}", pattern)
+	}
+
+	written := make(map[string]string, len(files))
+
+	for _, sf := range files {
+		name := strings.NewReplacer(
+			"{name}", pathToken(sf.Info.Name),
+			"{system}", pathToken(sf.Info.System),
+		).Replace(pattern)
+
+		path := filepath.Join(dir, name)
+		if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("service %s would be written to %s, outside of %s", sf.Info.Name, name, dir)
+		}
+
+		if other, exists := written[name]; exists {
+			return fmt.Errorf("services %s and %s would both be written to %s", other, sf.Info.Name, name)
+		}
+		written[name] = sf.Info.Name
+
+		if err := sf.WriteFile(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pathToken returns name as a single lower-cased path element: path separators, spaces and other
+// characters that are not letters, digits, dots, dashes or underscores are replaced by dashes,
+// and leading dots are dropped so that the token can't be a hidden file or a parent directory.
+// An empty token is returned as an underscore.
+func pathToken(name string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+
+	token := strings.TrimLeft(b.String(), ".")
+	if token == "" {
+		return "_"
+	}
+
+	return token
+}
+
+// WriteFile sorts the service file and writes it to path, as JSON when path has a .json extension
+// and as YAML otherwise, creating parent directories as needed.
+// The file is written atomically: readers see either the previous content or the new one.
+func (sf *ServiceFile) WriteFile(path string) error {
+	sf.Sort()
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if filepath.Ext(path) == ".json" {
+		data, err = json.MarshalIndent(sf, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = MarshalYAML(sf)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode service %s: %w", sf.Info.Name, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+
+	if err := WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path, then renames it to path,
+// so that readers see either the previous content of path or data.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadOptions configures LoadDir.
+type LoadOptions struct {
+	// Recursive makes LoadDir read service files of subdirectories too.
+	Recursive bool
+}
+
+// LoadOption configures LoadOptions.
+type LoadOption func(*LoadOptions)
+
+// WithRecursive makes LoadDir read service files of subdirectories too.
+func WithRecursive() LoadOption {
+	return func(o *LoadOptions) {
+		o.Recursive = true
+	}
+}
+
+// LoadDir reads every *.yaml and *.yml service file in dir with Load, sorted by path.
+// An error is returned if a file has an unsupported version or if a service is defined by more than one file.
+func LoadDir(dir string, opts ...LoadOption) ([]*ServiceFile, error) {
+	return loadDir(dir, []string{".yaml", ".yml"}, func(path string) (*ServiceFile, error) {
+		sf, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkVersion(sf); err != nil {
+			return nil, fmt.Errorf("file %s: %w", path, err)
+		}
+
+		return sf, nil
+	}, opts)
+}
+
+// LoadServiceFile reads a service file, as JSON when path has a .json extension and as YAML with Load otherwise,
+// and validates it. Errors mention the path of the file.
+func LoadServiceFile(path string) (*ServiceFile, error) {
+	var (
+		sf  *ServiceFile
+		err error
+	)
+
+	if filepath.Ext(path) == ".json" {
+		sf, err = loadJSON(path)
+	} else {
+		sf, err = Load(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid service file %s: %w", path, err)
+	}
+
+	return sf, nil
+}
+
+// LoadServiceFiles reads every *.yaml, *.yml and *.json service file in dir with LoadServiceFile, sorted by path.
+// An error is returned if a file is invalid or if a service is defined by more than one file.
+func LoadServiceFiles(dir string, opts ...LoadOption) ([]*ServiceFile, error) {
+	return loadDir(dir, []string{".yaml", ".yml", ".json"}, LoadServiceFile, opts)
+}
+
+func loadJSON(path string) (*ServiceFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	var sf ServiceFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("failed to parse file %s: %w", path, err)
+	}
+
+	return &sf, nil
+}
+
+// loadDir reads the files of dir with one of the extensions using load, sorted by path.
+func loadDir(dir string, extensions []string, load func(path string) (*ServiceFile, error), opts []LoadOption) ([]*ServiceFile, error) {
+	var o LoadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		files []*ServiceFile
+		paths = make(map[string]string)
+	)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && !o.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !slices.Contains(extensions, filepath.Ext(path)) {
+			return nil
+		}
+
+		sf, err := load(path)
+		if err != nil {
+			return err
+		}
+
+		if other, exists := paths[sf.Info.Name]; exists {
+			return fmt.Errorf("service %q is defined in both %s and %s", sf.Info.Name, other, path)
+		}
+		paths[sf.Info.Name] = path
+
+		files = append(files, sf)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load directory %s: %w", dir, err)
+	}
+
+	return files, nil
+}
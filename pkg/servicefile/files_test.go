@@ -0,0 +1,300 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDir(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "billing", Description: "Charges customers", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders", Technology: "grpc", Proto: "grpc"},
+			},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "orders", Description: "Takes orders", Annotations: map[string]string{"owner": "team-orders"}},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", TimeoutMS: 500},
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders"},
+			},
+		},
+		{
+			Version:       Version,
+			Info:          Info{Name: "Auth"},
+			Relationships: []Relationship{},
+		},
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, WriteFiles(files, dir))
+
+	loaded, err := LoadDir(dir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, files, loaded)
+}
+
+func TestLoadDirErrors(t *testing.T) {
+	t.Parallel()
+
+	valid := "servicefile: \"0.1.0\"\ninfo:\n  name: orders\nrelationships: []\n"
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		opts        []LoadOption
+		want        []string
+		errContains string
+	}{
+		{
+			name:  "subdirectories are skipped",
+			files: map[string]string{"orders.yaml": valid, "nested/orders.yml": valid, "README.md": "# Services"},
+			want:  []string{"orders"},
+		},
+		{
+			name:        "duplicate service in subdirectory",
+			files:       map[string]string{"orders.yaml": valid, "nested/orders.yml": valid},
+			opts:        []LoadOption{WithRecursive()},
+			errContains: `service "orders" is defined in both`,
+		},
+		{
+			name:        "unsupported version",
+			files:       map[string]string{"orders.yaml": "servicefile: \"9.9.9\"\ninfo:\n  name: orders\n"},
+			errContains: `unsupported version "9.9.9"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			loaded, err := LoadDir(dir, tt.opts...)
+
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(loaded))
+			for _, sf := range loaded {
+				names = append(names, sf.Info.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestLoadServiceFiles(t *testing.T) {
+	t.Parallel()
+
+	billing := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "billing", Description: "Charges customers", System: "shop", Tags: []string{"payments"}},
+		Relationships: []Relationship{
+			{Action: RelationshipActionReplies, Name: "orders", Technology: "grpc", Proto: "grpc"},
+		},
+	}
+	orders := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "orders", Description: "Takes orders", Owner: "team-orders"},
+		Relationships: []Relationship{
+			{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", TimeoutMS: 500},
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Port: 5432},
+		},
+	}
+
+	dir := t.TempDir()
+
+	data, err := MarshalYAML(billing)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "billing.servicefile.yaml"), data, 0o644))
+
+	data, err = json.Marshal(orders)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders.servicefile.json"), data, 0o644))
+
+	loaded, err := LoadServiceFile(filepath.Join(dir, "orders.servicefile.json"))
+	require.NoError(t, err)
+	assert.Equal(t, orders, loaded)
+
+	all, err := LoadServiceFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceFile{billing, orders}, all)
+}
+
+func TestLoadServiceFileErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		errContains string
+	}{
+		{
+			name:        "invalid service file",
+			file:        "orders.yaml",
+			content:     "servicefile: \"0.1.0\"\ninfo:\n  name: orders\nrelationships:\n  - action: fetches\n    name: billing\n",
+			errContains: `orders.yaml: relationship 0 (fetches billing) has unknown action "fetches"`,
+		},
+		{
+			name:        "malformed JSON",
+			file:        "orders.json",
+			content:     `{"servicefile": "0.1.0", "info": {`,
+			errContains: "failed to parse file",
+		},
+		{
+			name:        "unsupported version",
+			file:        "orders.json",
+			content:     `{"servicefile": "9.9.9", "info": {"name": "orders"}}`,
+			errContains: `unsupported version "9.9.9"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			_, err := LoadServiceFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), path)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestWriteServiceFiles(t *testing.T) {
+	t.Parallel()
+
+	generate := func() []*ServiceFile {
+		return []*ServiceFile{
+			{
+				Version: Version,
+				Info:    Info{Name: "Orders", Description: "Takes orders", Tags: []string{"shop", "core"}},
+				Relationships: []Relationship{
+					{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"},
+					{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				},
+			},
+			{
+				Version:       Version,
+				Info:          Info{Name: "billing", Description: "Charges customers"},
+				Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "Orders"}},
+			},
+		}
+	}
+
+	for _, pattern := range []string{"{name}.servicefile.yaml", "{name}/servicefile.json"} {
+		t.Run(pattern, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			read := func() map[string]string {
+				contents := make(map[string]string)
+				err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+					if err != nil || d.IsDir() {
+						return err
+					}
+					data, err := os.ReadFile(path)
+					contents[path] = string(data)
+					return err
+				})
+				require.NoError(t, err)
+				return contents
+			}
+
+			require.NoError(t, WriteServiceFiles(generate(), dir, pattern))
+			first := read()
+
+			regenerated := generate()
+			regenerated[0].Relationships[0], regenerated[0].Relationships[1] = regenerated[0].Relationships[1], regenerated[0].Relationships[0]
+			require.NoError(t, WriteServiceFiles(regenerated, dir, pattern))
+
+			assert.Len(t, first, 2, "temporary files must not be left behind")
+			assert.Equal(t, first, read())
+
+			loaded, err := LoadServiceFiles(dir, WithRecursive())
+			require.NoError(t, err)
+			assert.ElementsMatch(t, regenerated, loaded)
+		})
+	}
+}
+
+func TestWriteServiceFilesErrors(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Version: Version, Info: Info{Name: "Orders"}},
+		{Version: Version, Info: Info{Name: "orders"}},
+	}
+
+	err := WriteServiceFiles(files, t.TempDir(), "{name}.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "services Orders and orders would both be written to orders.yaml")
+
+	err = WriteServiceFiles(files, t.TempDir(), "servicefile.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not contain {name}")
+
+	sanitized := []*ServiceFile{
+		{Version: Version, Info: Info{Name: "Order Service"}},
+		{Version: Version, Info: Info{Name: "order/service"}},
+	}
+
+	err = WriteServiceFiles(sanitized, t.TempDir(), "{name}.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "services Order Service and order/service would both be written to order-service.yaml")
+
+	err = WriteServiceFiles(files[:1], t.TempDir(), "../{name}.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "service Orders would be written to ../orders.yaml, outside of")
+}
+
+func TestWriteServiceFilesLayout(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Version: Version, Info: Info{Name: "Orders", System: "Shop Front"}},
+		{Version: Version, Info: Info{Name: "../billing", System: "finance"}},
+		{Version: Version, Info: Info{Name: "legacy"}},
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, WriteServiceFiles(files, dir, "{system}/{name}.yaml"))
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		paths = append(paths, filepath.ToSlash(rel))
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"shop-front/orders.yaml", "finance/-billing.yaml", "_/legacy.yaml"}, paths)
+}
@@ -0,0 +1,503 @@
+package servicefile
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DeploymentOrder returns service names ordered so that every service comes after the services it depends on.
+// Relationships to targets that are not services in files are ignored.
+// An error is returned if the dependencies form a cycle.
+func DeploymentOrder(files []*ServiceFile) ([]string, error) {
+	return NewGraph(files).TopoSort()
+}
+
+// dependencyEdge returns the direction of the dependency described by a relationship of the service.
+// A service depends on what it uses, requests or sends to, while replies and receives
+// describe the opposite side of the same interaction, so the target depends on the service.
+func dependencyEdge(service string, rel Relationship) (from, to string) {
+	switch rel.Action {
+	case RelationshipActionReplies, RelationshipActionReceives:
+		return rel.Name, service
+	default:
+		return service, rel.Name
+	}
+}
+
+// neighbors returns the nodes within depth hops of node, following relationships in both directions.
+// Nodes are services and relationship targets, including targets that are not services in files.
+func neighbors(files []*ServiceFile, node string, depth int) map[string]struct{} {
+	adjacent := make(map[string]map[string]struct{})
+	link := func(a, b string) {
+		if adjacent[a] == nil {
+			adjacent[a] = make(map[string]struct{})
+		}
+		adjacent[a][b] = struct{}{}
+	}
+
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+			link(sf.Info.Name, rel.Name)
+			link(rel.Name, sf.Info.Name)
+		}
+	}
+
+	visited := map[string]struct{}{node: {}}
+	frontier := []string{node}
+
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, n := range frontier {
+			for m := range adjacent[n] {
+				if _, seen := visited[m]; seen {
+					continue
+				}
+				visited[m] = struct{}{}
+				next = append(next, m)
+			}
+		}
+		frontier = next
+	}
+
+	return visited
+}
+
+// SystemBoundary returns the service files of the system and the sorted names of the nodes outside
+// of it that they are directly connected to: relationship targets that are not services of the system,
+// and services of other systems with a relationship to one of its services.
+// Nodes further away, like the other neighbors of boundary services, are left out.
+// Targets referring to a service by its alias are renamed to the service, see ResolveAliases,
+// the returned service files being copies.
+func SystemBoundary(files []*ServiceFile, system string) ([]*ServiceFile, []string) {
+	files = ResolveAliases(files)
+
+	members := make(map[string]struct{})
+	services := make([]*ServiceFile, 0)
+
+	for _, sf := range files {
+		if sf.Info.System == system {
+			members[sf.Info.Name] = struct{}{}
+			services = append(services, sf)
+		}
+	}
+
+	boundary := make(map[string]struct{})
+
+	for _, sf := range files {
+		_, inside := members[sf.Info.Name]
+
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			_, targetInside := members[rel.Name]
+
+			switch {
+			case inside && !targetInside:
+				boundary[rel.Name] = struct{}{}
+			case !inside && targetInside:
+				boundary[sf.Info.Name] = struct{}{}
+			}
+		}
+	}
+
+	return services, sortedKeys(boundary)
+}
+
+// DetectCycles returns the elementary dependency cycles between the services of files, each as the
+// ordered names of its services, starting with the smallest name, and sorted.
+// Services depend on each other as for DeploymentOrder: relationships to targets that are not services
+// in files are ignored, and replies and receives point from the target to the service, so that both
+// sides of the same interaction don't make a cycle.
+func DetectCycles(files []*ServiceFile) [][]string {
+	deps := NewGraph(files).dependencies()
+
+	var cycles [][]string
+	for _, component := range stronglyConnectedComponents(deps) {
+		if len(component) < 2 {
+			continue
+		}
+		cycles = append(cycles, elementaryCycles(deps, component)...)
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return slices.Compare(cycles[i], cycles[j]) < 0
+	})
+
+	return cycles
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the dependency graph,
+// computed with Tarjan's algorithm. Nodes are visited in name order so that the result is deterministic.
+func stronglyConnectedComponents(deps map[string]map[string]struct{}) [][]string {
+	var (
+		index      = make(map[string]int, len(deps))
+		lowlink    = make(map[string]int, len(deps))
+		onStack    = make(map[string]bool, len(deps))
+		stack      []string
+		components [][]string
+	)
+
+	var visit func(node string)
+	visit = func(node string) {
+		index[node] = len(index)
+		lowlink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, next := range sortedKeys(deps[node]) {
+			if _, visited := index[next]; !visited {
+				visit(next)
+				lowlink[node] = min(lowlink[node], lowlink[next])
+			} else if onStack[next] {
+				lowlink[node] = min(lowlink[node], index[next])
+			}
+		}
+
+		if lowlink[node] != index[node] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	for _, node := range slices.Sorted(maps.Keys(deps)) {
+		if _, visited := index[node]; !visited {
+			visit(node)
+		}
+	}
+
+	return components
+}
+
+// elementaryCycles returns the cycles of the dependency graph going through the sorted nodes of a
+// strongly connected component only. Each cycle is found once, from its smallest node: cycles starting
+// at a node only go through the nodes that come after it.
+func elementaryCycles(deps map[string]map[string]struct{}, component []string) [][]string {
+	var cycles [][]string
+
+	for i, start := range component {
+		allowed := make(map[string]bool, len(component)-i)
+		for _, node := range component[i:] {
+			allowed[node] = true
+		}
+
+		path := []string{start}
+		onPath := map[string]bool{start: true}
+
+		var walk func(node string)
+		walk = func(node string) {
+			for _, next := range sortedKeys(deps[node]) {
+				switch {
+				case next == start:
+					cycles = append(cycles, slices.Clone(path))
+				case allowed[next] && !onPath[next]:
+					path = append(path, next)
+					onPath[next] = true
+					walk(next)
+					path = path[:len(path)-1]
+					onPath[next] = false
+				}
+			}
+		}
+
+		walk(start)
+	}
+
+	return cycles
+}
+
+// Inbound is a relationship pointing at a service or an external component, seen from its target.
+type Inbound struct {
+	// Service is the name of the service the relationship belongs to.
+	Service     string
+	Action      RelationshipAction
+	Technology  string
+	Proto       string
+	Description string
+}
+
+// ReverseRelationships returns the relationships of files whose target is the named service
+// or external component, sorted by service and action, such as the services using a datastore.
+// Relationships are returned as they are declared, replies and receives included.
+func ReverseRelationships(files []*ServiceFile, target string) []Inbound {
+	var inbound []Inbound
+
+	for _, e := range NewGraph(files).InboundOf(target) {
+		inbound = append(inbound, Inbound{
+			Service:     e.From,
+			Action:      e.Relationship.Action,
+			Technology:  e.Relationship.Technology,
+			Proto:       e.Relationship.Proto,
+			Description: e.Relationship.Description,
+		})
+	}
+
+	return inbound
+}
+
+// IsExternal reports whether name is the target of a relationship of files without being one of their services,
+// such as a datastore or a third-party API.
+func IsExternal(files []*ServiceFile, name string) bool {
+	return NewGraph(files).IsExternal(name)
+}
+
+// MarkExternal classifies the relationship targets of files, setting External on the relationships
+// whose target is not one of the services of files. Services may be referred to before they are declared:
+// classification only depends on the whole set of files. Targets referring to a service by its alias
+// are not external, see ResolveAliases.
+func MarkExternal(files []*ServiceFile) {
+	services := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+	}
+	aliases := aliasNames(files)
+
+	for _, sf := range files {
+		for i := range sf.Relationships {
+			name := sf.Relationships[i].Name
+			if resolved, ok := aliases[name]; ok {
+				name = resolved
+			}
+			_, internal := services[name]
+			sf.Relationships[i].External = !internal && name != ""
+		}
+	}
+}
+
+// Graph is the graph of the relationships between services and the components they are related to.
+// Nodes are services and relationship targets, edges go from services to the targets of their
+// relationships, in the direction the relationships are declared.
+type Graph struct {
+	// external tells whether each node is an external component rather than a service.
+	external map[string]bool
+	edges    []Edge
+	outbound map[string][]Edge
+	inbound  map[string][]Edge
+}
+
+// Node is a node of a Graph.
+type Node struct {
+	Name string
+	// External is set on relationship targets that are not services, see IsExternal.
+	External bool
+}
+
+// Edge is a relationship of a service to its target.
+type Edge struct {
+	From         string
+	To           string
+	Relationship Relationship
+}
+
+// GraphOption configures NewGraph.
+type GraphOption func(*graphOptions)
+
+type graphOptions struct {
+	environment string
+}
+
+// WithGraphEnvironment makes NewGraph leave out the relationships that don't exist in the environment,
+// see Relationship.InEnvironment.
+func WithGraphEnvironment(env string) GraphOption {
+	return func(o *graphOptions) {
+		o.environment = env
+	}
+}
+
+// NewGraph returns the graph of files. Relationships without target, such as replies to any caller,
+// are not edges. Targets referring to a service by its alias are the node of the service, see ResolveAliases.
+func NewGraph(files []*ServiceFile, opts ...GraphOption) *Graph {
+	var o graphOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	g := &Graph{
+		external: make(map[string]bool),
+		outbound: make(map[string][]Edge),
+		inbound:  make(map[string][]Edge),
+	}
+
+	for _, sf := range files {
+		g.external[sf.Info.Name] = false
+	}
+
+	for _, sf := range ResolveAliases(files) {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" || !rel.InEnvironment(o.environment) {
+				continue
+			}
+			if _, ok := g.external[rel.Name]; !ok {
+				g.external[rel.Name] = true
+			}
+
+			g.edges = append(g.edges, Edge{From: sf.Info.Name, To: rel.Name, Relationship: rel})
+		}
+	}
+
+	slices.SortStableFunc(g.edges, func(a, b Edge) int {
+		return cmp.Or(
+			cmp.Compare(a.From, b.From),
+			cmp.Compare(a.To, b.To),
+			cmp.Compare(a.Relationship.Action, b.Relationship.Action),
+		)
+	})
+
+	for _, e := range g.edges {
+		g.outbound[e.From] = append(g.outbound[e.From], e)
+		g.inbound[e.To] = append(g.inbound[e.To], e)
+	}
+
+	return g
+}
+
+// Nodes returns the nodes of the graph sorted by name.
+func (g *Graph) Nodes() []Node {
+	nodes := make([]Node, 0, len(g.external))
+	for name, external := range g.external {
+		nodes = append(nodes, Node{Name: name, External: external})
+	}
+
+	slices.SortFunc(nodes, func(a, b Node) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return nodes
+}
+
+// Edges returns the edges of the graph sorted by service, target and action.
+func (g *Graph) Edges() []Edge {
+	return slices.Clone(g.edges)
+}
+
+// OutboundOf returns the edges from the node, sorted by target and action.
+func (g *Graph) OutboundOf(name string) []Edge {
+	return slices.Clone(g.outbound[name])
+}
+
+// InboundOf returns the edges to the node, sorted by service and action.
+func (g *Graph) InboundOf(name string) []Edge {
+	return slices.Clone(g.inbound[name])
+}
+
+// IsExternal reports whether the node is a relationship target that is not a service.
+// It returns false for names that are not nodes of the graph.
+func (g *Graph) IsExternal(name string) bool {
+	return g.external[name]
+}
+
+// dependencies maps each service name to the set of services it depends on, see dependencyEdge.
+// External components are left out.
+func (g *Graph) dependencies() map[string]map[string]struct{} {
+	deps := make(map[string]map[string]struct{}, len(g.external))
+	for name, external := range g.external {
+		if !external {
+			deps[name] = make(map[string]struct{})
+		}
+	}
+
+	for _, e := range g.edges {
+		from, to := dependencyEdge(e.From, e.Relationship)
+		if _, ok := deps[from]; !ok {
+			continue
+		}
+		if _, ok := deps[to]; !ok || from == to {
+			continue
+		}
+		deps[from][to] = struct{}{}
+	}
+
+	return deps
+}
+
+// TopoSort returns the services of the graph in dependency order, every service coming after the services
+// it depends on, ties being broken by name.
+// External components are leaves: they depend on nothing, and are left out of the order.
+// An error listing the services in a cycle is returned if the dependencies form one.
+func (g *Graph) TopoSort() ([]string, error) {
+	deps := g.dependencies()
+
+	indegree := make(map[string]int, len(deps))
+	dependents := make(map[string][]string, len(deps))
+
+	for name, targets := range deps {
+		indegree[name] = len(targets)
+		for target := range targets {
+			dependents[target] = append(dependents[target], name)
+		}
+	}
+
+	ready := make([]string, 0, len(deps))
+	for name, n := range indegree {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(deps))
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		next := dependents[name]
+		sort.Strings(next)
+		for _, dependent := range next {
+			indegree[dependent]--
+			if indegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+		sort.Strings(ready)
+	}
+
+	if len(order) != len(deps) {
+		// Services left unordered are in a cycle or depend on one, only the former are reported.
+		var cyclic []string
+		for _, component := range stronglyConnectedComponents(deps) {
+			if len(component) > 1 {
+				cyclic = append(cyclic, component...)
+			}
+		}
+		sort.Strings(cyclic)
+
+		return nil, fmt.Errorf("dependency cycle detected between services: %s", strings.Join(cyclic, ", "))
+	}
+
+	return order, nil
+}
+
+// Orphans returns the sorted names of the services without any edge, neither relationship with a target
+// nor relationship of another service targeting them. They are often dead or mis-annotated services.
+// External components always have an inbound edge, and are never orphans.
+func (g *Graph) Orphans() []string {
+	var orphans []string
+	for _, node := range g.Nodes() {
+		if !node.External && len(g.outbound[node.Name]) == 0 && len(g.inbound[node.Name]) == 0 {
+			orphans = append(orphans, node.Name)
+		}
+	}
+
+	return orphans
+}
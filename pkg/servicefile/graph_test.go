@@ -0,0 +1,365 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		files       []*ServiceFile
+		want        []string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "dag",
+			files: []*ServiceFile{
+				{
+					Info: Info{Name: "api"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionRequests, Name: "auth"},
+						{Action: RelationshipActionSends, Name: "notification"},
+						{Action: RelationshipActionUses, Name: "PostgreSQL"},
+					},
+				},
+				{
+					Info: Info{Name: "notification"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionRequests, Name: "auth"},
+						{Action: RelationshipActionReceives, Name: "api"},
+					},
+				},
+				{
+					Info: Info{Name: "auth"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionReplies, Name: "api"},
+						{Action: RelationshipActionUses, Name: "Redis"},
+					},
+				},
+			},
+			want: []string{"auth", "notification", "api"},
+		},
+		{
+			name: "cycle",
+			files: []*ServiceFile{
+				{
+					Info:          Info{Name: "a"},
+					Relationships: []Relationship{{Action: RelationshipActionUses, Name: "b"}},
+				},
+				{
+					Info:          Info{Name: "b"},
+					Relationships: []Relationship{{Action: RelationshipActionUses, Name: "c"}},
+				},
+				{
+					Info:          Info{Name: "c"},
+					Relationships: []Relationship{{Action: RelationshipActionUses, Name: "a"}},
+				},
+				{
+					Info: Info{Name: "d"},
+				},
+			},
+			wantErr:     true,
+			errContains: "a, b, c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DeploymentOrder(tt.files)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				assert.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectCycles(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/cycles")
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, DetectCycles(files))
+
+	files = append(files,
+		&ServiceFile{
+			Info:          Info{Name: "e"},
+			Relationships: []Relationship{{Action: RelationshipActionUses, Name: "d"}},
+		},
+		&ServiceFile{
+			Info:          Info{Name: "f"},
+			Relationships: []Relationship{{Action: RelationshipActionUses, Name: "e"}, {Action: RelationshipActionUses, Name: "f"}},
+		},
+	)
+	files[0].Relationships = append(files[0].Relationships, Relationship{Action: RelationshipActionUses, Name: "e"})
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"a", "e", "d"}}, DetectCycles(files))
+
+	assert.Empty(t, DetectCycles([]*ServiceFile{
+		{Info: Info{Name: "orders"}, Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "billing"}}},
+		{Info: Info{Name: "billing"}, Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "orders"}}},
+	}))
+}
+
+func TestReverseRelationships(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp", Description: "Stores orders"},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionReplies},
+			},
+		},
+		{
+			Info: Info{Name: "billing"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"},
+				{Action: RelationshipActionReplies, Name: "orders", Technology: "grpc"},
+			},
+		},
+		{
+			Info:          Info{Name: "analytics"},
+			Relationships: []Relationship{{Action: RelationshipActionReceives, Name: "PostgreSQL", Technology: "debezium"}},
+		},
+	}
+
+	assert.Equal(t, []Inbound{
+		{Service: "analytics", Action: RelationshipActionReceives, Technology: "debezium"},
+		{Service: "billing", Action: RelationshipActionUses, Technology: "postgresql"},
+		{Service: "orders", Action: RelationshipActionUses, Technology: "postgresql", Proto: "tcp", Description: "Stores orders"},
+	}, ReverseRelationships(files, "PostgreSQL"))
+
+	assert.Equal(t, []Inbound{
+		{Service: "orders", Action: RelationshipActionRequests, Technology: "grpc"},
+	}, ReverseRelationships(files, "billing"))
+
+	assert.Empty(t, ReverseRelationships(files, "analytics"))
+	assert.Empty(t, ReverseRelationships(files, ""))
+}
+
+func TestSystemBoundary(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing"},
+				{Action: RelationshipActionRequests, Name: "payments"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+				{Action: RelationshipActionReplies},
+			},
+		},
+		{
+			Info:          Info{Name: "billing", System: "shop"},
+			Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "orders"}},
+		},
+		{
+			Info:          Info{Name: "payments", System: "finance"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "Bank"}},
+		},
+		{
+			Info:          Info{Name: "fraud", System: "finance"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "payments"}},
+		},
+		{
+			Info:          Info{Name: "analytics", System: "data"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "orders"}},
+		},
+	}
+
+	services, boundary := SystemBoundary(files, "shop")
+
+	names := make([]string, 0, len(services))
+	for _, sf := range services {
+		names = append(names, sf.Info.Name)
+	}
+
+	assert.Equal(t, []string{"orders", "billing"}, names)
+	assert.Equal(t, []string{"PostgreSQL", "analytics", "payments"}, boundary)
+	assert.NotContains(t, boundary, "Bank")
+	assert.NotContains(t, boundary, "fraud")
+}
+
+func TestSystemBoundaryWithAlias(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info:          Info{Name: "orders", System: "shop"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "pay"}},
+		},
+		{Info: Info{Name: "payments", Alias: "pay", System: "finance"}},
+	}
+
+	services, boundary := SystemBoundary(files, "shop")
+
+	require.Len(t, services, 1)
+	assert.Equal(t, "payments", services[0].Relationships[0].Name)
+	assert.Equal(t, []string{"payments"}, boundary)
+}
+
+func TestMarkExternal(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+				{Action: RelationshipActionReplies},
+			},
+		},
+		{
+			Info:          Info{Name: "billing"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "Stripe"}},
+		},
+	}
+
+	assert.True(t, IsExternal(files, "PostgreSQL"))
+	assert.True(t, IsExternal(files, "Stripe"))
+	assert.False(t, IsExternal(files, "billing"), "billing is referred to before it is declared")
+	assert.False(t, IsExternal(files, "orders"))
+	assert.False(t, IsExternal(files, "Redis"), "Redis is never referred to")
+	assert.False(t, IsExternal(files, ""))
+
+	MarkExternal(files)
+
+	assert.Equal(t, []bool{false, true, false}, []bool{
+		files[0].Relationships[0].External,
+		files[0].Relationships[1].External,
+		files[0].Relationships[2].External,
+	})
+	assert.True(t, files[1].Relationships[0].External)
+}
+
+func TestMarkExternalWithAlias(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "payments"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+			},
+		},
+		{Info: Info{Name: "billing", Alias: "payments"}},
+	}
+
+	assert.False(t, IsExternal(files, "payments"))
+
+	MarkExternal(files)
+
+	assert.False(t, files[0].Relationships[0].External, "payments is the alias of billing")
+	assert.True(t, files[0].Relationships[1].External)
+}
+
+func TestGraph(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/graph")
+	require.NoError(t, err)
+
+	g := NewGraph(files)
+
+	assert.Equal(t, []Node{
+		{Name: "PostgreSQL", External: true},
+		{Name: "billing"},
+		{Name: "orders"},
+	}, g.Nodes())
+
+	requests := Edge{From: "orders", To: "billing", Relationship: Relationship{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"}}
+	replies := Edge{From: "billing", To: "orders", Relationship: Relationship{Action: RelationshipActionReplies, Name: "orders", Technology: "grpc"}}
+	billingUses := Edge{From: "billing", To: "PostgreSQL", Relationship: Relationship{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"}}
+	ordersUses := Edge{From: "orders", To: "PostgreSQL", Relationship: Relationship{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"}}
+
+	assert.Equal(t, []Edge{billingUses, replies, ordersUses, requests}, g.Edges())
+	assert.Equal(t, []Edge{ordersUses, requests}, g.OutboundOf("orders"))
+	assert.Equal(t, []Edge{billingUses, ordersUses}, g.InboundOf("PostgreSQL"))
+	assert.Empty(t, g.OutboundOf("PostgreSQL"))
+	assert.Empty(t, g.InboundOf("unknown"))
+
+	assert.True(t, g.IsExternal("PostgreSQL"))
+	assert.False(t, g.IsExternal("orders"))
+	assert.False(t, g.IsExternal("unknown"))
+}
+
+func TestGraphTopoSort(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/diamond")
+	require.NoError(t, err)
+
+	order, err := NewGraph(files).TopoSort()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"store", "auth", "catalog", "api"}, order)
+
+	files, err = LoadDir("testdata/cycles")
+	require.NoError(t, err)
+
+	_, err = NewGraph(files).TopoSort()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle detected between services: a, b, c")
+
+	files = append(files, &ServiceFile{
+		Info:          Info{Name: "e"},
+		Relationships: []Relationship{{Action: RelationshipActionUses, Name: "a"}},
+	})
+
+	_, err = NewGraph(files).TopoSort()
+	require.Error(t, err)
+	assert.EqualError(t, err, "dependency cycle detected between services: a, b, c", "e depends on the cycle without being in it")
+}
+
+func TestGraphOrphans(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/orphans")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"legacy"}, NewGraph(files).Orphans())
+
+	files, err = LoadDir("testdata/graph")
+	require.NoError(t, err)
+
+	assert.Empty(t, NewGraph(files).Orphans())
+}
+
+func TestGraphEnvironment(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/environments")
+	require.NoError(t, err)
+
+	nodes := func(g *Graph) []string {
+		var names []string
+		for _, node := range g.Nodes() {
+			names = append(names, node.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"PostgreSQL", "Profiler", "api", "orders"}, nodes(NewGraph(files)))
+	assert.Equal(t, []string{"PostgreSQL", "Profiler", "api", "orders"}, nodes(NewGraph(files, WithGraphEnvironment("dev"))))
+	assert.Equal(t, []string{"PostgreSQL", "api", "orders"}, nodes(NewGraph(files, WithGraphEnvironment("prod"))))
+	assert.Empty(t, NewGraph(files, WithGraphEnvironment("prod")).InboundOf("Profiler"))
+}
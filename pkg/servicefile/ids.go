@@ -0,0 +1,213 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenamedFromAnnotation is the annotation of services listing their former names, comma separated,
+// so that renamed services keep the IDs of their former names, see NewIDMap.
+const RenamedFromAnnotation = "renamed-from"
+
+// IDMap maps the names of services and relationship targets to the IDs renderers use for their nodes.
+type IDMap map[string]string
+
+// NewIDMap assigns an ID to every service and relationship target of files.
+// Names present in prior keep their ID. A service that is not in prior takes over the ID of
+// a name that is gone from files, when that name is one of its aliases or when both names only
+// differ in case and punctuation. Every other name gets a slug of the name, made unique.
+// Aliases are the alias of the service and its former names, see RenamedFromAnnotation.
+func NewIDMap(files []*ServiceFile, prior IDMap) IDMap {
+	names := nodeNames(files)
+
+	present := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		present[name] = struct{}{}
+	}
+
+	ids := make(IDMap, len(names))
+	taken := make(map[string]struct{}, len(names))
+	assign := func(name, id string) {
+		ids[name] = id
+		taken[id] = struct{}{}
+	}
+
+	for _, name := range names {
+		if id, ok := prior[name]; ok {
+			assign(name, id)
+		}
+	}
+
+	// gone returns the ID of a prior name that is not used anymore.
+	gone := func(name string) (string, bool) {
+		if _, ok := present[name]; ok {
+			return "", false
+		}
+
+		id, ok := prior[name]
+		if !ok {
+			return "", false
+		}
+
+		if _, ok := taken[id]; ok {
+			return "", false
+		}
+
+		return id, true
+	}
+
+	priorNames := make([]string, 0, len(prior))
+	for name := range prior {
+		priorNames = append(priorNames, name)
+	}
+	sort.Strings(priorNames)
+
+	for _, sf := range sortedServiceFiles(files) {
+		name := sf.Info.Name
+		if _, ok := ids[name]; ok {
+			continue
+		}
+
+		for _, alias := range serviceAliases(sf) {
+			if id, ok := gone(alias); ok {
+				assign(name, id)
+				break
+			}
+		}
+
+		if _, ok := ids[name]; ok {
+			continue
+		}
+
+		for _, priorName := range priorNames {
+			if fuzzyName(priorName) != fuzzyName(name) {
+				continue
+			}
+
+			if id, ok := gone(priorName); ok {
+				assign(name, id)
+				break
+			}
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := ids[name]; ok {
+			continue
+		}
+
+		id := slug(name)
+		for i := 2; ; i++ {
+			if _, ok := taken[id]; !ok {
+				break
+			}
+			id = slug(name) + "-" + strconv.Itoa(i)
+		}
+
+		assign(name, id)
+	}
+
+	return ids
+}
+
+// WriteIDMap writes the IDs of the services and relationship targets of files as a JSON object
+// mapping names to IDs. The IDs of a prior mapping passed with WithIDMap are kept.
+func WriteIDMap(w io.Writer, files []*ServiceFile, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(NewIDMap(files, o.IDs)); err != nil {
+		return fmt.Errorf("failed to encode id map: %w", err)
+	}
+
+	return nil
+}
+
+// ReadIDMap reads a mapping written by WriteIDMap.
+func ReadIDMap(r io.Reader) (IDMap, error) {
+	var ids IDMap
+	if err := json.NewDecoder(r).Decode(&ids); err != nil {
+		return nil, fmt.Errorf("failed to decode id map: %w", err)
+	}
+
+	return ids, nil
+}
+
+// nodeNames returns the names of the services of files sorted, followed by the names of
+// the relationship targets that are not services, sorted.
+func nodeNames(files []*ServiceFile) []string {
+	services := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+	}
+
+	targets := make(map[string]struct{})
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if _, ok := services[rel.Name]; !ok && rel.Name != "" {
+				targets[rel.Name] = struct{}{}
+			}
+		}
+	}
+
+	return append(sortedKeys(services), sortedKeys(targets)...)
+}
+
+// serviceAliases returns the alias of the service followed by its former names, see RenamedFromAnnotation.
+func serviceAliases(sf *ServiceFile) []string {
+	var aliases []string
+	if alias := strings.TrimSpace(sf.Info.Alias); alias != "" {
+		aliases = append(aliases, alias)
+	}
+	for _, alias := range strings.Split(sf.Info.Annotations[RenamedFromAnnotation], ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
+
+// fuzzyName returns the lower-cased letters and digits of name.
+func fuzzyName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return -1
+		}
+	}, name)
+}
+
+// slug returns name lower-cased with every run of characters other than letters and digits replaced by a dash.
+func slug(name string) string {
+	var b strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	if b.Len() == 0 {
+		return "node"
+	}
+
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIDMap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		files    []*ServiceFile
+		prior    IDMap
+		expected IDMap
+	}{
+		{
+			name: "slugs without prior mapping",
+			files: []*ServiceFile{
+				{Info: Info{Name: "Order Service"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "PostgreSQL"}}},
+				{Info: Info{Name: "order-service"}},
+			},
+			expected: IDMap{"Order Service": "order-service", "order-service": "order-service-2", "PostgreSQL": "postgresql"},
+		},
+		{
+			name: "renamed service keeps its id through an alias",
+			files: []*ServiceFile{
+				{Info: Info{Name: "checkout", Annotations: map[string]string{RenamedFromAnnotation: "cart, basket"}}},
+			},
+			prior:    IDMap{"basket": "basket-1"},
+			expected: IDMap{"checkout": "basket-1"},
+		},
+		{
+			name: "renamed service keeps its id through a fuzzy name",
+			files: []*ServiceFile{
+				{Info: Info{Name: "Order Service"}},
+			},
+			prior:    IDMap{"order_service": "orders"},
+			expected: IDMap{"Order Service": "orders"},
+		},
+		{
+			name: "id of a name still in use is not taken over",
+			files: []*ServiceFile{
+				{Info: Info{Name: "orders"}},
+				{Info: Info{Name: "Orders"}},
+			},
+			prior:    IDMap{"orders": "orders"},
+			expected: IDMap{"orders": "orders", "Orders": "orders-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, NewIDMap(tt.files, tt.prior))
+		})
+	}
+}
+
+func TestWriteIDMap(t *testing.T) {
+	t.Parallel()
+
+	prior := []*ServiceFile{
+		{Info: Info{Name: "billing"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "Stripe"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteIDMap(&buf, prior))
+
+	ids, err := ReadIDMap(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, IDMap{"billing": "billing", "Stripe": "stripe"}, ids)
+
+	renamed := []*ServiceFile{
+		{Info: Info{Name: "payments", Annotations: map[string]string{RenamedFromAnnotation: "billing"}}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "Stripe"}}},
+	}
+
+	buf.Reset()
+	require.NoError(t, WriteIDMap(&buf, renamed, WithIDMap(ids)))
+
+	ids, err = ReadIDMap(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, IDMap{"payments": "billing", "Stripe": "stripe"}, ids)
+}
@@ -0,0 +1,109 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// CatalogIndex summarizes a catalog of service files.
+type CatalogIndex struct {
+	ServiceCount          int          `json:"service_count"`
+	RelationshipCount     int          `json:"relationship_count"`
+	UnresolvedTargetCount int          `json:"unresolved_target_count"`
+	Services              []IndexEntry `json:"services"`
+	Technologies          []string     `json:"technologies"`
+	Protos                []string     `json:"protos"`
+}
+
+// IndexEntry is a service listed in a CatalogIndex.
+type IndexEntry struct {
+	Name              string `json:"name"`
+	System            string `json:"system,omitempty"`
+	Owner             string `json:"owner,omitempty"`
+	RelationshipCount int    `json:"relationship_count"`
+}
+
+// Index writes a JSON index of the catalog: counts, the list of services sorted by name,
+// the distinct technologies and protos in use and the number of distinct relationship targets
+// that are not services of the catalog, targets referring to a service by its alias being resolved.
+func Index(files []*ServiceFile, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(NewCatalogIndex(files)); err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+
+	return nil
+}
+
+// NewCatalogIndex builds the index of the catalog.
+func NewCatalogIndex(files []*ServiceFile) CatalogIndex {
+	services := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+	}
+	aliases := aliasNames(files)
+
+	var (
+		technologies = make(map[string]struct{})
+		protos       = make(map[string]struct{})
+		unresolved   = make(map[string]struct{})
+	)
+
+	index := CatalogIndex{
+		ServiceCount: len(files),
+		Services:     make([]IndexEntry, 0, len(files)),
+	}
+
+	for _, sf := range files {
+		index.RelationshipCount += len(sf.Relationships)
+		index.Services = append(index.Services, IndexEntry{
+			Name:              sf.Info.Name,
+			System:            sf.Info.System,
+			Owner:             sf.Info.Owner,
+			RelationshipCount: len(sf.Relationships),
+		})
+
+		for _, rel := range sf.Relationships {
+			for _, technology := range rel.AllTechnologies() {
+				technologies[technology] = struct{}{}
+			}
+			if rel.Proto != "" {
+				protos[rel.Proto] = struct{}{}
+			}
+			name := rel.Name
+			if resolved, ok := aliases[name]; ok {
+				name = resolved
+			}
+			if _, ok := services[name]; !ok && name != "" {
+				unresolved[name] = struct{}{}
+			}
+		}
+	}
+
+	sort.Slice(index.Services, func(i, j int) bool {
+		if index.Services[i].Name != index.Services[j].Name {
+			return index.Services[i].Name < index.Services[j].Name
+		}
+		return index.Services[i].System < index.Services[j].System
+	})
+
+	index.Technologies = sortedKeys(technologies)
+	index.Protos = sortedKeys(protos)
+	index.UnresolvedTargetCount = len(unresolved)
+
+	return index
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
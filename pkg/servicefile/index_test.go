@@ -0,0 +1,80 @@
+package servicefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "orders", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp"},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Proto: "grpc"},
+				{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka", Proto: "tcp"},
+			},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "billing", System: "shop", Owner: "payments-team"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders", Technology: "grpc", Proto: "grpc"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql"},
+			},
+		},
+		{
+			Version: Version,
+			Info:    Info{Name: "auth"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Index(files, &buf))
+
+	var got CatalogIndex
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	expected := CatalogIndex{
+		ServiceCount:          3,
+		RelationshipCount:     5,
+		UnresolvedTargetCount: 2,
+		Services: []IndexEntry{
+			{Name: "auth", RelationshipCount: 0},
+			{Name: "billing", System: "shop", Owner: "payments-team", RelationshipCount: 2},
+			{Name: "orders", System: "shop", RelationshipCount: 3},
+		},
+		Technologies: []string{"grpc", "kafka", "postgresql"},
+		Protos:       []string{"grpc", "tcp"},
+	}
+
+	assert.Equal(t, expected, got)
+
+	var again bytes.Buffer
+	require.NoError(t, Index(files, &again))
+	assert.Equal(t, buf.String(), again.String())
+}
+
+func TestNewCatalogIndexWithAlias(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "payments"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+			},
+		},
+		{Info: Info{Name: "billing", Alias: "payments"}},
+	}
+
+	assert.Equal(t, 1, NewCatalogIndex(files).UnresolvedTargetCount, "payments is the alias of billing")
+}
@@ -0,0 +1,20 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// MarshalJSON encodes the service file with its relationships sorted, so that regenerated
+// service files only differ where their content does. The service file itself is left untouched.
+func (sf ServiceFile) MarshalJSON() ([]byte, error) {
+	type plain ServiceFile
+
+	sorted := plain(sf)
+	sorted.Relationships = make([]Relationship, len(sf.Relationships))
+	copy(sorted.Relationships, sf.Relationships)
+	sorted.Info.Tags = slices.Clone(sf.Info.Tags)
+	(*ServiceFile)(&sorted).Sort()
+
+	return json.Marshal(sorted)
+}
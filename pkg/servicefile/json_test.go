@@ -0,0 +1,58 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info: Info{
+			Name:        "orders",
+			Description: "Takes orders",
+			System:      "shop",
+			Annotations: map[string]string{"owner": "team-orders", "lifecycle": "production"},
+		},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp", Description: "Stores orders"},
+			{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka"},
+			{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", SLA: "p99<200ms", TimeoutMS: 500},
+			{Action: RelationshipActionReplies},
+		},
+	}
+
+	got, err := json.MarshalIndent(sf, "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "servicefile.golden.json")
+	if *update {
+		require.NoError(t, os.WriteFile(golden, got, 0o644))
+	}
+
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+
+	assert.Equal(t, RelationshipActionUses, sf.Relationships[0].Action, "input must be left untouched")
+
+	again, err := json.MarshalIndent(*sf, "", "  ")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(again)+"\n")
+
+	var decoded ServiceFile
+	require.NoError(t, json.Unmarshal(got, &decoded))
+	sf.Sort()
+	assert.Equal(t, *sf, decoded)
+}
@@ -0,0 +1,171 @@
+package servicefile
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Names of the rules checked by Lint.
+const (
+	// LintServiceDescription reports services without description, an error by default.
+	LintServiceDescription = "service-description"
+	// LintServiceSystem reports services without system, a warning by default.
+	LintServiceSystem = "service-system"
+	// LintRelationshipDescription reports relationships without description, an error by default.
+	LintRelationshipDescription = "relationship-description"
+	// LintRelationshipTechnology reports relationships with a target but without technology, a warning by default.
+	LintRelationshipTechnology = "relationship-technology"
+)
+
+// DefaultLintSeverities returns the severity of each lint rule when it isn't overridden.
+func DefaultLintSeverities() map[string]Severity {
+	return map[string]Severity{
+		LintServiceDescription:      SeverityError,
+		LintServiceSystem:           SeverityWarning,
+		LintRelationshipDescription: SeverityError,
+		LintRelationshipTechnology:  SeverityWarning,
+	}
+}
+
+// LintOptions configures Lint.
+type LintOptions struct {
+	// Rules lists the rules to check, every rule when empty.
+	Rules []string
+	// RuleSeverities overrides the severity of rules, see DefaultLintSeverities.
+	// Rules set to SeverityOff are not checked.
+	RuleSeverities map[string]Severity
+}
+
+// LintOption configures LintOptions.
+type LintOption func(*LintOptions)
+
+// WithLintRules limits linting to the given rules.
+func WithLintRules(rules ...string) LintOption {
+	return func(o *LintOptions) {
+		o.Rules = append(o.Rules, rules...)
+	}
+}
+
+// WithLintSeverities overrides the severity of the given lint rules, so that teams can turn
+// warnings into errors as their documentation improves.
+func WithLintSeverities(severities map[string]Severity) LintOption {
+	return func(o *LintOptions) {
+		if o.RuleSeverities == nil {
+			o.RuleSeverities = make(map[string]Severity, len(severities))
+		}
+		for rule, severity := range severities {
+			o.RuleSeverities[rule] = severity
+		}
+	}
+}
+
+func (o LintOptions) severity(rule string) Severity {
+	if severity, ok := o.RuleSeverities[rule]; ok {
+		return severity
+	}
+
+	return DefaultLintSeverities()[rule]
+}
+
+// LintIssue is a documentation gap reported by Lint.
+type LintIssue struct {
+	// Rule is the name of the rule reporting the issue.
+	Rule string
+	// Severity is the severity of the rule.
+	Severity Severity
+	// Service is the name of the service the issue is about.
+	Service string
+	// Action and Target identify the relationship of relationship issues, and are empty for service issues.
+	Action RelationshipAction
+	Target string
+	// Message describes the issue.
+	Message string
+}
+
+// String returns the severity, message and rule of the issue.
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Severity, i.Message, i.Rule)
+}
+
+// LintIssues are the issues reported by Lint.
+type LintIssues []LintIssue
+
+// MaxSeverity returns the highest severity of the issues, SeverityOff when there are none.
+func (issues LintIssues) MaxSeverity() Severity {
+	maxSeverity := SeverityOff
+	for _, issue := range issues {
+		maxSeverity = max(maxSeverity, issue.Severity)
+	}
+
+	return maxSeverity
+}
+
+// Fail reports whether some issue meets or exceeds the threshold.
+// Nothing fails at SeverityOff.
+func (issues LintIssues) Fail(threshold Severity) bool {
+	return threshold != SeverityOff && issues.MaxSeverity() >= threshold
+}
+
+// Lint checks the documentation of the service files for completeness, such as services and relationships
+// left without description, and returns the issues found. Unlike Validate, which reports service files
+// that are not well-formed, Lint reports gaps that make the documentation less useful.
+// Issues are sorted by service, service issues coming before the issues of its relationships,
+// which are in the order of ServiceFile.Sort.
+func Lint(files []*ServiceFile, opts ...LintOption) LintIssues {
+	var o LintOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var issues LintIssues
+	report := func(rule string, issue LintIssue, format string, args ...any) {
+		if len(o.Rules) > 0 && !slices.Contains(o.Rules, rule) {
+			return
+		}
+
+		severity := o.severity(rule)
+		if severity == SeverityOff {
+			return
+		}
+
+		issue.Rule = rule
+		issue.Severity = severity
+		issue.Message = fmt.Sprintf(format, args...)
+		issues = append(issues, issue)
+	}
+
+	for _, sf := range sortedServiceFiles(files) {
+		service := LintIssue{Service: sf.Info.Name}
+
+		if sf.Info.Description == "" {
+			report(LintServiceDescription, service, "service %s has no description", sf.Info.Name)
+		}
+
+		if sf.Info.System == "" {
+			report(LintServiceSystem, service, "service %s has no system", sf.Info.Name)
+		}
+
+		for _, rel := range sf.Relationships {
+			relationship := LintIssue{Service: sf.Info.Name, Action: rel.Action, Target: rel.Name}
+
+			if rel.Description == "" {
+				report(LintRelationshipDescription, relationship, "relationship %s of service %s has no description", lintRelationship(rel), sf.Info.Name)
+			}
+
+			if rel.Name != "" && len(rel.AllTechnologies()) == 0 {
+				report(LintRelationshipTechnology, relationship, "relationship %s of service %s has no technology", lintRelationship(rel), sf.Info.Name)
+			}
+		}
+	}
+
+	return issues
+}
+
+// lintRelationship returns the action of the relationship followed by its target, if any.
+func lintRelationship(rel Relationship) string {
+	if rel.Name == "" {
+		return string(rel.Action)
+	}
+
+	return string(rel.Action) + " " + rel.Name
+}
@@ -0,0 +1,144 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", Description: "Takes orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders"},
+				{Action: RelationshipActionRequests, Name: "billing", Description: "Charges orders"},
+				{Action: RelationshipActionReplies},
+			},
+		},
+		{
+			Info: Info{Name: "billing", System: "payments"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		opts     []LintOption
+		expected LintIssues
+	}{
+		{
+			name: "every rule",
+			expected: LintIssues{
+				{
+					Rule:     LintServiceDescription,
+					Severity: SeverityError,
+					Service:  "billing",
+					Message:  "service billing has no description",
+				},
+				{
+					Rule:     LintServiceSystem,
+					Severity: SeverityWarning,
+					Service:  "orders",
+					Message:  "service orders has no system",
+				},
+				{
+					Rule:     LintRelationshipDescription,
+					Severity: SeverityError,
+					Service:  "orders",
+					Action:   RelationshipActionReplies,
+					Message:  "relationship replies of service orders has no description",
+				},
+				{
+					Rule:     LintRelationshipTechnology,
+					Severity: SeverityWarning,
+					Service:  "orders",
+					Action:   RelationshipActionRequests,
+					Target:   "billing",
+					Message:  "relationship requests billing of service orders has no technology",
+				},
+			},
+		},
+		{
+			name: "selected rules",
+			opts: []LintOption{WithLintRules(LintServiceSystem, LintRelationshipTechnology)},
+			expected: LintIssues{
+				{
+					Rule:     LintServiceSystem,
+					Severity: SeverityWarning,
+					Service:  "orders",
+					Message:  "service orders has no system",
+				},
+				{
+					Rule:     LintRelationshipTechnology,
+					Severity: SeverityWarning,
+					Service:  "orders",
+					Action:   RelationshipActionRequests,
+					Target:   "billing",
+					Message:  "relationship requests billing of service orders has no technology",
+				},
+			},
+		},
+		{
+			name: "overridden severities",
+			opts: []LintOption{WithLintSeverities(map[string]Severity{
+				LintServiceDescription:      SeverityOff,
+				LintRelationshipDescription: SeverityOff,
+				LintServiceSystem:           SeverityError,
+			})},
+			expected: LintIssues{
+				{
+					Rule:     LintServiceSystem,
+					Severity: SeverityError,
+					Service:  "orders",
+					Message:  "service orders has no system",
+				},
+				{
+					Rule:     LintRelationshipTechnology,
+					Severity: SeverityWarning,
+					Service:  "orders",
+					Action:   RelationshipActionRequests,
+					Target:   "billing",
+					Message:  "relationship requests billing of service orders has no technology",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, Lint(files, tt.opts...))
+		})
+	}
+}
+
+func TestLintIssuesFail(t *testing.T) {
+	t.Parallel()
+
+	warnings := LintIssues{
+		{Rule: LintServiceSystem, Severity: SeverityWarning, Service: "orders"},
+		{Rule: LintRelationshipTechnology, Severity: SeverityWarning, Service: "orders"},
+	}
+
+	assert.Equal(t, SeverityWarning, warnings.MaxSeverity())
+	assert.False(t, warnings.Fail(SeverityError), "warnings must pass at --fail-on=error")
+	assert.True(t, warnings.Fail(SeverityWarning), "warnings must fail at --fail-on=warning")
+	assert.False(t, warnings.Fail(SeverityOff))
+
+	failing := append(warnings, LintIssue{Rule: LintServiceDescription, Severity: SeverityError, Service: "billing"})
+	assert.Equal(t, SeverityError, failing.MaxSeverity())
+	assert.True(t, failing.Fail(SeverityError))
+
+	assert.Equal(t, SeverityOff, LintIssues(nil).MaxSeverity())
+	assert.False(t, LintIssues(nil).Fail(SeverityInfo))
+}
+
+func TestLintIssueString(t *testing.T) {
+	t.Parallel()
+
+	issue := LintIssue{Rule: LintServiceSystem, Severity: SeverityWarning, Service: "orders", Message: "service orders has no system"}
+	assert.Equal(t, "warning: service orders has no system (service-system)", issue.String())
+}
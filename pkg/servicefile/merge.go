@@ -0,0 +1,94 @@
+package servicefile
+
+import "fmt"
+
+// Merge adds the definition of the same service found in another source to the service file.
+// Relationships are united, relationships with the same action and name being collapsed into the one
+// with the most technology, proto and description set, the earliest one on ties.
+// The alias, description, system and owner of the service are taken from other when not set, and so are annotations.
+// Tags are united.
+// An error is returned if other describes a service with a different name.
+func (sf *ServiceFile) Merge(other *ServiceFile) error {
+	if sf.Info.Name != other.Info.Name {
+		return fmt.Errorf("cannot merge service %q into service %q", other.Info.Name, sf.Info.Name)
+	}
+
+	if sf.Version == "" {
+		sf.Version = other.Version
+	}
+
+	if sf.Info.Source.IsZero() {
+		sf.Info.Source = other.Info.Source
+	}
+
+	if sf.Info.Alias == "" {
+		sf.Info.Alias = other.Info.Alias
+	}
+
+	if sf.Info.Description == "" {
+		sf.Info.Description = other.Info.Description
+	}
+
+	if sf.Info.System == "" {
+		sf.Info.System = other.Info.System
+	}
+
+	if sf.Info.Owner == "" {
+		sf.Info.Owner = other.Info.Owner
+	}
+
+	for _, tag := range other.Info.Tags {
+		if !sf.Info.HasTag(tag) {
+			sf.Info.Tags = append(sf.Info.Tags, tag)
+		}
+	}
+
+	for key, value := range other.Info.Annotations {
+		if _, exists := sf.Info.Annotations[key]; exists {
+			continue
+		}
+		if sf.Info.Annotations == nil {
+			sf.Info.Annotations = make(map[string]string, len(other.Info.Annotations))
+		}
+		sf.Info.Annotations[key] = value
+	}
+
+	type key struct {
+		action RelationshipAction
+		name   string
+	}
+
+	merged := make([]Relationship, 0, len(sf.Relationships)+len(other.Relationships))
+	indexes := make(map[key]int, cap(merged))
+
+	for _, rel := range append(append([]Relationship(nil), sf.Relationships...), other.Relationships...) {
+		k := key{action: rel.Action, name: rel.Name}
+
+		i, exists := indexes[k]
+		if !exists {
+			indexes[k] = len(merged)
+			merged = append(merged, rel)
+			continue
+		}
+
+		if richness(rel) > richness(merged[i]) {
+			merged[i] = rel
+		}
+	}
+
+	sf.Relationships = merged
+
+	return nil
+}
+
+// richness returns the number of optional details set on the relationship.
+func richness(rel Relationship) int {
+	n := 0
+	for _, value := range []string{rel.Technology, rel.Proto, rel.Description} {
+		if value != "" {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,68 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "orders", Tags: []string{"core"}, Annotations: map[string]string{"owner": "team-orders"}},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL"},
+			{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Proto: "grpc"},
+		},
+	}
+
+	other := &ServiceFile{
+		Version: Version,
+		Info: Info{
+			Name:        "orders",
+			Description: "Takes orders",
+			System:      "shop",
+			Tags:        []string{"checkout", "core"},
+			Annotations: map[string]string{"owner": "someone-else", "lifecycle": "experimental"},
+		},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders"},
+			{Action: RelationshipActionRequests, Name: "billing", Technology: "http"},
+			{Action: RelationshipActionSends, Name: "Kafka"},
+		},
+	}
+
+	require.NoError(t, sf.Merge(other))
+
+	assert.Equal(t, &ServiceFile{
+		Version: Version,
+		Info: Info{
+			Name:        "orders",
+			Description: "Takes orders",
+			System:      "shop",
+			Tags:        []string{"core", "checkout"},
+			Annotations: map[string]string{"owner": "team-orders", "lifecycle": "experimental"},
+		},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: "Stores orders"},
+			{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Proto: "grpc"},
+			{Action: RelationshipActionSends, Name: "Kafka"},
+		},
+	}, sf)
+
+	assert.Len(t, other.Relationships, 3, "other must be left untouched")
+}
+
+func TestMergeDifferentNames(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{Info: Info{Name: "orders", Description: "Takes orders"}}
+
+	err := sf.Merge(&ServiceFile{Info: Info{Name: "billing", Description: "Charges customers"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `cannot merge service "billing" into service "orders"`)
+	assert.Equal(t, "Takes orders", sf.Info.Description)
+}
@@ -0,0 +1,355 @@
+package servicefile
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderOptions configures what renderers include in their output.
+type RenderOptions struct {
+	// Focus limits the output to the named service and the nodes within FocusDepth hops of it.
+	// Everything is rendered when Focus is empty.
+	Focus string
+	// FocusDepth is the number of hops, following relationships in either direction, kept around Focus.
+	FocusDepth int
+	// IDs is a prior mapping of node names to IDs, see NewIDMap. When set, renderers identify nodes
+	// by stable IDs, kept across renames, and label them with their names.
+	IDs IDMap
+	// IncludeLegend makes renderers add a legend explaining the styles used in the output.
+	IncludeLegend bool
+	// Tags limits the output to the services labeled with any of the tags.
+	// Every service is rendered when Tags is empty.
+	Tags []string
+	// Environment limits the output to the relationships existing in the environment, see FilterByEnvironment.
+	// Every relationship is rendered when Environment is empty.
+	Environment string
+}
+
+// RenderOption configures RenderOptions.
+type RenderOption func(*RenderOptions)
+
+// WithFocus limits rendering to the service and the nodes within depth hops of it.
+func WithFocus(service string, depth int) RenderOption {
+	return func(o *RenderOptions) {
+		o.Focus = service
+		o.FocusDepth = depth
+	}
+}
+
+// WithIDMap makes renderers identify nodes by IDs that stay stable with respect to the prior mapping.
+func WithIDMap(prior IDMap) RenderOption {
+	return func(o *RenderOptions) {
+		if prior == nil {
+			prior = IDMap{}
+		}
+		o.IDs = prior
+	}
+}
+
+// WithLegend makes renderers add a legend explaining the styles used in the output.
+func WithLegend() RenderOption {
+	return func(o *RenderOptions) {
+		o.IncludeLegend = true
+	}
+}
+
+// WithTags limits rendering to the services labeled with any of the tags.
+func WithTags(tags ...string) RenderOption {
+	return func(o *RenderOptions) {
+		o.Tags = append(o.Tags, tags...)
+	}
+}
+
+// WithEnvironment limits rendering to the relationships existing in the environment.
+func WithEnvironment(env string) RenderOption {
+	return func(o *RenderOptions) {
+		o.Environment = env
+	}
+}
+
+// nodeIDs returns the IDs of the nodes of files, or nil when nodes are identified by their names.
+func (o RenderOptions) nodeIDs(files []*ServiceFile) IDMap {
+	if o.IDs == nil {
+		return nil
+	}
+
+	return NewIDMap(files, o.IDs)
+}
+
+func newRenderOptions(opts []RenderOption) RenderOptions {
+	var o RenderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// Apply returns the service files to render according to the options.
+// Aliases are resolved, see ResolveAliases, then relationships are filtered by environment
+// and services by tag before focusing.
+func (o RenderOptions) Apply(files []*ServiceFile) []*ServiceFile {
+	files = ResolveAliases(files)
+
+	if o.Environment != "" {
+		files = FilterByEnvironment(files, o.Environment)
+	}
+
+	if len(o.Tags) > 0 {
+		files = FilterByTags(files, o.Tags...)
+	}
+
+	if o.Focus == "" {
+		return files
+	}
+
+	return FocusSubgraph(files, o.Focus, o.FocusDepth)
+}
+
+// FilterByEnvironment returns the service files with the relationships existing in the environment only,
+// see Relationship.InEnvironment. Services are all kept, even when none of their relationships is.
+// The returned service files are copies, files are left untouched.
+func FilterByEnvironment(files []*ServiceFile, env string) []*ServiceFile {
+	result := make([]*ServiceFile, 0, len(files))
+	for _, sf := range files {
+		filtered := *sf
+		filtered.Relationships = make([]Relationship, 0, len(sf.Relationships))
+		for _, rel := range sf.Relationships {
+			if rel.InEnvironment(env) {
+				filtered.Relationships = append(filtered.Relationships, rel)
+			}
+		}
+
+		result = append(result, &filtered)
+	}
+
+	return result
+}
+
+// FilterByTags returns the services of files labeled with any of the tags.
+// Relationships to services that are filtered out are dropped, relationships to external
+// components are kept.
+// The returned service files are copies, files are left untouched.
+func FilterByTags(files []*ServiceFile, tags ...string) []*ServiceFile {
+	services := make(map[string]struct{}, len(files))
+	kept := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+		if slices.ContainsFunc(tags, sf.Info.HasTag) {
+			kept[sf.Info.Name] = struct{}{}
+		}
+	}
+
+	result := make([]*ServiceFile, 0, len(kept))
+	for _, sf := range files {
+		if _, ok := kept[sf.Info.Name]; !ok {
+			continue
+		}
+
+		filtered := *sf
+		filtered.Relationships = make([]Relationship, 0, len(sf.Relationships))
+		for _, rel := range sf.Relationships {
+			_, service := services[rel.Name]
+			if _, ok := kept[rel.Name]; ok || !service {
+				filtered.Relationships = append(filtered.Relationships, rel)
+			}
+		}
+
+		result = append(result, &filtered)
+	}
+
+	return result
+}
+
+// FocusSubgraph returns the part of files within depth hops of the focus service.
+// Only services within reach are returned, keeping their relationships to nodes within reach
+// and relationships without a target.
+// Targets referring to a service by its alias are renamed to the service, see ResolveAliases,
+// and the focus service can be given by its alias too.
+// The returned service files are copies, files are left untouched.
+func FocusSubgraph(files []*ServiceFile, focus string, depth int) []*ServiceFile {
+	if name, ok := aliasNames(files)[focus]; ok {
+		focus = name
+	}
+	files = ResolveAliases(files)
+
+	reachable := neighbors(files, focus, depth)
+
+	result := make([]*ServiceFile, 0, len(reachable))
+	for _, sf := range files {
+		if _, ok := reachable[sf.Info.Name]; !ok {
+			continue
+		}
+
+		focused := *sf
+		focused.Relationships = make([]Relationship, 0, len(sf.Relationships))
+		for _, rel := range sf.Relationships {
+			if _, ok := reachable[rel.Name]; ok || rel.Name == "" {
+				focused.Relationships = append(focused.Relationships, rel)
+			}
+		}
+
+		result = append(result, &focused)
+	}
+
+	return result
+}
+
+// sortedServiceFiles returns copies of the service files sorted by name, with sorted relationships.
+func sortedServiceFiles(files []*ServiceFile) []*ServiceFile {
+	sorted := make([]*ServiceFile, 0, len(files))
+	for _, sf := range files {
+		c := sf.Clone()
+		c.Sort()
+		sorted = append(sorted, c)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Info.Name < sorted[j].Info.Name
+	})
+
+	return sorted
+}
+
+// relationshipLabel returns the label of a relationship edge: its action followed by its technology
+// and its port.
+func relationshipLabel(rel Relationship) string {
+	var details []string
+	if technology := relationshipTechnology(rel); technology != "" {
+		details = append(details, technology)
+	}
+	if rel.Port != 0 {
+		details = append(details, "port "+strconv.Itoa(rel.Port))
+	}
+
+	if len(details) == 0 {
+		return string(rel.Action)
+	}
+
+	return string(rel.Action) + " (" + strings.Join(details, ", ") + ")"
+}
+
+// relationshipTechnology returns the technologies of a relationship joined with slashes, such as grpc/http2.
+func relationshipTechnology(rel Relationship) string {
+	return strings.Join(rel.AllTechnologies(), "/")
+}
+
+// relationshipTooltip returns the details of a relationship shown on hover: its description,
+// followed by its SLA and timeout when set.
+func relationshipTooltip(rel Relationship) string {
+	var lines []string
+	if rel.Description != "" {
+		lines = append(lines, rel.Description)
+	}
+	if rel.SLA != "" {
+		lines = append(lines, "SLA: "+rel.SLA)
+	}
+	if rel.TimeoutMS != 0 {
+		lines = append(lines, "timeout: "+strconv.Itoa(rel.TimeoutMS)+"ms")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// isMessage reports whether the relationship is an asynchronous message rather than a synchronous call or use,
+// either marked Async or sending or receiving messages.
+func isMessage(rel Relationship) bool {
+	return rel.Async || rel.Action == RelationshipActionSends || rel.Action == RelationshipActionReceives
+}
+
+// hasMessages reports whether some relationship of files with a target is an asynchronous message.
+func hasMessages(files []*ServiceFile) bool {
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name != "" && isMessage(rel) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasDeprecated reports whether some relationship of files with a target is deprecated.
+func hasDeprecated(files []*ServiceFile) bool {
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name != "" && rel.Deprecated {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// legendEntry is a style explained by the legend of a diagram.
+type legendEntry struct {
+	message    bool
+	deprecated bool
+	label      string
+}
+
+// legendEntries returns the entries of the legend of files, for the styles used by their relationships only.
+// Deprecated relationships are drawn in their own style, whether they are calls or messages.
+func legendEntries(files []*ServiceFile) []legendEntry {
+	var calls, messages bool
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" || rel.Deprecated {
+				continue
+			}
+			if isMessage(rel) {
+				messages = true
+			} else {
+				calls = true
+			}
+		}
+	}
+
+	var entries []legendEntry
+	if calls {
+		entries = append(entries, legendEntry{label: "synchronous call or use"})
+	}
+	if messages {
+		entries = append(entries, legendEntry{message: true, label: "asynchronous message"})
+	}
+	if hasDeprecated(files) {
+		entries = append(entries, legendEntry{deprecated: true, label: "deprecated"})
+	}
+
+	return entries
+}
+
+// systemGroups returns the sorted systems of the service files and their services.
+// Services without system are grouped under the empty system, which comes first.
+func systemGroups(files []*ServiceFile) ([]string, map[string][]*ServiceFile) {
+	groups := make(map[string][]*ServiceFile)
+	for _, sf := range files {
+		groups[sf.Info.System] = append(groups[sf.Info.System], sf)
+	}
+
+	return sortedKeys(keySet(groups)), groups
+}
+
+// externalTargets returns the sorted names of the relationship targets that are not services of files,
+// the ones IsExternal reports, drawn with the external style of each format.
+func externalTargets(files []*ServiceFile) []string {
+	services := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+	}
+
+	externals := make(map[string]struct{})
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if _, ok := services[rel.Name]; !ok && rel.Name != "" {
+				externals[rel.Name] = struct{}{}
+			}
+		}
+	}
+
+	return sortedKeys(externals)
+}
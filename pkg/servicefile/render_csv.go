@@ -0,0 +1,49 @@
+package servicefile
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// csvHeader is the header row written by RenderCSV.
+var csvHeader = []string{"service", "action", "target", "technology", "proto", "description"}
+
+// RenderCSV writes the relationships of the service files as CSV, for spreadsheets: a header row
+// followed by a row per relationship, sorted by service and then in the order of ServiceFile.Sort.
+// Relationships with several technologies have them joined with slashes, such as grpc/http2.
+// Services without relationships have no row.
+func RenderCSV(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	files = sortedServiceFiles(o.Apply(files))
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			row := []string{
+				sf.Info.Name,
+				string(rel.Action),
+				rel.Name,
+				relationshipTechnology(rel),
+				rel.Proto,
+				rel.Description,
+			}
+
+			if err := cw.Write(row); err != nil {
+				return fmt.Errorf("failed to write csv: %w", err)
+			}
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package servicefile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCSV(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp", Description: `Stores "orders", drafts included`},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Technologies: []string{"grpc", "http2"}},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info: Info{Name: "billing"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders", Description: "Charges orders\nand refunds them"},
+			},
+		},
+		{
+			Info: Info{Name: "audit"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderCSV(files, &buf))
+
+	assert.Equal(t, `service,action,target,technology,proto,description
+billing,replies,orders,,,"Charges orders
+and refunds them"
+orders,replies,,,,Provides order APIs
+orders,requests,billing,grpc/http2,,
+orders,uses,PostgreSQL,postgresql,tcp,"Stores ""orders"", drafts included"
+`, buf.String())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, `Stores "orders", drafts included`, records[4][5])
+}
@@ -0,0 +1,130 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderD2 writes the service files as a D2 diagram.
+// Services are shapes nested in a container per system, relationship targets that are not services
+// are shapes too, and every relationship with a target is a connection labeled with the action and
+// technology, messages sent or received being drawn dashed and deprecated relationships dashed and red. Service and relationship descriptions,
+// SLAs and timeouts are rendered as tooltips. Shapes and connections are sorted, so that the output is stable.
+// Shapes are keyed by their names, or by stable IDs labeled with their names when WithIDMap is used.
+// Services with an alias are labeled with it.
+func RenderD2(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	ids := o.nodeIDs(files)
+	files = sortedServiceFiles(o.Apply(files))
+
+	key := func(name string) string {
+		if ids == nil {
+			return d2Key(name)
+		}
+		return d2Key(ids[name])
+	}
+
+	paths := make(map[string]string)
+	path := func(name string) string {
+		if p, ok := paths[name]; ok {
+			return p
+		}
+		return key(name)
+	}
+
+	bw := bufio.NewWriter(w)
+
+	shape := func(indent, name, label, tooltip string) {
+		var attrs []string
+		if ids != nil || label != name {
+			attrs = append(attrs, "label: "+d2String(label))
+		}
+		if tooltip != "" {
+			attrs = append(attrs, "tooltip: "+d2String(tooltip))
+		}
+
+		if len(attrs) == 0 {
+			fmt.Fprintf(bw, "%s%s\n", indent, key(name))
+			return
+		}
+		fmt.Fprintf(bw, "%s%s: {%s}\n", indent, key(name), strings.Join(attrs, "; "))
+	}
+
+	fmt.Fprintln(bw, "direction: right")
+
+	systems, groups := systemGroups(files)
+	for _, system := range systems {
+		if system == "" {
+			for _, sf := range groups[system] {
+				shape("", sf.Info.Name, sf.Info.DisplayName(), sf.Info.Description)
+			}
+			continue
+		}
+
+		fmt.Fprintf(bw, "%s: {\n", d2Key(system))
+		for _, sf := range groups[system] {
+			shape("  ", sf.Info.Name, sf.Info.DisplayName(), sf.Info.Description)
+			paths[sf.Info.Name] = d2Key(system) + "." + key(sf.Info.Name)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+
+	for _, name := range externalTargets(files) {
+		shape("", name, name, "")
+	}
+
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			var attrs []string
+			if tooltip := relationshipTooltip(rel); tooltip != "" {
+				attrs = append(attrs, "tooltip: "+d2String(tooltip))
+			}
+			if isMessage(rel) || rel.Deprecated {
+				attrs = append(attrs, "style.stroke-dash: 3")
+			}
+			if rel.Deprecated {
+				attrs = append(attrs, "style.stroke: red", "style.font-color: red")
+			}
+
+			fmt.Fprintf(bw, "%s -> %s: %s", path(sf.Info.Name), path(rel.Name), d2String(relationshipLabel(rel)))
+			if len(attrs) > 0 {
+				fmt.Fprintf(bw, " {%s}", strings.Join(attrs, "; "))
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write d2: %w", err)
+	}
+
+	return nil
+}
+
+// d2Key returns s as a D2 key, quoted unless it is made of letters, digits, underscores and dashes only.
+// Quoting keeps spaces, dots and other characters with a meaning in D2 part of the key.
+func d2Key(s string) string {
+	plain := s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+	}) < 0
+	if plain {
+		return s
+	}
+
+	return d2String(s)
+}
+
+// d2String returns s as a double quoted D2 string.
+func d2String(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
@@ -0,0 +1,70 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderD2(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop", Description: "Takes orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: `Stores "orders"`},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", SLA: "p99<200ms", TimeoutMS: 500},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info: Info{Name: "billing", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionSends, Name: "events.kafka", Technology: "kafka"},
+			},
+		},
+		{
+			Info: Info{Name: "Mail Service"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReceives, Name: "events.kafka", Technology: "kafka"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderD2(files, &buf))
+
+	assert.Equal(t, `direction: right
+"Mail Service"
+shop: {
+  billing
+  orders: {tooltip: "Takes orders"}
+}
+PostgreSQL
+"events.kafka"
+"Mail Service" -> "events.kafka": "receives (kafka)" {style.stroke-dash: 3}
+shop.billing -> "events.kafka": "sends (kafka)" {style.stroke-dash: 3}
+shop.orders -> shop.billing: "requests (grpc)" {tooltip: "SLA: p99<200ms\ntimeout: 500ms"}
+shop.orders -> PostgreSQL: "uses (postgresql)" {tooltip: "Stores \"orders\""}
+`, buf.String())
+}
+
+func TestRenderD2WithIDMap(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "Order Service"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "PostgreSQL"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderD2(files, &buf, WithIDMap(IDMap{"OrderService": "orders"})))
+
+	assert.Equal(t, `direction: right
+orders: {label: "Order Service"}
+postgresql: {label: "PostgreSQL"}
+orders -> postgresql: "uses"
+`, buf.String())
+}
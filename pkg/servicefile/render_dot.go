@@ -0,0 +1,132 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderDOT writes the service files as a Graphviz digraph.
+// Services are nodes grouped in a cluster per system, relationship targets that are not services
+// are boxes, and every relationship with a target is an edge from the service to the target labeled
+// with the action and technology. Nodes and edges are sorted, so that the output is stable.
+// Relationship descriptions, SLAs and timeouts are rendered as edge tooltips,
+// messages sent or received are drawn dashed and deprecated relationships dashed and red.
+// Nodes are identified by their names, or by stable IDs labeled with their names when WithIDMap is used,
+// services having an alias being labeled with their alias instead.
+func RenderDOT(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	ids := o.nodeIDs(files)
+	files = sortedServiceFiles(o.Apply(files))
+
+	id := func(name string) string {
+		if ids == nil {
+			return dotQuote(name)
+		}
+		return dotQuote(ids[name])
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph servicefile {")
+
+	node := func(indent, name, label string, attrs ...string) {
+		if ids != nil || label != name {
+			attrs = append([]string{"label=" + dotQuote(label)}, attrs...)
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(bw, "%s%s;\n", indent, id(name))
+			return
+		}
+		fmt.Fprintf(bw, "%s%s [%s];\n", indent, id(name), strings.Join(attrs, ", "))
+	}
+
+	systems, groups := systemGroups(files)
+	for _, system := range systems {
+		if system == "" {
+			for _, sf := range groups[system] {
+				node("  ", sf.Info.Name, sf.Info.DisplayName())
+			}
+			continue
+		}
+
+		fmt.Fprintf(bw, "  subgraph %s {\n", dotQuote("cluster_"+system))
+		fmt.Fprintf(bw, "    label=%s;\n", dotQuote(system))
+		for _, sf := range groups[system] {
+			node("    ", sf.Info.Name, sf.Info.DisplayName())
+		}
+		fmt.Fprintln(bw, "  }")
+	}
+
+	for _, name := range externalTargets(files) {
+		node("  ", name, name, "shape=box")
+	}
+
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			attrs := []string{"label=" + dotQuote(relationshipLabel(rel))}
+			if tooltip := relationshipTooltip(rel); tooltip != "" {
+				attrs = append(attrs, "tooltip="+dotQuote(tooltip))
+			}
+			if isMessage(rel) || rel.Deprecated {
+				attrs = append(attrs, "style=dashed")
+			}
+			if rel.Deprecated {
+				attrs = append(attrs, "color=red", "fontcolor=red")
+			}
+
+			fmt.Fprintf(bw, "  %s -> %s [%s];\n", id(sf.Info.Name), id(rel.Name), strings.Join(attrs, ", "))
+		}
+	}
+
+	if o.IncludeLegend {
+		writeDOTLegend(bw, legendEntries(files))
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write dot: %w", err)
+	}
+
+	return nil
+}
+
+// writeDOTLegend writes the legend as a cluster with an example edge for each entry.
+func writeDOTLegend(w io.Writer, entries []legendEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "  subgraph cluster_legend {")
+	fmt.Fprintln(w, `    label="Legend";`)
+	fmt.Fprintln(w, "    node [shape=point];")
+
+	for i, entry := range entries {
+		attrs := []string{"label=" + dotQuote(entry.label)}
+		if entry.message || entry.deprecated {
+			attrs = append(attrs, "style=dashed")
+		}
+		if entry.deprecated {
+			attrs = append(attrs, "color=red", "fontcolor=red")
+		}
+
+		fmt.Fprintf(w, "    \"legend_%d_from\" -> \"legend_%d_to\" [%s];\n", i, i, strings.Join(attrs, ", "))
+	}
+
+	fmt.Fprintln(w, "  }")
+}
+
+// dotQuote returns s as a quoted DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
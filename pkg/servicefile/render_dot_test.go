@@ -0,0 +1,258 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDOT(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Port: 5432, Description: `Stores "orders"`},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Technologies: []string{"grpc", "http2"}},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info: Info{Name: "billing"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders", Description: "Charges orders"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDOT(files, &buf))
+
+	assert.Equal(t, `digraph servicefile {
+  "billing";
+  "orders";
+  "PostgreSQL" [shape=box];
+  "billing" -> "orders" [label="replies", tooltip="Charges orders"];
+  "orders" -> "billing" [label="requests (grpc/http2)"];
+  "orders" -> "PostgreSQL" [label="uses (postgresql, port 5432)", tooltip="Stores \"orders\""];
+}
+`, buf.String())
+}
+
+func TestRenderDOTWithSystems(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "orders", System: "shop"}, Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "billing"}}},
+		{Info: Info{Name: "billing", System: "shop"}, Relationships: []Relationship{{Action: RelationshipActionSends, Name: "Kafka"}}},
+		{Info: Info{Name: "mailer", System: "notifications"}, Relationships: []Relationship{{Action: RelationshipActionReceives, Name: "Kafka"}}},
+		{Info: Info{Name: "gateway"}, Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "orders"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDOT(files, &buf))
+
+	assert.Equal(t, `digraph servicefile {
+  "gateway";
+  subgraph "cluster_notifications" {
+    label="notifications";
+    "mailer";
+  }
+  subgraph "cluster_shop" {
+    label="shop";
+    "billing";
+    "orders";
+  }
+  "Kafka" [shape=box];
+  "billing" -> "Kafka" [label="sends", style=dashed];
+  "gateway" -> "orders" [label="requests"];
+  "mailer" -> "Kafka" [label="receives", style=dashed];
+  "orders" -> "billing" [label="requests"];
+}
+`, buf.String())
+}
+
+func TestRenderDOTWithFocus(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "api"}, Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "auth"}}},
+		{Info: Info{Name: "auth"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "Redis"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDOT(files, &buf, WithFocus("api", 1)))
+
+	assert.Equal(t, `digraph servicefile {
+  "api";
+  "auth";
+  "api" -> "auth" [label="requests"];
+}
+`, buf.String())
+}
+
+func TestRenderDOTWithIDMap(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "Order Service"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "PostgreSQL"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDOT(files, &buf, WithIDMap(IDMap{"OrderService": "orders"})))
+
+	assert.Equal(t, `digraph servicefile {
+  "orders" [label="Order Service"];
+  "postgresql" [label="PostgreSQL", shape=box];
+  "orders" -> "postgresql" [label="uses"];
+}
+`, buf.String())
+}
+
+func TestRenderDOTWithSLA(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "checkout"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing", Description: "Charges customers", SLA: "p99<200ms", TimeoutMS: 500},
+				{Action: RelationshipActionRequests, Name: "inventory", TimeoutMS: 1500},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDOT(files, &buf))
+
+	assert.Equal(t, `digraph servicefile {
+  "checkout";
+  "billing" [shape=box];
+  "inventory" [shape=box];
+  "checkout" -> "billing" [label="requests", tooltip="Charges customers\nSLA: p99<200ms\ntimeout: 500ms"];
+  "checkout" -> "inventory" [label="requests", tooltip="timeout: 1500ms"];
+}
+`, buf.String())
+}
+
+func TestRenderDOTWithDeprecated(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "checkout"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+				{Action: RelationshipActionRequests, Name: "legacy-auth", Deprecated: true},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderDOT(files, &buf))
+
+	assert.Equal(t, `digraph servicefile {
+  "checkout";
+  "auth" [shape=box];
+  "legacy-auth" [shape=box];
+  "checkout" -> "auth" [label="requests"];
+  "checkout" -> "legacy-auth" [label="requests", style=dashed, color=red, fontcolor=red];
+}
+`, buf.String())
+}
+
+func TestRenderDOTWithLegend(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		files    []*ServiceFile
+		expected string
+	}{
+		{
+			name: "calls and messages",
+			files: []*ServiceFile{
+				{
+					Info: Info{Name: "orders"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionRequests, Name: "billing"},
+						{Action: RelationshipActionSends, Name: "Kafka"},
+					},
+				},
+			},
+			expected: `digraph servicefile {
+  "orders";
+  "Kafka" [shape=box];
+  "billing" [shape=box];
+  "orders" -> "billing" [label="requests"];
+  "orders" -> "Kafka" [label="sends", style=dashed];
+  subgraph cluster_legend {
+    label="Legend";
+    node [shape=point];
+    "legend_0_from" -> "legend_0_to" [label="synchronous call or use"];
+    "legend_1_from" -> "legend_1_to" [label="asynchronous message", style=dashed];
+  }
+}
+`,
+		},
+		{
+			name: "deprecated call",
+			files: []*ServiceFile{
+				{
+					Info: Info{Name: "checkout"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionRequests, Name: "auth"},
+						{Action: RelationshipActionRequests, Name: "legacy-auth", Deprecated: true},
+					},
+				},
+			},
+			expected: `digraph servicefile {
+  "checkout";
+  "auth" [shape=box];
+  "legacy-auth" [shape=box];
+  "checkout" -> "auth" [label="requests"];
+  "checkout" -> "legacy-auth" [label="requests", style=dashed, color=red, fontcolor=red];
+  subgraph cluster_legend {
+    label="Legend";
+    node [shape=point];
+    "legend_0_from" -> "legend_0_to" [label="synchronous call or use"];
+    "legend_1_from" -> "legend_1_to" [label="deprecated", style=dashed, color=red, fontcolor=red];
+  }
+}
+`,
+		},
+		{
+			name: "calls only",
+			files: []*ServiceFile{
+				{
+					Info:          Info{Name: "orders"},
+					Relationships: []Relationship{{Action: RelationshipActionUses, Name: "PostgreSQL"}},
+				},
+			},
+			expected: `digraph servicefile {
+  "orders";
+  "PostgreSQL" [shape=box];
+  "orders" -> "PostgreSQL" [label="uses"];
+  subgraph cluster_legend {
+    label="Legend";
+    node [shape=point];
+    "legend_0_from" -> "legend_0_to" [label="synchronous call or use"];
+  }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			require.NoError(t, RenderDOT(tt.files, &buf, WithLegend()))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RenderMarkdown writes the service files as Markdown documentation.
+// A table of contents linking to every service is followed by a section per service, sorted by name,
+// with its description, its system, its owner and a table of its relationships. Targets that are services
+// link to their section.
+func RenderMarkdown(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	files = sortedServiceFiles(o.Apply(files))
+
+	// Anchors are suffixed until unused, the "services" anchor being taken by the table of contents heading.
+	anchors := make(map[string]string, len(files))
+	used := map[string]bool{markdownAnchor("Services"): true}
+	for _, sf := range files {
+		base := markdownAnchor(sf.Info.Name)
+		anchor := base
+		for n := 1; used[anchor]; n++ {
+			anchor = base + "-" + strconv.Itoa(n)
+		}
+		used[anchor] = true
+		anchors[sf.Info.Name] = anchor
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# Services")
+	fmt.Fprintln(bw)
+
+	for _, sf := range files {
+		fmt.Fprintf(bw, "- [%s](#%s)\n", markdownText(sf.Info.Name), anchors[sf.Info.Name])
+	}
+
+	for _, sf := range files {
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "## %s\n", markdownText(sf.Info.Name))
+
+		if sf.Info.Description != "" {
+			fmt.Fprintln(bw)
+			fmt.Fprintln(bw, markdownText(sf.Info.Description))
+		}
+
+		if sf.Info.Alias != "" {
+			fmt.Fprintln(bw)
+			fmt.Fprintf(bw, "**Alias:** %s\n", markdownText(sf.Info.Alias))
+		}
+
+		if sf.Info.System != "" {
+			fmt.Fprintln(bw)
+			fmt.Fprintf(bw, "**System:** %s\n", markdownText(sf.Info.System))
+		}
+
+		if sf.Info.Owner != "" {
+			fmt.Fprintln(bw)
+			fmt.Fprintf(bw, "**Owner:** %s\n", markdownText(sf.Info.Owner))
+		}
+
+		fmt.Fprintln(bw)
+
+		if len(sf.Relationships) == 0 {
+			fmt.Fprintln(bw, "No relationships.")
+			continue
+		}
+
+		fmt.Fprintln(bw, "| Action | Target | Technology | Proto | Description |")
+		fmt.Fprintln(bw, "| --- | --- | --- | --- | --- |")
+
+		for _, rel := range sf.Relationships {
+			target := markdownCell(rel.Name)
+			if anchor, ok := anchors[rel.Name]; ok {
+				target = "[" + target + "](#" + anchor + ")"
+			}
+
+			fmt.Fprintf(bw, "| %s | %s | %s | %s | %s |\n",
+				markdownCell(string(rel.Action)), target,
+				markdownCell(relationshipTechnology(rel)), markdownCell(rel.Proto), markdownCell(rel.Description))
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write markdown: %w", err)
+	}
+
+	return nil
+}
+
+// markdownAnchor returns the anchor GitHub generates for a heading: the heading lower-cased,
+// without punctuation and with spaces replaced by dashes.
+func markdownAnchor(heading string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '-'
+		case r == '-', r == '_', unicode.IsLetter(r), unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		default:
+			return -1
+		}
+	}, heading)
+}
+
+// markdownText returns s with the characters starting Markdown formatting escaped.
+func markdownText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>#|", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// markdownCell returns s escaped for a table cell, which cannot span lines.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownText(s), "\n", "<br>")
+}
@@ -0,0 +1,101 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop", Owner: "team-orders", Description: "Takes orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp", Description: "Stores orders | drafts"},
+				{Action: RelationshipActionRequests, Name: "Billing Service", Technology: "grpc"},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info: Info{Name: "Billing Service", Description: "Charges customers\nand refunds them"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMarkdown(files, &buf))
+
+	assert.Equal(t, `# Services
+
+- [Billing Service](#billing-service)
+- [orders](#orders)
+
+## Billing Service
+
+Charges customers
+and refunds them
+
+No relationships.
+
+## orders
+
+Takes orders
+
+**System:** shop
+
+**Owner:** team-orders
+
+| Action | Target | Technology | Proto | Description |
+| --- | --- | --- | --- | --- |
+| replies |  |  |  | Provides order APIs |
+| requests | [Billing Service](#billing-service) | grpc |  |  |
+| uses | PostgreSQL | postgresql | tcp | Stores orders \| drafts |
+`, buf.String())
+}
+
+func TestRenderMarkdownUniqueAnchors(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "foo"}},
+		{Info: Info{Name: "foo-1"}},
+		{Info: Info{Name: "Foo"}},
+		{Info: Info{Name: "Services"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMarkdown(files, &buf))
+
+	assert.Contains(t, buf.String(), `# Services
+
+- [Foo](#foo)
+- [Services](#services-1)
+- [foo](#foo-1)
+- [foo-1](#foo-1-1)
+`)
+}
+
+func TestMarkdownAnchor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		heading  string
+		expected string
+	}{
+		{heading: "orders", expected: "orders"},
+		{heading: "Billing Service", expected: "billing-service"},
+		{heading: "api.gateway_v2", expected: "apigateway_v2"},
+		{heading: "Café (EU)", expected: "café-eu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.heading, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, markdownAnchor(tt.heading))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderMermaid writes the service files as a Mermaid flowchart.
+// Services are nodes grouped in a subgraph per system, relationship targets that are not services
+// are external nodes drawn as stadiums, and every relationship with a target is an edge labeled with
+// the action and technology, messages sent or received being drawn dotted and deprecated relationships
+// dotted and red.
+// Nodes are identified by the IDs of NewIDMap, kept stable across renames with WithIDMap.
+func RenderMermaid(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	ids := o.nodeIDs(files)
+	if ids == nil {
+		ids = NewIDMap(files, nil)
+	}
+	files = sortedServiceFiles(o.Apply(files))
+
+	id := func(name string) string {
+		return mermaidID(ids[name])
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "flowchart LR")
+
+	systems, groups := systemGroups(files)
+	for _, system := range systems {
+		indent := "  "
+		if system != "" {
+			fmt.Fprintf(bw, "  subgraph %s[%s]\n", mermaidID("system-"+slug(system)), mermaidLabel(system))
+			indent = "    "
+		}
+
+		for _, sf := range groups[system] {
+			fmt.Fprintf(bw, "%s%s[%s]\n", indent, id(sf.Info.Name), mermaidLabel(sf.Info.DisplayName()))
+		}
+
+		if system != "" {
+			fmt.Fprintln(bw, "  end")
+		}
+	}
+
+	for _, name := range externalTargets(files) {
+		fmt.Fprintf(bw, "  %s([%s])\n", id(name), mermaidLabel(name))
+	}
+
+	var edges int
+	var deprecated []string
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			arrow := "-->"
+			if isMessage(rel) || rel.Deprecated {
+				arrow = "-.->"
+			}
+			if rel.Deprecated {
+				deprecated = append(deprecated, strconv.Itoa(edges))
+			}
+
+			fmt.Fprintf(bw, "  %s %s|%s| %s\n", id(sf.Info.Name), arrow, mermaidLabel(relationshipLabel(rel)), id(rel.Name))
+			edges++
+		}
+	}
+
+	if len(deprecated) > 0 {
+		fmt.Fprintf(bw, "  linkStyle %s stroke:red,color:red\n", strings.Join(deprecated, ","))
+	}
+
+	if o.IncludeLegend {
+		writeMermaidLegend(bw, legendEntries(files), edges)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write mermaid: %w", err)
+	}
+
+	return nil
+}
+
+// writeMermaidLegend writes the legend as a subgraph with an example edge for each entry.
+// Its edges are numbered from edges, the number of edges of the diagram, for linkStyle to style them.
+func writeMermaidLegend(w io.Writer, entries []legendEntry, edges int) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "  subgraph legend[Legend]")
+
+	var deprecated []string
+	for i, entry := range entries {
+		arrow := "-->"
+		if entry.message || entry.deprecated {
+			arrow = "-.->"
+		}
+		if entry.deprecated {
+			deprecated = append(deprecated, strconv.Itoa(edges+i))
+		}
+
+		fmt.Fprintf(w, "    legend_%d_from[ ] %s|%s| legend_%d_to[ ]\n", i, arrow, mermaidLabel(entry.label), i)
+	}
+
+	fmt.Fprintln(w, "  end")
+
+	if len(deprecated) > 0 {
+		fmt.Fprintf(w, "  linkStyle %s stroke:red,color:red\n", strings.Join(deprecated, ","))
+	}
+}
+
+// mermaidID returns id made of the characters Mermaid accepts in node identifiers,
+// avoiding the end keyword that closes subgraphs.
+func mermaidID(id string) string {
+	id = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+
+	if strings.EqualFold(id, "end") {
+		id += "_"
+	}
+
+	return id
+}
+
+// mermaidLabel returns s as a quoted Mermaid label.
+func mermaidLabel(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+
+	return `"` + s + `"`
+}
@@ -0,0 +1,158 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMermaid(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Port: 5432},
+				{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka"},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info:          Info{Name: "billing", System: "shop"},
+			Relationships: []Relationship{{Action: RelationshipActionReplies, Name: "orders"}},
+		},
+		{
+			Info:          Info{Name: "Auth \"Service\""},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "end"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMermaid(files, &buf))
+
+	expected := `flowchart LR
+  auth-service["Auth #quot;Service#quot;"]
+  subgraph system-shop["shop"]
+    billing["billing"]
+    orders["orders"]
+  end
+  kafka(["Kafka"])
+  postgresql(["PostgreSQL"])
+  end_(["end"])
+  auth-service -->|"requests"| end_
+  billing -->|"replies"| orders
+  orders -->|"requests (grpc)"| billing
+  orders -.->|"sends (kafka)"| kafka
+  orders -->|"uses (postgresql, port 5432)"| postgresql
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestRenderMermaidWithAlias(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "PaymentsAuthorizationService", Alias: "Payments"},
+		},
+		{
+			Info:          Info{Name: "orders"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "Payments", Technology: "grpc"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMermaid(files, &buf))
+
+	expected := `flowchart LR
+  paymentsauthorizationservice["Payments"]
+  orders["orders"]
+  orders -->|"requests (grpc)"| paymentsauthorizationservice
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestRenderMermaidWithDeprecated(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "checkout"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+				{Action: RelationshipActionRequests, Name: "legacy-auth", Deprecated: true},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMermaid(files, &buf))
+
+	expected := `flowchart LR
+  checkout["checkout"]
+  auth(["auth"])
+  legacy-auth(["legacy-auth"])
+  checkout -->|"requests"| auth
+  checkout -.->|"requests"| legacy-auth
+  linkStyle 1 stroke:red,color:red
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestRenderMermaidWithLegend(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info:          Info{Name: "orders"},
+			Relationships: []Relationship{{Action: RelationshipActionReceives, Name: "Kafka"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMermaid(files, &buf, WithLegend()))
+
+	assert.Equal(t, `flowchart LR
+  orders["orders"]
+  kafka(["Kafka"])
+  orders -.->|"receives"| kafka
+  subgraph legend[Legend]
+    legend_0_from[ ] -.->|"asynchronous message"| legend_0_to[ ]
+  end
+`, buf.String())
+}
+
+func TestRenderMermaidWithDeprecatedLegend(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "checkout"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+				{Action: RelationshipActionRequests, Name: "legacy-auth", Deprecated: true},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMermaid(files, &buf, WithLegend()))
+
+	assert.Equal(t, `flowchart LR
+  checkout["checkout"]
+  auth(["auth"])
+  legacy-auth(["legacy-auth"])
+  checkout -->|"requests"| auth
+  checkout -.->|"requests"| legacy-auth
+  linkStyle 1 stroke:red,color:red
+  subgraph legend[Legend]
+    legend_0_from[ ] -->|"synchronous call or use"| legend_0_to[ ]
+    legend_1_from[ ] -.->|"deprecated"| legend_1_to[ ]
+  end
+  linkStyle 3 stroke:red,color:red
+`, buf.String())
+}
@@ -0,0 +1,142 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// plantUMLAsyncTag is the relationship tag of asynchronous relationships.
+const plantUMLAsyncTag = "async"
+
+// plantUMLDeprecatedTag is the relationship tag of deprecated relationships.
+const plantUMLDeprecatedTag = "deprecated"
+
+// plantUMLInclude is the C4-PlantUML library included by RenderPlantUML.
+const plantUMLInclude = "https://raw.githubusercontent.com/plantuml-stdlib/C4-PlantUML/master/C4_Container.puml"
+
+// RenderPlantUML writes the service files as a C4-PlantUML container diagram.
+// Services of a system are containers within a boundary of their system, services without system
+// are systems, and relationship targets that are not services are external systems.
+// Every relationship with a target is a Rel labeled with its action and proto, carrying its technology
+// and description, asynchronous relationships being tagged to be drawn dashed and deprecated relationships
+// to be drawn dashed and red. WithLegend adds the C4 legend.
+// Elements are identified by the IDs of NewIDMap, kept stable across renames with WithIDMap.
+func RenderPlantUML(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	ids := o.nodeIDs(files)
+	if ids == nil {
+		ids = NewIDMap(files, nil)
+	}
+	files = sortedServiceFiles(o.Apply(files))
+
+	id := func(name string) string {
+		return plantUMLID(ids[name])
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "@startuml")
+	fmt.Fprintln(bw, "!include "+plantUMLInclude)
+	fmt.Fprintln(bw)
+
+	messages, deprecated := hasMessages(files), hasDeprecated(files)
+	if messages {
+		fmt.Fprintf(bw, "AddRelTag(%q, $lineStyle = DashedLine())\n", plantUMLAsyncTag)
+	}
+	if deprecated {
+		fmt.Fprintf(bw, "AddRelTag(%q, $textColor = \"red\", $lineColor = \"red\", $lineStyle = DashedLine())\n", plantUMLDeprecatedTag)
+	}
+	if messages || deprecated {
+		fmt.Fprintln(bw)
+	}
+
+	systems, groups := systemGroups(files)
+	for _, system := range systems {
+		if system == "" {
+			for _, sf := range groups[system] {
+				fmt.Fprintf(bw, "System(%s, %s, %s)\n", id(sf.Info.Name), plantUMLString(sf.Info.DisplayName()), plantUMLString(sf.Info.Description))
+			}
+			continue
+		}
+
+		fmt.Fprintf(bw, "System_Boundary(%s, %s) {\n", plantUMLID("system-"+slug(system)), plantUMLString(system))
+		for _, sf := range groups[system] {
+			fmt.Fprintf(bw, "  Container(%s, %s, \"\", %s)\n", id(sf.Info.Name), plantUMLString(sf.Info.DisplayName()), plantUMLString(sf.Info.Description))
+		}
+		fmt.Fprintln(bw, "}")
+	}
+
+	for _, name := range externalTargets(files) {
+		fmt.Fprintf(bw, "System_Ext(%s, %s)\n", id(name), plantUMLString(name))
+	}
+
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			var relTags []string
+			if isMessage(rel) {
+				relTags = append(relTags, plantUMLAsyncTag)
+			}
+			if rel.Deprecated {
+				relTags = append(relTags, plantUMLDeprecatedTag)
+			}
+
+			var tags string
+			if len(relTags) > 0 {
+				tags = fmt.Sprintf(", $tags=%q", strings.Join(relTags, "+"))
+			}
+
+			fmt.Fprintf(bw, "Rel(%s, %s, %s, %s, %s%s)\n",
+				id(sf.Info.Name), id(rel.Name),
+				plantUMLString(plantUMLLabel(rel)), plantUMLString(relationshipTechnology(rel)), plantUMLString(rel.Description), tags)
+		}
+	}
+
+	if o.IncludeLegend {
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, "SHOW_LEGEND()")
+	}
+
+	fmt.Fprintln(bw, "@enduml")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write plantuml: %w", err)
+	}
+
+	return nil
+}
+
+// plantUMLLabel returns the label of a relationship: its action followed by its proto.
+func plantUMLLabel(rel Relationship) string {
+	if rel.Proto == "" {
+		return string(rel.Action)
+	}
+
+	return string(rel.Action) + " (" + rel.Proto + ")"
+}
+
+// plantUMLID returns id made of the characters PlantUML accepts in element aliases.
+func plantUMLID(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+}
+
+// plantUMLString returns s as a quoted macro argument. Macro arguments cannot escape double quotes,
+// which are replaced by single quotes.
+func plantUMLString(s string) string {
+	s = strings.ReplaceAll(s, `"`, "'")
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
@@ -0,0 +1,82 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPlantUML(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop", Description: "Takes orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: `Stores "orders"`},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Proto: "http2"},
+				{Action: RelationshipActionUses, Name: "Kafka", Technology: "kafka", Async: true},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info: Info{Name: "billing", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka", Description: "Publishes invoices"},
+			},
+		},
+		{
+			Info: Info{Name: "mailer"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReceives, Name: "Kafka", Technology: "kafka"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderPlantUML(files, &buf))
+
+	assert.Equal(t, `@startuml
+!include https://raw.githubusercontent.com/plantuml-stdlib/C4-PlantUML/master/C4_Container.puml
+
+AddRelTag("async", $lineStyle = DashedLine())
+
+System(mailer, "mailer", "")
+System_Boundary(system_shop, "shop") {
+  Container(billing, "billing", "", "")
+  Container(orders, "orders", "", "Takes orders")
+}
+System_Ext(kafka, "Kafka")
+System_Ext(postgresql, "PostgreSQL")
+Rel(billing, kafka, "sends", "kafka", "Publishes invoices", $tags="async")
+Rel(mailer, kafka, "receives", "kafka", "", $tags="async")
+Rel(orders, billing, "requests (http2)", "grpc", "")
+Rel(orders, kafka, "uses", "kafka", "", $tags="async")
+Rel(orders, postgresql, "uses", "postgresql", "Stores 'orders'")
+@enduml
+`, buf.String())
+}
+
+func TestRenderPlantUMLWithLegend(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "Order Service"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "redis-cache"}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderPlantUML(files, &buf, WithLegend()))
+
+	assert.Equal(t, `@startuml
+!include https://raw.githubusercontent.com/plantuml-stdlib/C4-PlantUML/master/C4_Container.puml
+
+System(order_service, "Order Service", "")
+System_Ext(redis_cache, "redis-cache")
+Rel(order_service, redis_cache, "uses", "", "")
+
+SHOW_LEGEND()
+@enduml
+`, buf.String())
+}
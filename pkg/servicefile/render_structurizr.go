@@ -0,0 +1,112 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// structurizrDeprecatedTag is the tag of deprecated relationships.
+const structurizrDeprecatedTag = "Deprecated"
+
+// RenderStructurizr writes the service files as a Structurizr DSL workspace.
+// Services of a system are containers of a software system named after it, services without system
+// are software systems, and relationship targets that are not services are software systems tagged External.
+// Every relationship with a target is a relationship described by its action, followed by its description,
+// and carrying its technology, deprecated relationships being tagged Deprecated.
+// Elements are identified by the IDs of NewIDMap, kept stable across renames with WithIDMap.
+func RenderStructurizr(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	ids := o.nodeIDs(files)
+	if ids == nil {
+		ids = NewIDMap(files, nil)
+	}
+	files = sortedServiceFiles(o.Apply(files))
+
+	id := func(name string) string {
+		return structurizrID(ids[name])
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "workspace {")
+	fmt.Fprintln(bw, "    model {")
+
+	systems, groups := systemGroups(files)
+	for _, system := range systems {
+		if system == "" {
+			for _, sf := range groups[system] {
+				fmt.Fprintf(bw, "        %s = softwareSystem %s %s\n", id(sf.Info.Name), structurizrString(sf.Info.DisplayName()), structurizrString(sf.Info.Description))
+			}
+			continue
+		}
+
+		fmt.Fprintf(bw, "        %s = softwareSystem %s {\n", structurizrID("system-"+slug(system)), structurizrString(system))
+		for _, sf := range groups[system] {
+			fmt.Fprintf(bw, "            %s = container %s %s\n", id(sf.Info.Name), structurizrString(sf.Info.DisplayName()), structurizrString(sf.Info.Description))
+		}
+		fmt.Fprintln(bw, "        }")
+	}
+
+	for _, name := range externalTargets(files) {
+		fmt.Fprintf(bw, "        %s = softwareSystem %s {\n", id(name), structurizrString(name))
+		fmt.Fprintln(bw, `            tags "External"`)
+		fmt.Fprintln(bw, "        }")
+	}
+
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+
+			var tags string
+			if rel.Deprecated {
+				tags = " " + structurizrString(structurizrDeprecatedTag)
+			}
+
+			fmt.Fprintf(bw, "        %s -> %s %s %s%s\n",
+				id(sf.Info.Name), id(rel.Name), structurizrString(structurizrDescription(rel)), structurizrString(relationshipTechnology(rel)), tags)
+		}
+	}
+
+	fmt.Fprintln(bw, "    }")
+	fmt.Fprintln(bw, "}")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write structurizr: %w", err)
+	}
+
+	return nil
+}
+
+// structurizrDescription returns the description of a relationship: its action followed by its description.
+func structurizrDescription(rel Relationship) string {
+	if rel.Description == "" {
+		return string(rel.Action)
+	}
+
+	return string(rel.Action) + ": " + rel.Description
+}
+
+// structurizrID returns id made of the characters the DSL accepts in identifiers.
+func structurizrID(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+}
+
+// structurizrString returns s as a quoted DSL token. Double quotes are replaced by single quotes and
+// line breaks by spaces, since tokens cannot span lines.
+func structurizrString(s string) string {
+	s = strings.ReplaceAll(s, `"`, "'")
+	s = strings.ReplaceAll(s, "\n", " ")
+
+	return `"` + s + `"`
+}
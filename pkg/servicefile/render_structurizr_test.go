@@ -0,0 +1,114 @@
+package servicefile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderStructurizr(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop", Description: "Takes orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Description: `Stores "orders"`},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionReplies, Description: "Provides order APIs"},
+			},
+		},
+		{
+			Info: Info{Name: "billing", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionSends, Name: "Kafka", Technology: "kafka", Description: "Publishes invoices"},
+			},
+		},
+		{
+			Info: Info{Name: "mailer", Description: "Sends e-mails"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReceives, Name: "Kafka", Technology: "kafka"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderStructurizr(files, &buf))
+
+	golden := filepath.Join("testdata", "workspace.golden.dsl")
+	if *update {
+		require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+	}
+
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), buf.String())
+
+	checkStructurizrDSL(t, buf.String())
+}
+
+var (
+	structurizrElement      = regexp.MustCompile(`^(\w+) = (softwareSystem|container) "[^"]*"( "[^"]*")?( \{)?$`)
+	structurizrRelationship = regexp.MustCompile(`^(\w+) -> (\w+) "[^"]*" "[^"]*"$`)
+)
+
+// checkStructurizrDSL checks the subset of the Structurizr DSL grammar emitted by RenderStructurizr:
+// blocks are balanced, elements are assigned unique identifiers, containers are nested in
+// software systems only and relationships refer to declared elements.
+func checkStructurizrDSL(t *testing.T, dsl string) {
+	t.Helper()
+
+	var (
+		blocks   []string
+		declared = make(map[string]struct{})
+	)
+
+	for i, line := range strings.Split(strings.TrimSuffix(dsl, "\n"), "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "workspace {" && len(blocks) == 0:
+			blocks = append(blocks, "workspace")
+		case line == "model {" && len(blocks) == 1 && blocks[0] == "workspace":
+			blocks = append(blocks, "model")
+		case line == `tags "External"` && len(blocks) > 0 && blocks[len(blocks)-1] == "softwareSystem":
+		case line == "}":
+			require.NotEmpty(t, blocks, "line %d closes no block", i+1)
+			blocks = blocks[:len(blocks)-1]
+		case structurizrElement.MatchString(line):
+			m := structurizrElement.FindStringSubmatch(line)
+
+			parent := "model"
+			if m[2] == "container" {
+				parent = "softwareSystem"
+			}
+			require.Equal(t, parent, blocks[len(blocks)-1], "line %d: %s outside of a %s", i+1, m[2], parent)
+
+			_, exists := declared[m[1]]
+			require.False(t, exists, "line %d: identifier %s declared twice", i+1, m[1])
+			declared[m[1]] = struct{}{}
+
+			if m[4] != "" {
+				blocks = append(blocks, m[2])
+			}
+		case structurizrRelationship.MatchString(line):
+			m := structurizrRelationship.FindStringSubmatch(line)
+			require.Equal(t, "model", blocks[len(blocks)-1], "line %d: relationship outside of the model", i+1)
+
+			for _, identifier := range m[1:3] {
+				_, exists := declared[identifier]
+				require.True(t, exists, "line %d: undeclared identifier %s", i+1, identifier)
+			}
+		default:
+			require.Fail(t, "unexpected line", "line %d: %q", i+1, line)
+		}
+	}
+
+	require.Empty(t, blocks, "unclosed blocks")
+}
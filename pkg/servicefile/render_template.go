@@ -0,0 +1,58 @@
+package servicefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"text/template"
+)
+
+// RenderTemplate writes the service files through a text/template, for formats without a dedicated renderer.
+//
+// The template is executed with the service files to render, a []*ServiceFile sorted by service name
+// with sorted relationships, so that templates range over them and read their fields, such as
+// .Info.Name, .Info.DisplayName and .Relationships. The template can call these functions:
+//
+//   - externals returns the sorted names of the relationship targets that are not services.
+//   - bySystem returns the services grouped by system, as a map[string][]*ServiceFile ranged over in
+//     system order, services without system being under the empty system.
+//   - sortedRelationships returns the relationships of a service file in the order of ServiceFile.Sort.
+//   - label returns the label diagrams give a relationship: its action followed by its technology and port.
+//   - technologies returns the technologies of a relationship joined with slashes, such as grpc/http2.
+func RenderTemplate(files []*ServiceFile, tmpl string, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+	files = sortedServiceFiles(o.Apply(files))
+
+	t, err := template.New("servicefile").Funcs(template.FuncMap{
+		"externals": func() []string {
+			return externalTargets(files)
+		},
+		"bySystem": func() map[string][]*ServiceFile {
+			_, groups := systemGroups(files)
+			return groups
+		},
+		"sortedRelationships": func(sf *ServiceFile) []Relationship {
+			relationships := slices.Clone(sf.Relationships)
+			slices.SortStableFunc(relationships, compareRelationships)
+			return relationships
+		},
+		"label":        relationshipLabel,
+		"technologies": relationshipTechnology,
+	}).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if err := t.Execute(bw, files); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write template output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Port: 5432},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc", Technologies: []string{"grpc", "http2"}},
+			},
+		},
+		{
+			Info: Info{Name: "billing", Alias: "Billing", System: "shop"},
+		},
+		{
+			Info:          Info{Name: "search"},
+			Relationships: []Relationship{{Action: RelationshipActionUses, Name: "Elasticsearch"}},
+		},
+	}
+
+	tmpl := `{{range $system, $services := bySystem}}h1. {{or $system "Other"}}
+{{range $services}}h2. {{.Info.DisplayName}}
+{{range sortedRelationships .}}* {{label .}} -> {{.Name}}{{with technologies .}} [{{.}}]{{end}}
+{{end}}{{end}}{{end}}Externals:{{range externals}} {{.}}{{end}}
+`
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderTemplate(files, tmpl, &buf))
+
+	assert.Equal(t, `h1. Other
+h2. search
+* uses -> Elasticsearch
+h1. shop
+h2. Billing
+h2. orders
+* requests (grpc/http2) -> billing [grpc/http2]
+* uses (postgresql, port 5432) -> PostgreSQL [postgresql]
+Externals: Elasticsearch PostgreSQL
+`, buf.String())
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := RenderTemplate(nil, "{{range}}", &buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse template")
+
+	err = RenderTemplate([]*ServiceFile{{Info: Info{Name: "orders"}}}, "{{index . 3}}", &buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to execute template")
+}
@@ -0,0 +1,213 @@
+package servicefile
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFocusSubgraph(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "api"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+				{Action: RelationshipActionReplies},
+			},
+		},
+		{
+			Info: Info{Name: "auth"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "Redis"},
+			},
+		},
+		{
+			Info: Info{Name: "gateway"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "api"},
+			},
+		},
+		{
+			Info: Info{Name: "billing"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		focus    string
+		depth    int
+		expected []*ServiceFile
+	}{
+		{
+			name:  "depth 0",
+			focus: "api",
+			depth: 0,
+			expected: []*ServiceFile{
+				{
+					Info:          Info{Name: "api"},
+					Relationships: []Relationship{{Action: RelationshipActionReplies}},
+				},
+			},
+		},
+		{
+			name:  "depth 1",
+			focus: "api",
+			depth: 1,
+			expected: []*ServiceFile{
+				{
+					Info: Info{Name: "api"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionRequests, Name: "auth"},
+						{Action: RelationshipActionUses, Name: "PostgreSQL"},
+						{Action: RelationshipActionReplies},
+					},
+				},
+				{
+					Info:          Info{Name: "auth"},
+					Relationships: []Relationship{},
+				},
+				{
+					Info: Info{Name: "gateway"},
+					Relationships: []Relationship{
+						{Action: RelationshipActionRequests, Name: "api"},
+					},
+				},
+			},
+		},
+		{
+			name:  "depth 2",
+			focus: "api",
+			depth: 2,
+			expected: []*ServiceFile{
+				files[0],
+				files[1],
+				files[2],
+				files[3],
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := FocusSubgraph(files, tt.focus, tt.depth)
+
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestFocusSubgraphWithAlias(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info:          Info{Name: "gateway"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "payments"}},
+		},
+		{
+			Info:          Info{Name: "billing", Alias: "payments"},
+			Relationships: []Relationship{{Action: RelationshipActionUses, Name: "PostgreSQL"}},
+		},
+		{Info: Info{Name: "search"}},
+	}
+
+	expected := []*ServiceFile{
+		{
+			Info:          Info{Name: "gateway"},
+			Relationships: []Relationship{{Action: RelationshipActionRequests, Name: "billing"}},
+		},
+		{
+			Info:          Info{Name: "billing", Alias: "payments"},
+			Relationships: []Relationship{{Action: RelationshipActionUses, Name: "PostgreSQL"}},
+		},
+	}
+
+	assert.Equal(t, expected, FocusSubgraph(files, "billing", 1))
+	assert.Equal(t, expected, FocusSubgraph(files, "payments", 1), "the focus can be given by its alias")
+}
+
+func TestRenderOptionsApply(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{Info: Info{Name: "api"}, Relationships: []Relationship{{Action: RelationshipActionUses, Name: "auth"}}},
+		{Info: Info{Name: "auth"}, Relationships: []Relationship{}},
+		{Info: Info{Name: "billing"}, Relationships: []Relationship{}},
+	}
+
+	var opts RenderOptions
+	assert.Equal(t, files, opts.Apply(files))
+
+	WithFocus("auth", 1)(&opts)
+	assert.Equal(t, files[:2], opts.Apply(files))
+}
+
+func TestFilterByTags(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "api", Tags: []string{"edge", "public"}},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+				{Action: RelationshipActionRequests, Name: "billing"},
+				{Action: RelationshipActionUses, Name: "Redis"},
+			},
+		},
+		{Info: Info{Name: "auth", Tags: []string{"security"}}, Relationships: []Relationship{}},
+		{Info: Info{Name: "billing"}, Relationships: []Relationship{}},
+	}
+
+	expected := []*ServiceFile{
+		{
+			Info: Info{Name: "api", Tags: []string{"edge", "public"}},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "auth"},
+				{Action: RelationshipActionUses, Name: "Redis"},
+			},
+		},
+		{Info: Info{Name: "auth", Tags: []string{"security"}}, Relationships: []Relationship{}},
+	}
+
+	assert.Equal(t, expected, FilterByTags(files, "public", "security"))
+	assert.Len(t, files[0].Relationships, 3, "files must be left untouched")
+
+	var opts RenderOptions
+	WithTags("edge")(&opts)
+	WithFocus("api", 1)(&opts)
+	assert.Equal(t, []*ServiceFile{{
+		Info:          Info{Name: "api", Tags: []string{"edge", "public"}},
+		Relationships: []Relationship{{Action: RelationshipActionUses, Name: "Redis"}},
+	}}, opts.Apply(files))
+}
+
+func TestFilterByEnvironment(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/environments")
+	require.NoError(t, err)
+
+	prod := FilterByEnvironment(files, "prod")
+	require.Len(t, prod, 2)
+	assert.Equal(t, []Relationship{{Action: RelationshipActionRequests, Name: "orders", Technology: "grpc"}}, prod[0].Relationships)
+	assert.Len(t, prod[1].Relationships, 1)
+	assert.Len(t, files[0].Relationships, 2, "files must be left untouched")
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderMermaid(files, &buf, WithEnvironment("prod")))
+	assert.NotContains(t, buf.String(), "Profiler")
+
+	buf.Reset()
+	require.NoError(t, RenderMermaid(files, &buf, WithEnvironment("dev")))
+	assert.Contains(t, buf.String(), "Profiler")
+}
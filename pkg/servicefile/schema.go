@@ -0,0 +1,86 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// schemaDialect is the JSON Schema dialect of Schema.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaRequired lists the required properties of each object of the format, by Go type.
+// Every other property is optional.
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeOf(ServiceFile{}):  {"servicefile", "info"},
+	reflect.TypeOf(Info{}):         {"name"},
+	reflect.TypeOf(Relationship{}): {"action"},
+}
+
+// Schema returns a JSON Schema describing service files, for editors to validate YAML and JSON
+// service files written by hand. It is generated from the ServiceFile type: properties are the
+// fields of the types, the version is the Version constant and actions are the RelationshipActions.
+func Schema() ([]byte, error) {
+	schema := schemaOf(reflect.TypeOf(ServiceFile{}))
+	schema["$schema"] = schemaDialect
+	schema["title"] = "ServiceFile"
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// schemaOf returns the schema of values of type t.
+func schemaOf(t reflect.Type) map[string]any {
+	switch t {
+	case reflect.TypeOf(RelationshipAction("")):
+		return map[string]any{"type": "string", "enum": RelationshipActions()}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = schemaOf(field.Type)
+		}
+
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if required := schemaRequired[t]; len(required) > 0 {
+			schema["required"] = required
+		}
+
+		switch t {
+		case reflect.TypeOf(ServiceFile{}):
+			properties["servicefile"] = map[string]any{"type": "string", "const": Version}
+		case reflect.TypeOf(Relationship{}):
+			properties["port"] = map[string]any{"type": "integer", "minimum": 1, "maximum": 65535}
+		}
+
+		return schema
+	default:
+		panic(fmt.Sprintf("servicefile: no schema for type %s", t))
+	}
+}
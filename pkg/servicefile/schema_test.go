@@ -0,0 +1,84 @@
+package servicefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	data, err := Schema()
+	require.NoError(t, err)
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	compiler := jsonschema.NewCompiler()
+	require.NoError(t, compiler.AddResource("servicefile.schema.json", doc))
+
+	schema, err := compiler.Compile("servicefile.schema.json")
+	require.NoError(t, err)
+
+	validate := func(content string) error {
+		t.Helper()
+
+		var value any
+		require.NoError(t, yaml.Unmarshal([]byte(content), &value))
+
+		encoded, err := json.Marshal(value)
+		require.NoError(t, err)
+
+		instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+		require.NoError(t, err)
+
+		return schema.Validate(instance)
+	}
+
+	valid := `servicefile: 0.1.0
+info:
+  name: orders
+  description: Takes orders
+  tags: [shop]
+  annotations:
+    lifecycle: production
+relationships:
+  - action: uses
+    name: PostgreSQL
+    technology: postgresql
+    technologies: [postgresql, pgbouncer]
+    port: 5432
+    async: false
+    environments: [prod]
+    timeout_ms: 500
+  - action: replies
+`
+	assert.NoError(t, validate(valid))
+
+	marshaled, err := MarshalYAML(&ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "billing", Owner: "payments"},
+		Relationships: []Relationship{
+			{Action: RelationshipActionSends, Name: "Kafka", Async: true, External: true, SLA: "p99<200ms"},
+		},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, validate(string(marshaled)), "marshaled service files must be valid")
+
+	invalid := map[string]string{
+		"unsupported version": "servicefile: 9.9.9\ninfo:\n  name: orders\n",
+		"missing name":        "servicefile: 0.1.0\ninfo:\n  description: Takes orders\n",
+		"unknown action":      "servicefile: 0.1.0\ninfo:\n  name: orders\nrelationships:\n  - action: fetches\n    name: billing\n",
+		"invalid port":        "servicefile: 0.1.0\ninfo:\n  name: orders\nrelationships:\n  - action: uses\n    port: 70000\n",
+		"unknown field":       "servicefile: 0.1.0\ninfo:\n  name: orders\n  technolgy: go\n",
+	}
+	for name, content := range invalid {
+		assert.Error(t, validate(content), name)
+	}
+}
@@ -1,86 +1,433 @@
 package servicefile
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"maps"
+	"slices"
 	"sort"
-
-	"gopkg.in/yaml.v3"
+	"strings"
 )
 
 const Version string = "0.1.0"
 
 // ServiceFile represents a service file.
 type ServiceFile struct {
-	Version       string         `yaml:"servicefile"`
-	Info          Info           `yaml:"info"`
-	Relationships []Relationship `yaml:"relationships"`
+	Version       string         `yaml:"servicefile" json:"servicefile"`
+	Info          Info           `yaml:"info" json:"info"`
+	Relationships []Relationship `yaml:"relationships" json:"relationships"`
 }
 
 // Info represents a info about service.
+// Alias is a short name diagrams label the service with, relationships referring to the service
+// either by its name or by its alias.
 type Info struct {
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	System      string `yaml:"system,omitempty"`
+	Name        string            `yaml:"name" json:"name"`
+	Alias       string            `yaml:"alias,omitempty" json:"alias,omitempty"`
+	Description string            `yaml:"description" json:"description,omitempty"`
+	System      string            `yaml:"system,omitempty" json:"system,omitempty"`
+	Owner       string            `yaml:"owner,omitempty" json:"owner,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	// Source locates the annotation the service was parsed from, zero when the service wasn't parsed
+	// from code. It is not serialized.
+	Source Source `yaml:"-" json:"-"`
+}
+
+// Source is the location of the code a service or relationship was parsed from,
+// for diagnostics and editor integration.
+type Source struct {
+	File string
+	Line int
+}
+
+// IsZero reports whether the location is unknown.
+func (s Source) IsZero() bool {
+	return s.File == ""
+}
+
+// String returns the location as file:line.
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// DisplayName returns the name the service is displayed with: its alias, or its name when it has none.
+func (i Info) DisplayName() string {
+	if i.Alias != "" {
+		return i.Alias
+	}
+
+	return i.Name
+}
+
+// HasTag reports whether the service is labeled with the tag.
+func (i Info) HasTag(tag string) bool {
+	return slices.Contains(i.Tags, tag)
 }
 
 // Relationship represents a relationship between current service and external components.
+// SLA is the free-form service level expected from the relationship, such as p99<200ms,
+// and TimeoutMS its timeout in milliseconds, zero when unset. Port is the port the target
+// is reached on, zero when unset. Async is set on asynchronous relationships, such as messages
+// published to a queue, relationships being synchronous by default. Environments lists the
+// environments the relationship exists in, such as dev, the relationship existing in all of them when empty.
 type Relationship struct {
-	Action      RelationshipAction `yaml:"action"`
-	Name        string             `yaml:"name,omitempty"`
-	Description string             `yaml:"description,omitempty"`
-	Technology  string             `yaml:"technology"`
-	Proto       string             `yaml:"proto,omitempty"`
+	Action      RelationshipAction `yaml:"action" json:"action"`
+	Name        string             `yaml:"name,omitempty" json:"name"`
+	Description string             `yaml:"description,omitempty" json:"description,omitempty"`
+	Technology  string             `yaml:"technology,omitempty" json:"technology,omitempty"`
+	// Technologies lists every technology of relationships involving several of them, such as grpc
+	// over http2 with protobuf. Technology is then the first of them, for compatibility.
+	Technologies []string `yaml:"technologies,omitempty" json:"technologies,omitempty"`
+	Proto        string   `yaml:"proto,omitempty" json:"proto,omitempty"`
+	Port         int      `yaml:"port,omitempty" json:"port,omitempty"`
+	Async        bool     `yaml:"async,omitempty" json:"async,omitempty"`
+	Environments []string `yaml:"environments,omitempty" json:"environments,omitempty"`
+	// Deprecated is set on relationships being sunset, such as dependencies kept during a migration
+	// while traffic is routed to their replacement. Renderers draw them with a distinct style.
+	Deprecated bool `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	// External is set on relationships whose target is not one of the services, such as a datastore
+	// or a third-party API, when the relationships were classified, see MarkExternal.
+	External bool `yaml:"external,omitempty" json:"external,omitempty"`
+	// Discovered is set on relationships a parser inferred from code, such as calls to client constructors,
+	// rather than read from annotations. They are less certain than declared relationships.
+	Discovered  bool              `yaml:"discovered,omitempty" json:"discovered,omitempty"`
+	SLA         string            `yaml:"sla,omitempty" json:"sla,omitempty"`
+	TimeoutMS   int               `yaml:"timeout_ms,omitempty" json:"timeout_ms,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	// Source locates the annotation or the code the relationship was parsed from, zero when
+	// the relationship wasn't parsed from code. It is not serialized.
+	Source Source `yaml:"-" json:"-"`
+}
+
+// Equal reports whether both relationships have the same fields.
+// Nil and empty annotations are considered equal.
+func (r Relationship) Equal(other Relationship) bool {
+	return r.Action == other.Action &&
+		r.Name == other.Name &&
+		r.Description == other.Description &&
+		r.Technology == other.Technology &&
+		slices.Equal(r.Technologies, other.Technologies) &&
+		r.Proto == other.Proto &&
+		r.Port == other.Port &&
+		r.Async == other.Async &&
+		slices.Equal(r.Environments, other.Environments) &&
+		r.Deprecated == other.Deprecated &&
+		r.External == other.External &&
+		r.Discovered == other.Discovered &&
+		r.SLA == other.SLA &&
+		r.TimeoutMS == other.TimeoutMS &&
+		maps.Equal(r.Annotations, other.Annotations)
+}
+
+// InEnvironment reports whether the relationship exists in the environment: when it lists the environment,
+// or lists none. Every relationship exists in the empty environment.
+func (r Relationship) InEnvironment(env string) bool {
+	return env == "" || len(r.Environments) == 0 || slices.Contains(r.Environments, env)
 }
 
 // RelationshipAction represents an action between services.
+// The actions of the specification are the RelationshipAction constants,
+// other actions are allowed but not valid.
 type RelationshipAction string
 
 const (
-	RelationshipActionUses     = "uses"
-	RelationshipActionRequests = "requests"
-	RelationshipActionReplies  = "replies"
-	RelationshipActionSends    = "sends"
-	RelationshipActionReceives = "receives"
+	RelationshipActionUses     RelationshipAction = "uses"
+	RelationshipActionRequests RelationshipAction = "requests"
+	RelationshipActionReplies  RelationshipAction = "replies"
+	RelationshipActionSends    RelationshipAction = "sends"
+	RelationshipActionReceives RelationshipAction = "receives"
 )
 
-// Sort sorts the relationships in the service file.
+// RelationshipActions returns the actions of the specification.
+func RelationshipActions() []RelationshipAction {
+	return []RelationshipAction{
+		RelationshipActionUses,
+		RelationshipActionRequests,
+		RelationshipActionReplies,
+		RelationshipActionSends,
+		RelationshipActionReceives,
+	}
+}
+
+// AllTechnologies returns the technologies of the relationship: Technologies when set,
+// Technology otherwise, nil when the relationship has no technology.
+func (r Relationship) AllTechnologies() []string {
+	if len(r.Technologies) > 0 {
+		return r.Technologies
+	}
+
+	if r.Technology == "" {
+		return nil
+	}
+
+	return []string{r.Technology}
+}
+
+// IsValid reports whether the action is one of the actions of the specification.
+func (a RelationshipAction) IsValid() bool {
+	return slices.Contains(RelationshipActions(), a)
+}
+
+// CanonicalProtos returns the protos relationships are normalized to, see NormalizeProto.
+func CanonicalProtos() []string {
+	return []string{"tcp", "udp", "http", "https", "grpc", "amqp", "kafka"}
+}
+
+// protoAliases maps lower-cased alternative spellings of protos to their canonical proto.
+var protoAliases = map[string]string{
+	"tcp/ip":     "tcp",
+	"udp/ip":     "udp",
+	"http/1.0":   "http",
+	"http/1.1":   "http",
+	"g-rpc":      "grpc",
+	"amqp091":    "amqp",
+	"amqp-0-9-1": "amqp",
+	"amqp 0-9-1": "amqp",
+}
+
+// NormalizeProto returns the canonical spelling of proto: trimmed and lower-cased, aliases such as
+// HTTP/1.1 being replaced by their canonical proto. It reports whether the result is one of the
+// CanonicalProtos. Protos that aren't pass through lower-cased. An empty proto stays empty.
+func NormalizeProto(proto string) (string, bool) {
+	proto = strings.ToLower(strings.TrimSpace(proto))
+	if canonical, ok := protoAliases[proto]; ok {
+		proto = canonical
+	}
+
+	return proto, slices.Contains(CanonicalProtos(), proto)
+}
+
+// NormalizeProto replaces the proto of the relationship by its canonical spelling, see NormalizeProto.
+// It reports whether the proto is one of the CanonicalProtos.
+func (r *Relationship) NormalizeProto() bool {
+	proto, ok := NormalizeProto(r.Proto)
+	r.Proto = proto
+
+	return ok
+}
+
+// Sort sorts the relationships and the tags of the service file.
+// Relationships are sorted by action, then name, technology, technologies, proto and description,
+// remaining ties being broken by every other field, see compareRelationships, so that the order of
+// the relationships, and thus the encoding of the service file, doesn't depend on their initial order.
 func (sf *ServiceFile) Sort() {
-	sort.Slice(sf.Relationships, func(i, j int) bool {
-		rel1 := sf.Relationships[i]
-		rel2 := sf.Relationships[j]
+	sort.SliceStable(sf.Relationships, func(i, j int) bool {
+		return compareRelationships(sf.Relationships[i], sf.Relationships[j]) < 0
+	})
 
-		if rel1.Action != rel2.Action {
-			return string(rel1.Action) < string(rel2.Action)
-		}
+	sort.Strings(sf.Info.Tags)
+}
+
+// compareRelationships orders relationships by action, name, technology, technologies, proto
+// and description, then by the other fields in the order they are declared in, annotations being
+// compared as their sorted key and value pairs. It only returns 0 for equal relationships.
+func compareRelationships(a, b Relationship) int {
+	return cmp.Or(
+		cmp.Compare(a.Action, b.Action),
+		cmp.Compare(a.Name, b.Name),
+		cmp.Compare(a.Technology, b.Technology),
+		slices.Compare(a.Technologies, b.Technologies),
+		cmp.Compare(a.Proto, b.Proto),
+		cmp.Compare(a.Description, b.Description),
+		cmp.Compare(a.Port, b.Port),
+		compareBools(a.Async, b.Async),
+		slices.Compare(a.Environments, b.Environments),
+		compareBools(a.Deprecated, b.Deprecated),
+		compareBools(a.External, b.External),
+		compareBools(a.Discovered, b.Discovered),
+		cmp.Compare(a.SLA, b.SLA),
+		cmp.Compare(a.TimeoutMS, b.TimeoutMS),
+		compareAnnotations(a.Annotations, b.Annotations),
+	)
+}
+
+// compareBools orders false before true.
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
 
-		if rel1.Name != rel2.Name {
-			return rel1.Name < rel2.Name
+// compareAnnotations compares annotations as their key and value pairs sorted by key.
+func compareAnnotations(a, b map[string]string) int {
+	keysA := slices.Sorted(maps.Keys(a))
+	keysB := slices.Sorted(maps.Keys(b))
+
+	for i := range min(len(keysA), len(keysB)) {
+		if c := cmp.Or(cmp.Compare(keysA[i], keysB[i]), cmp.Compare(a[keysA[i]], b[keysB[i]])); c != 0 {
+			return c
 		}
+	}
+
+	return cmp.Compare(len(keysA), len(keysB))
+}
+
+// Deduplicate sorts the relationships and removes the ones identical to another relationship.
+// It returns the number of removed relationships.
+func (sf *ServiceFile) Deduplicate() int {
+	sf.Sort()
+
+	if len(sf.Relationships) == 0 {
+		return 0
+	}
 
-		if rel1.Technology != rel2.Technology {
-			return rel1.Technology < rel2.Technology
+	unique := sf.Relationships[:1]
+	for _, rel := range sf.Relationships[1:] {
+		if rel.Equal(unique[len(unique)-1]) {
+			continue
 		}
+		unique = append(unique, rel)
+	}
+
+	removed := len(sf.Relationships) - len(unique)
+	sf.Relationships = unique
 
-		if rel1.Proto != rel2.Proto {
-			return rel1.Proto < rel2.Proto
+	return removed
+}
+
+// Equal reports whether both service files have the same content: the order of their relationships
+// and tags does not matter, and nil and empty relationships, tags and annotations are considered equal.
+func (sf *ServiceFile) Equal(other *ServiceFile) bool {
+	if sf == nil || other == nil {
+		return sf == other
+	}
+
+	a, b := sf.Clone(), other.Clone()
+	a.Sort()
+	b.Sort()
+
+	return a.Version == b.Version &&
+		a.Info.Equal(b.Info) &&
+		slices.EqualFunc(a.Relationships, b.Relationships, Relationship.Equal)
+}
+
+// Equal reports whether both infos have the same fields.
+// Nil and empty tags and annotations are considered equal.
+func (i Info) Equal(other Info) bool {
+	return i.Name == other.Name &&
+		i.Alias == other.Alias &&
+		i.Description == other.Description &&
+		i.System == other.System &&
+		i.Owner == other.Owner &&
+		slices.Equal(i.Tags, other.Tags) &&
+		maps.Equal(i.Annotations, other.Annotations)
+}
+
+// Clone returns a deep copy of the service file, sharing no slice or map with it,
+// so that the copy can be modified without affecting the original.
+func (sf *ServiceFile) Clone() *ServiceFile {
+	c := *sf
+	c.Info = sf.Info.Clone()
+
+	if sf.Relationships != nil {
+		c.Relationships = make([]Relationship, len(sf.Relationships))
+		for i, rel := range sf.Relationships {
+			c.Relationships[i] = rel.Clone()
 		}
+	}
 
-		return rel1.Description < rel2.Description
-	})
+	return &c
 }
 
-// Load reads and parses a ServiceFile from a YAML file at the given path.
-func Load(path string) (*ServiceFile, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+// Clone returns a deep copy of the info.
+func (i Info) Clone() Info {
+	i.Tags = slices.Clone(i.Tags)
+	i.Annotations = maps.Clone(i.Annotations)
+
+	return i
+}
+
+// Clone returns a deep copy of the relationship.
+func (r Relationship) Clone() Relationship {
+	r.Technologies = slices.Clone(r.Technologies)
+	r.Environments = slices.Clone(r.Environments)
+	r.Annotations = maps.Clone(r.Annotations)
+
+	return r
+}
+
+// Hash returns a fingerprint of the service file content.
+// Relationships are hashed in sorted order, so the order they are listed in does not matter,
+// and sources are left out, so that moving annotations within the code doesn't change the hash.
+// Nil and empty slices and maps hash the same, as Equal considers them equal.
+func (sf *ServiceFile) Hash() string {
+	sorted := *sf
+	sorted.Info.Source = Source{}
+	sorted.Info.Tags = nilIfEmpty(slices.Clone(sf.Info.Tags))
+	sorted.Info.Annotations = nilIfEmptyMap(sf.Info.Annotations)
+	sorted.Relationships = make([]Relationship, len(sf.Relationships))
+	for i, rel := range sf.Relationships {
+		rel.Source = Source{}
+		rel.Technologies = nilIfEmpty(rel.Technologies)
+		rel.Environments = nilIfEmpty(rel.Environments)
+		rel.Annotations = nilIfEmptyMap(rel.Annotations)
+		sorted.Relationships[i] = rel
 	}
+	sorted.Sort()
 
-	var sf ServiceFile
-	if err := yaml.Unmarshal(data, &sf); err != nil {
-		return nil, fmt.Errorf("failed to parse file %s: %w", path, err)
+	h := sha256.New()
+	fmt.Fprintf(h, "%#v", sorted)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// nilIfEmpty returns s, nil when it is empty.
+func nilIfEmpty[S ~[]E, E any](s S) S {
+	if len(s) == 0 {
+		return nil
 	}
 
-	return &sf, nil
+	return s
+}
+
+// nilIfEmptyMap returns m, nil when it is empty.
+func nilIfEmptyMap[M ~map[K]V, K comparable, V any](m M) M {
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+// String returns a human-readable dump of the service file, for debugging: a line describing
+// the service followed by an indented line per relationship, in sorted order.
+func (sf *ServiceFile) String() string {
+	sorted := sf.Clone()
+	sorted.Sort()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: %s, description: %s, system: %s, owner: %s",
+		sorted.Info.Name,
+		sorted.Info.Description,
+		sorted.Info.System,
+		sorted.Info.Owner,
+	)
+
+	for _, rel := range sorted.Relationships {
+		fmt.Fprintf(&b, "\n  action: %s, name: %s, technology: %s, proto: %s, port: %d, description: %s",
+			rel.Action,
+			rel.Name,
+			rel.Technology,
+			rel.Proto,
+			rel.Port,
+			rel.Description,
+		)
+	}
+
+	return b.String()
+}
+
+// Load reads and parses a ServiceFile from a YAML file at the given path.
+// References to environment variables in string values, written as ${VAR} or
+// ${VAR:-default}, are expanded from the process environment.
+func Load(path string) (*ServiceFile, error) {
+	return LoadWithEnv(path, nil)
 }
@@ -1,8 +1,11 @@
 package servicefile
 
 import (
+	"encoding/json"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -206,6 +209,42 @@ relationships:
 			},
 			wantErr: false,
 		},
+		{
+			name: "servicefile with annotations",
+			yamlContent: `
+servicefile: 0.1.0
+info:
+    name: "billing"
+    annotations:
+        owner: "payments-team"
+        cost-center: "4200"
+relationships:
+  - action: "uses"
+    name: "database"
+    technology: "postgresql"
+    annotations:
+        slo: "99.9%"
+`,
+			want: &ServiceFile{
+				Version: "0.1.0",
+				Info: Info{
+					Name: "billing",
+					Annotations: map[string]string{
+						"owner":       "payments-team",
+						"cost-center": "4200",
+					},
+				},
+				Relationships: []Relationship{
+					{
+						Action:      "uses",
+						Name:        "database",
+						Technology:  "postgresql",
+						Annotations: map[string]string{"slo": "99.9%"},
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:        "invalid yaml",
 			yamlContent: `name: "test" invalid: yaml: content`,
@@ -312,6 +351,25 @@ func TestSort(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "tags",
+			input: &ServiceFile{
+				Version: Version,
+				Info: Info{
+					Name: "tagged-service",
+					Tags: []string{"payments", "critical"},
+				},
+				Relationships: []Relationship{},
+			},
+			expected: &ServiceFile{
+				Version: Version,
+				Info: Info{
+					Name: "tagged-service",
+					Tags: []string{"critical", "payments"},
+				},
+				Relationships: []Relationship{},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -329,3 +387,355 @@ func TestSort(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceFileString(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "Billing", Description: "Charges customers", System: "payments", Owner: "payments-team"},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp", Port: 5432, Description: "Stores invoices"},
+			{Action: RelationshipActionReplies, Description: "Provides billing APIs"},
+		},
+	}
+
+	expected := `name: Billing, description: Charges customers, system: payments, owner: payments-team
+  action: replies, name: , technology: , proto: , port: 0, description: Provides billing APIs
+  action: uses, name: PostgreSQL, technology: postgresql, proto: tcp, port: 5432, description: Stores invoices`
+	assert.Equal(t, expected, sf.String())
+	assert.Equal(t, RelationshipActionUses, sf.Relationships[0].Action, "String must not sort the service file")
+}
+
+func TestSortIsIndependentOfInputOrder(t *testing.T) {
+	t.Parallel()
+
+	relationships := []Relationship{
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis"},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", Port: 6379},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", Async: true},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", Environments: []string{"prod"}},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", SLA: "99.9%"},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", TimeoutMS: 100},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", Annotations: map[string]string{"team": "a"}},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", Annotations: map[string]string{"team": "b"}},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", Discovered: true},
+		{Action: RelationshipActionUses, Name: "Redis", Technology: "redis", External: true},
+		{Action: RelationshipActionUses, Name: "Redis", Proto: "tcp"},
+		{Action: RelationshipActionUses, Name: "Redis", Description: "Cache"},
+		{Action: RelationshipActionRequests, Name: "Catalog", Proto: "grpc"},
+		{Action: RelationshipActionRequests, Name: "Catalog", Proto: "http"},
+	}
+
+	expected := &ServiceFile{Relationships: slices.Clone(relationships)}
+	expected.Sort()
+	want, err := MarshalYAML(expected)
+	require.NoError(t, err)
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+	for range 100 {
+		shuffled := slices.Clone(relationships)
+		rnd.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+
+		sf := &ServiceFile{Relationships: shuffled}
+		sf.Sort()
+		got, err := MarshalYAML(sf)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api"},
+		Relationships: []Relationship{
+			{Action: "uses", Name: "database", Technology: "postgres"},
+			{Action: "sends", Name: "events", Technology: "kafka"},
+		},
+	}
+
+	reordered := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api"},
+		Relationships: []Relationship{
+			{Action: "sends", Name: "events", Technology: "kafka"},
+			{Action: "uses", Name: "database", Technology: "postgres"},
+		},
+	}
+
+	changed := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api", Description: "API gateway"},
+		Relationships: []Relationship{
+			{Action: "uses", Name: "database", Technology: "postgres"},
+			{Action: "sends", Name: "events", Technology: "kafka"},
+		},
+	}
+
+	assert.Equal(t, sf.Hash(), reordered.Hash())
+	assert.NotEqual(t, sf.Hash(), changed.Hash())
+	assert.Equal(t, "sends", string(reordered.Relationships[0].Action), "Hash must not reorder relationships")
+}
+
+func TestHashIgnoresNilAndEmptyFields(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version:       Version,
+		Info:          Info{Name: "api"},
+		Relationships: []Relationship{{Action: "uses", Name: "database"}},
+	}
+
+	empty := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api", Tags: []string{}, Annotations: map[string]string{}},
+		Relationships: []Relationship{
+			{
+				Action:       "uses",
+				Name:         "database",
+				Technologies: []string{},
+				Environments: []string{},
+				Annotations:  map[string]string{},
+			},
+		},
+	}
+
+	require.True(t, sf.Equal(empty))
+	assert.Equal(t, sf.Hash(), empty.Hash())
+}
+
+func TestSourceIsNotSerialized(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api", Source: Source{File: "api/api.go", Line: 3}},
+		Relationships: []Relationship{
+			{Action: "uses", Name: "database", Source: Source{File: "api/store.go", Line: 12}},
+		},
+	}
+
+	moved := sf.Clone()
+	moved.Info.Source.Line = 5
+	moved.Relationships[0].Source = Source{File: "api/db.go", Line: 1}
+
+	yamlData, err := MarshalYAML(sf)
+	require.NoError(t, err)
+	assert.NotContains(t, string(yamlData), "api.go")
+
+	jsonData, err := json.Marshal(sf)
+	require.NoError(t, err)
+	assert.NotContains(t, string(jsonData), "api.go")
+
+	assert.True(t, sf.Equal(moved))
+	assert.Equal(t, sf.Hash(), moved.Hash())
+	assert.Equal(t, "api/api.go:3", sf.Info.Source.String())
+	assert.True(t, Source{}.IsZero())
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info: Info{
+			Name:        "api",
+			Tags:        []string{"edge", "public"},
+			Annotations: map[string]string{"lifecycle": "production"},
+		},
+		Relationships: []Relationship{
+			{Action: "requests", Name: "orders", Technology: "grpc", Technologies: []string{"grpc", "http2"}, Annotations: map[string]string{"retries": "3"}},
+			{Action: "uses", Name: "database", Technology: "postgres"},
+		},
+	}
+
+	clone := sf.Clone()
+	require.Equal(t, sf, clone)
+
+	clone.Info.Name = "gateway"
+	clone.Info.Tags[0] = "internal"
+	clone.Info.Annotations["lifecycle"] = "deprecated"
+	clone.Relationships[0].Name = "billing"
+	clone.Relationships[0].Technologies[1] = "http3"
+	clone.Relationships[0].Annotations["retries"] = "5"
+	clone.Relationships = append(clone.Relationships[:1], Relationship{Action: "sends", Name: "events"})
+
+	assert.Equal(t, &ServiceFile{
+		Version: Version,
+		Info: Info{
+			Name:        "api",
+			Tags:        []string{"edge", "public"},
+			Annotations: map[string]string{"lifecycle": "production"},
+		},
+		Relationships: []Relationship{
+			{Action: "requests", Name: "orders", Technology: "grpc", Technologies: []string{"grpc", "http2"}, Annotations: map[string]string{"retries": "3"}},
+			{Action: "uses", Name: "database", Technology: "postgres"},
+		},
+	}, sf)
+
+	assert.Equal(t, &ServiceFile{Info: Info{Name: "empty"}}, (&ServiceFile{Info: Info{Name: "empty"}}).Clone())
+}
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api", Tags: []string{"edge", "public"}},
+		Relationships: []Relationship{
+			{Action: "uses", Name: "database", Technology: "postgres"},
+			{Action: "sends", Name: "events", Technology: "kafka"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		other *ServiceFile
+		want  bool
+	}{
+		{
+			name: "reordered",
+			other: &ServiceFile{
+				Version: Version,
+				Info:    Info{Name: "api", Tags: []string{"public", "edge"}, Annotations: map[string]string{}},
+				Relationships: []Relationship{
+					{Action: "sends", Name: "events", Technology: "kafka"},
+					{Action: "uses", Name: "database", Technology: "postgres"},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "different relationship",
+			other: &ServiceFile{
+				Version: Version,
+				Info:    Info{Name: "api", Tags: []string{"edge", "public"}},
+				Relationships: []Relationship{
+					{Action: "uses", Name: "database", Technology: "mysql"},
+					{Action: "sends", Name: "events", Technology: "kafka"},
+				},
+			},
+		},
+		{
+			name: "different info",
+			other: &ServiceFile{
+				Version:       Version,
+				Info:          Info{Name: "api", Owner: "edge-team", Tags: []string{"edge", "public"}},
+				Relationships: sf.Relationships,
+			},
+		},
+		{
+			name:  "different version",
+			other: &ServiceFile{Version: "0.0.1", Info: sf.Info, Relationships: sf.Relationships},
+		},
+		{
+			name: "nil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, sf.Equal(tt.other))
+			assert.Equal(t, tt.want, tt.other.Equal(sf))
+		})
+	}
+
+	assert.True(t, (&ServiceFile{Info: Info{Name: "api"}}).Equal(&ServiceFile{Info: Info{Name: "api"}, Relationships: []Relationship{}}))
+	assert.Equal(t, []string{"edge", "public"}, sf.Info.Tags, "Equal must not sort the service files")
+
+	var none *ServiceFile
+	assert.True(t, none.Equal(nil))
+}
+
+func TestDeduplicate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		input       []Relationship
+		expected    []Relationship
+		wantRemoved int
+	}{
+		{
+			name: "remove identical relationships",
+			input: []Relationship{
+				{Action: "uses", Name: "database", Technology: "postgres", Description: "stores data"},
+				{Action: "sends", Name: "events", Technology: "kafka"},
+				{Action: "uses", Name: "database", Technology: "postgres", Description: "stores data"},
+				{Action: "sends", Name: "events", Technology: "kafka"},
+				{Action: "uses", Name: "database", Technology: "postgres", Description: "stores data"},
+			},
+			expected: []Relationship{
+				{Action: "sends", Name: "events", Technology: "kafka"},
+				{Action: "uses", Name: "database", Technology: "postgres", Description: "stores data"},
+			},
+			wantRemoved: 3,
+		},
+		{
+			name: "keep relationships differing by a single field",
+			input: []Relationship{
+				{Action: "uses", Name: "database", Technology: "postgres", Proto: "tcp"},
+				{Action: "uses", Name: "database", Technology: "postgres"},
+			},
+			expected: []Relationship{
+				{Action: "uses", Name: "database", Technology: "postgres"},
+				{Action: "uses", Name: "database", Technology: "postgres", Proto: "tcp"},
+			},
+			wantRemoved: 0,
+		},
+		{
+			name:        "empty relationships",
+			input:       []Relationship{},
+			expected:    []Relationship{},
+			wantRemoved: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sf := &ServiceFile{Version: Version, Info: Info{Name: "service"}, Relationships: tt.input}
+
+			removed := sf.Deduplicate()
+
+			assert.Equal(t, tt.wantRemoved, removed)
+			assert.Equal(t, tt.expected, sf.Relationships)
+		})
+	}
+}
+
+func TestNormalizeProto(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		proto         string
+		want          string
+		wantCanonical bool
+	}{
+		{proto: "tcp", want: "tcp", wantCanonical: true},
+		{proto: " TCP ", want: "tcp", wantCanonical: true},
+		{proto: "gRPC", want: "grpc", wantCanonical: true},
+		{proto: "HTTP/1.1", want: "http", wantCanonical: true},
+		{proto: "AMQP091", want: "amqp", wantCanonical: true},
+		{proto: "QUIC", want: "quic", wantCanonical: false},
+		{proto: "", want: "", wantCanonical: false},
+	}
+
+	for _, tt := range tests {
+		got, canonical := NormalizeProto(tt.proto)
+		assert.Equal(t, tt.want, got, tt.proto)
+		assert.Equal(t, tt.wantCanonical, canonical, tt.proto)
+	}
+
+	rel := Relationship{Action: RelationshipActionRequests, Name: "billing", Proto: "HTTPS"}
+	assert.True(t, rel.NormalizeProto())
+	assert.Equal(t, "https", rel.Proto)
+}
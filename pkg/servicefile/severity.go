@@ -0,0 +1,64 @@
+package servicefile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is how serious a reported issue is. Severities are ordered, SeverityError being the highest.
+type Severity int
+
+const (
+	// SeverityOff disables a rule.
+	SeverityOff Severity = iota
+	// SeverityInfo is for issues reported for information only.
+	SeverityInfo
+	// SeverityWarning is for issues that should be looked at but don't make a service file invalid.
+	SeverityWarning
+	// SeverityError is for issues that make a service file invalid.
+	SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityOff:     "off",
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the severity named name, one of off, info, warning and error.
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return s, nil
+		}
+	}
+
+	return SeverityOff, fmt.Errorf("unknown severity %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = parsed
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package servicefile
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Stats are aggregate counts over service files, see Summary.
+type Stats struct {
+	// Services is the number of service files.
+	Services int `json:"services"`
+	// Relationships is the total number of relationships.
+	Relationships int `json:"relationships"`
+	// Actions is the number of relationships of each action.
+	Actions map[RelationshipAction]int `json:"actions"`
+	// Externals is the number of distinct relationship targets that are not services, see IsExternal.
+	Externals int `json:"externals"`
+	// Systems is the number of services of each system. Services without system are not counted.
+	Systems map[string]int `json:"systems"`
+}
+
+// Summary returns the statistics of files. Maps are never nil.
+func Summary(files []*ServiceFile) Stats {
+	stats := Stats{
+		Services: len(files),
+		Actions:  make(map[RelationshipAction]int),
+		Systems:  make(map[string]int),
+	}
+
+	for _, sf := range files {
+		if sf.Info.System != "" {
+			stats.Systems[sf.Info.System]++
+		}
+
+		for _, rel := range sf.Relationships {
+			stats.Relationships++
+			stats.Actions[rel.Action]++
+		}
+	}
+
+	for _, node := range NewGraph(files).Nodes() {
+		if node.External {
+			stats.Externals++
+		}
+	}
+
+	return stats
+}
+
+// String returns the statistics for humans, one count per line, with the counts of each action
+// and system indented below their total and sorted by name.
+func (s Stats) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "services: %d\n", s.Services)
+	fmt.Fprintf(&b, "relationships: %d\n", s.Relationships)
+	for _, action := range slices.Sorted(maps.Keys(s.Actions)) {
+		fmt.Fprintf(&b, "  %s: %d\n", action, s.Actions[action])
+	}
+	fmt.Fprintf(&b, "externals: %d\n", s.Externals)
+	fmt.Fprintf(&b, "systems: %d\n", len(s.Systems))
+	for _, system := range slices.Sorted(maps.Keys(s.Systems)) {
+		fmt.Fprintf(&b, "  %s: %d\n", system, s.Systems[system])
+	}
+
+	return b.String()
+}
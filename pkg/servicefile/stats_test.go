@@ -0,0 +1,64 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders", System: "shop"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "billing"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+				{Action: RelationshipActionSends, Name: "Kafka"},
+			},
+		},
+		{
+			Info: Info{Name: "billing", System: "finance"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+			},
+		},
+		{
+			Info: Info{Name: "catalog", System: "shop"},
+		},
+		{
+			Info: Info{Name: "legacy"},
+		},
+	}
+
+	stats := Summary(files)
+
+	assert.Equal(t, Stats{
+		Services:      4,
+		Relationships: 5,
+		Actions: map[RelationshipAction]int{
+			RelationshipActionReplies:  1,
+			RelationshipActionRequests: 1,
+			RelationshipActionSends:    1,
+			RelationshipActionUses:     2,
+		},
+		Externals: 2,
+		Systems:   map[string]int{"finance": 1, "shop": 2},
+	}, stats)
+
+	assert.Equal(t, `services: 4
+relationships: 5
+  replies: 1
+  requests: 1
+  sends: 1
+  uses: 2
+externals: 2
+systems: 2
+  finance: 1
+  shop: 2
+`, stats.String())
+
+	assert.Equal(t, Stats{Actions: map[RelationshipAction]int{}, Systems: map[string]int{}}, Summary(nil))
+}
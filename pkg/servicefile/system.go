@@ -0,0 +1,136 @@
+package servicefile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// System is a whole architecture in a single document: the service files of every service.
+// It is the document written by RenderYAML and RenderJSON, with services sorted by name.
+type System struct {
+	Version  string         `yaml:"servicefile" json:"servicefile"`
+	Services []*ServiceFile `yaml:"services" json:"services"`
+}
+
+// NewSystem returns the system made of copies of files, sorted by service name
+// with their relationships sorted. Files are left untouched.
+func NewSystem(files []*ServiceFile) *System {
+	return &System{Version: Version, Services: sortedServiceFiles(files)}
+}
+
+// Validate checks that the system has a supported version, that each of its services is valid,
+// see ServiceFile.Validate, and that no service is defined more than once, see ValidateUniqueServices.
+// Every problem found is reported.
+func (s *System) Validate() error {
+	var errs []error
+
+	if s.Version != Version {
+		errs = append(errs, fmt.Errorf("unsupported version %q, expected %q", s.Version, Version))
+	}
+
+	for i, sf := range s.Services {
+		if sf == nil {
+			errs = append(errs, fmt.Errorf("service %d is empty", i))
+			continue
+		}
+		if err := sf.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("service %d (%s): %w", i, sf.Info.Name, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		errs = append(errs, ValidateUniqueServices(s.Services))
+	}
+
+	return errors.Join(errs...)
+}
+
+// MarshalSystemYAML returns the YAML encoding of the system, with sorted relationships.
+// The system itself is left untouched.
+func MarshalSystemYAML(s *System) ([]byte, error) {
+	sorted := System{Version: s.Version, Services: make([]*ServiceFile, 0, len(s.Services))}
+	for _, sf := range s.Services {
+		c := sf.Clone()
+		c.Sort()
+		sorted.Services = append(sorted.Services, c)
+	}
+
+	data, err := yaml.Marshal(&sorted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal system: %w", err)
+	}
+
+	return data, nil
+}
+
+// ParseSystemYAML decodes a system encoded by MarshalSystemYAML and validates it, see System.Validate.
+func ParseSystemYAML(data []byte) (*System, error) {
+	var s System
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse system: %w", err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// LoadSystem reads a system, as JSON when path has a .json extension and as YAML otherwise,
+// expanding references to environment variables like Load, and validates it, see System.Validate.
+// Errors mention the path of the file.
+func LoadSystem(path string) (*System, error) {
+	var s System
+
+	if filepath.Ext(path) == ".json" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse file %s: %w", path, err)
+		}
+	} else if err := decodeYAMLFile(path, nil, &s); err != nil {
+		return nil, err
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid system %s: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+// RenderYAML writes the service files as a single YAML System document.
+func RenderYAML(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+
+	data, err := MarshalSystemYAML(NewSystem(o.Apply(files)))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// RenderJSON writes the service files as a single indented JSON System document.
+func RenderJSON(files []*ServiceFile, w io.Writer, opts ...RenderOption) error {
+	o := newRenderOptions(opts)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(NewSystem(o.Apply(files))); err != nil {
+		return fmt.Errorf("failed to encode system: %w", err)
+	}
+
+	return nil
+}
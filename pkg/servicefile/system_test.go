@@ -0,0 +1,103 @@
+package servicefile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	files, err := LoadDir("testdata/graph")
+	require.NoError(t, err)
+
+	for _, files := range [][]*ServiceFile{files, {files[1], files[0]}} {
+		want := NewSystem(files)
+
+		for name, render := range map[string]func([]*ServiceFile, *bytes.Buffer) error{
+			"system.yaml": func(files []*ServiceFile, buf *bytes.Buffer) error { return RenderYAML(files, buf) },
+			"system.json": func(files []*ServiceFile, buf *bytes.Buffer) error { return RenderJSON(files, buf) },
+		} {
+			var buf bytes.Buffer
+			require.NoError(t, render(files, &buf))
+
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+			got, err := LoadSystem(path)
+			require.NoError(t, err, name)
+			assert.Equal(t, want, got, name)
+		}
+	}
+}
+
+func TestRenderYAML(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Version: Version,
+			Info:    Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+				{Action: RelationshipActionRequests, Name: "billing", Environments: []string{"prod"}},
+			},
+		},
+		{Version: Version, Info: Info{Name: "billing"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, RenderYAML(files, &buf, WithEnvironment("dev")))
+
+	assert.Equal(t, `servicefile: 0.1.0
+services:
+    - servicefile: 0.1.0
+      info:
+        name: billing
+        description: ""
+      relationships: []
+    - servicefile: 0.1.0
+      info:
+        name: orders
+        description: ""
+      relationships:
+        - action: uses
+          name: PostgreSQL
+`, buf.String())
+
+	assert.Len(t, files[0].Relationships, 2)
+}
+
+func TestSystemValidate(t *testing.T) {
+	t.Parallel()
+
+	system := &System{
+		Version: Version,
+		Services: []*ServiceFile{
+			{Version: Version, Info: Info{Name: "orders"}},
+			{Version: "0.0.1", Info: Info{Name: "billing"}},
+			{Version: Version, Info: Info{Name: "billing"}},
+		},
+	}
+
+	err := system.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `service 1 (billing): unsupported version "0.0.1"`)
+
+	system.Services[1].Version = Version
+	err = system.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `service "billing" is defined more than once`)
+
+	system.Services = system.Services[:2]
+	require.NoError(t, system.Validate())
+
+	_, err = ParseSystemYAML([]byte("servicefile: 0.0.1\nservices: []\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported version")
+}
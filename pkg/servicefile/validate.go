@@ -0,0 +1,382 @@
+package servicefile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Names of the rules checked by Check and Validate.
+const (
+	// RuleVersion reports service files whose version is not Version, an error by default.
+	RuleVersion = "version"
+	// RuleMissingName reports service files without service name, an error by default.
+	RuleMissingName = "missing-name"
+	// RuleUnknownAction reports relationships whose action is not one of the known actions, an error by default.
+	RuleUnknownAction = "unknown-action"
+	// RuleDuplicateRelationship reports relationships that are exact duplicates, an error by default.
+	RuleDuplicateRelationship = "duplicate-relationship"
+	// RuleTechnologyProto reports implausible technology and proto combinations, an error by default.
+	// The rule only runs when ValidateOptions.TechnologyProtos is set.
+	RuleTechnologyProto = "technology-proto"
+	// RuleMissingDescription reports services and relationships without description, a warning by default.
+	RuleMissingDescription = "missing-description"
+	// RuleSelfRelationship reports relationships targeting the service they belong to, a warning by default.
+	RuleSelfRelationship = "self-relationship"
+	// RuleUndocumentedDeprecation reports deprecated relationships without a description explaining
+	// the migration, a warning by default.
+	RuleUndocumentedDeprecation = "undocumented-deprecation"
+)
+
+// DefaultRuleSeverities returns the severity of each rule when it isn't overridden.
+func DefaultRuleSeverities() map[string]Severity {
+	return map[string]Severity{
+		RuleVersion:                 SeverityError,
+		RuleMissingName:             SeverityError,
+		RuleUnknownAction:           SeverityError,
+		RuleDuplicateRelationship:   SeverityError,
+		RuleTechnologyProto:         SeverityError,
+		RuleMissingDescription:      SeverityWarning,
+		RuleSelfRelationship:        SeverityWarning,
+		RuleUndocumentedDeprecation: SeverityWarning,
+	}
+}
+
+// ValidateOptions configures the checks run by Validate.
+type ValidateOptions struct {
+	// TechnologyProtos lists the protos each technology is expected to be used over.
+	// Relationships combining a listed technology with another proto are reported.
+	// The check is disabled when TechnologyProtos is nil.
+	TechnologyProtos map[string][]string
+	// RuleSeverities overrides the severity of rules, see DefaultRuleSeverities.
+	// Rules set to SeverityOff are not checked.
+	RuleSeverities map[string]Severity
+}
+
+// ValidateOption configures ValidateOptions.
+type ValidateOption func(*ValidateOptions)
+
+// WithTechnologyProtoCheck enables reporting of implausible technology and proto combinations,
+// using DefaultTechnologyProtos when table is nil.
+func WithTechnologyProtoCheck(table map[string][]string) ValidateOption {
+	return func(o *ValidateOptions) {
+		if table == nil {
+			table = DefaultTechnologyProtos()
+		}
+		o.TechnologyProtos = table
+	}
+}
+
+// WithRuleSeverities overrides the severity of the given rules.
+func WithRuleSeverities(severities map[string]Severity) ValidateOption {
+	return func(o *ValidateOptions) {
+		if o.RuleSeverities == nil {
+			o.RuleSeverities = make(map[string]Severity, len(severities))
+		}
+		for rule, severity := range severities {
+			o.RuleSeverities[rule] = severity
+		}
+	}
+}
+
+// DefaultTechnologyProtos returns the protos common technologies are expected to be used over.
+func DefaultTechnologyProtos() map[string][]string {
+	return map[string][]string{
+		"grpc":       {"grpc", "http2"},
+		"http":       {"http", "https"},
+		"rest":       {"http", "https"},
+		"graphql":    {"http", "https"},
+		"postgresql": {"tcp"},
+		"mysql":      {"tcp"},
+		"redis":      {"tcp"},
+		"kafka":      {"tcp", "kafka"},
+		"rabbitmq":   {"amqp", "tcp"},
+		"nats":       {"nats", "tcp"},
+	}
+}
+
+func (o ValidateOptions) severity(rule string) Severity {
+	if severity, ok := o.RuleSeverities[rule]; ok {
+		return severity
+	}
+
+	return DefaultRuleSeverities()[rule]
+}
+
+// Issue is a problem reported by Check.
+type Issue struct {
+	// Rule is the name of the rule reporting the issue.
+	Rule string
+	// Severity is the configured severity of the rule.
+	Severity Severity
+	// Message describes the issue.
+	Message string
+}
+
+// Error returns the message of the issue.
+func (i Issue) Error() string {
+	return i.Message
+}
+
+// Check runs every enabled rule against the service file and returns the issues found, whatever their severity.
+func (sf *ServiceFile) Check(opts ...ValidateOption) []Issue {
+	var o ValidateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var issues []Issue
+	report := func(rule string, format string, args ...any) {
+		if severity := o.severity(rule); severity != SeverityOff {
+			issues = append(issues, Issue{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	if sf.Version != Version {
+		report(RuleVersion, "unsupported version %q, expected %q", sf.Version, Version)
+	}
+
+	if sf.Info.Name == "" {
+		report(RuleMissingName, "service has no name")
+	}
+
+	if sf.Info.Description == "" {
+		report(RuleMissingDescription, "service %s has no description", sf.Info.Name)
+	}
+
+	for i, rel := range sf.Relationships {
+		if !rel.Action.IsValid() {
+			report(RuleUnknownAction, "relationship %d (%s %s) has unknown action %q", i, rel.Action, rel.Name, rel.Action)
+		}
+
+		for j := range i {
+			if rel.Equal(sf.Relationships[j]) {
+				report(RuleDuplicateRelationship, "relationship %d (%s %s) duplicates relationship %d", i, rel.Action, rel.Name, j)
+				break
+			}
+		}
+
+		if rel.Name != "" && rel.Name == sf.Info.Name {
+			report(RuleSelfRelationship, "relationship %d (%s %s) targets its own service", i, rel.Action, rel.Name)
+		}
+
+		if o.TechnologyProtos != nil && !technologyMatchesProto(o.TechnologyProtos, rel.Technology, rel.Proto) {
+			report(RuleTechnologyProto, "%s (%s %s) uses technology %q over unexpected proto %q", relationshipLocation(i, rel), rel.Action, rel.Name, rel.Technology, rel.Proto)
+		}
+
+		if rel.Description == "" {
+			report(RuleMissingDescription, "relationship %d (%s %s) has no description", i, rel.Action, rel.Name)
+		}
+
+		if rel.Deprecated && rel.Description == "" {
+			report(RuleUndocumentedDeprecation, "relationship %d (%s %s) is deprecated without a description of the migration", i, rel.Action, rel.Name)
+		}
+	}
+
+	return issues
+}
+
+// Validate checks that the service file is well-formed and reports every problem found,
+// that is every issue reported by Check with SeverityError.
+func (sf *ServiceFile) Validate(opts ...ValidateOption) error {
+	var errs []error
+
+	for _, issue := range sf.Check(opts...) {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// relationshipLocation names the i-th relationship of a service file by the code it was parsed from,
+// or by its index when it wasn't parsed from code.
+func relationshipLocation(i int, rel Relationship) string {
+	if rel.Source.IsZero() {
+		return fmt.Sprintf("relationship %d", i)
+	}
+
+	return "relationship at " + rel.Source.String()
+}
+
+// technologyMatchesProto reports whether the combination is plausible according to table.
+// Technologies that are not in the table and relationships without a proto always match.
+func technologyMatchesProto(table map[string][]string, technology, proto string) bool {
+	if proto == "" {
+		return true
+	}
+
+	for tech, protos := range table {
+		if !strings.EqualFold(tech, technology) {
+			continue
+		}
+
+		for _, p := range protos {
+			if strings.EqualFold(p, proto) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// ValidateUniqueServices checks that no two service files describe a service
+// with the same name within the same system. Services sharing a name across
+// different systems are allowed.
+func ValidateUniqueServices(files []*ServiceFile) error {
+	type key struct {
+		name   string
+		system string
+	}
+
+	var errs []error
+
+	seen := make(map[key]int, len(files))
+	for i, sf := range files {
+		k := key{name: sf.Info.Name, system: sf.Info.System}
+
+		if first, exists := seen[k]; exists {
+			errs = append(errs, fmt.Errorf("service %q is defined more than once in system %q (service files %d and %d)", k.name, k.system, first, i))
+			continue
+		}
+
+		seen[k] = i
+	}
+
+	return errors.Join(errs...)
+}
+
+// ActionConflict is a relationship target reached by a service through several distinct actions.
+type ActionConflict struct {
+	Service string
+	Target  string
+	Actions []RelationshipAction
+}
+
+// ConflictingActions lists the targets each service reaches through more than one distinct action,
+// such as a service both using and requesting the same target, for review.
+// Conflicts are sorted by service and target, and their actions are sorted.
+// This is informational: modeling both actions may be intended.
+func ConflictingActions(files []*ServiceFile) []ActionConflict {
+	var conflicts []ActionConflict
+
+	for _, sf := range files {
+		actions := make(map[string]map[string]struct{})
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" {
+				continue
+			}
+			if actions[rel.Name] == nil {
+				actions[rel.Name] = make(map[string]struct{})
+			}
+			actions[rel.Name][string(rel.Action)] = struct{}{}
+		}
+
+		for target, set := range actions {
+			if len(set) < 2 {
+				continue
+			}
+
+			conflict := ActionConflict{Service: sf.Info.Name, Target: target}
+			for _, action := range sortedKeys(set) {
+				conflict.Actions = append(conflict.Actions, RelationshipAction(action))
+			}
+
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Service != conflicts[j].Service {
+			return conflicts[i].Service < conflicts[j].Service
+		}
+		return conflicts[i].Target < conflicts[j].Target
+	})
+
+	return conflicts
+}
+
+// DefaultInternalTargetPattern matches the names of relationship targets that look like internal services,
+// such as OrderService or billing-service.
+var DefaultInternalTargetPattern = regexp.MustCompile(`(?i)service$`)
+
+// ResolveOptions configures CheckTargetsResolve.
+type ResolveOptions struct {
+	// Pattern matches the names of targets that look internal, DefaultInternalTargetPattern when nil.
+	Pattern *regexp.Regexp
+	// Internal lists targets that are internal whatever their name.
+	Internal []string
+	// External lists targets that are not internal whatever their name, taking precedence over Internal.
+	External []string
+}
+
+// UnresolvedTarget is a relationship target that looks internal but that no service file defines.
+type UnresolvedTarget struct {
+	Service string
+	Target  string
+}
+
+// CheckTargetsResolve lists the relationship targets of files that look internal according to opts
+// but aren't the name or the alias of any service in files, along with the service relating to them.
+// Such targets are either undocumented internal services or external components named like services,
+// and usually reveal drift between the code and the documented architecture.
+// Targets are sorted by service and target, and reported once per service.
+func CheckTargetsResolve(files []*ServiceFile, opts ResolveOptions) []UnresolvedTarget {
+	pattern := opts.Pattern
+	if pattern == nil {
+		pattern = DefaultInternalTargetPattern
+	}
+
+	services := make(map[string]struct{}, len(files))
+	for _, sf := range files {
+		services[sf.Info.Name] = struct{}{}
+	}
+	for alias := range aliasNames(files) {
+		services[alias] = struct{}{}
+	}
+
+	internal := func(target string) bool {
+		if slices.Contains(opts.External, target) {
+			return false
+		}
+		return slices.Contains(opts.Internal, target) || pattern.MatchString(target)
+	}
+
+	var unresolved []UnresolvedTarget
+
+	seen := make(map[UnresolvedTarget]struct{})
+	for _, sf := range files {
+		for _, rel := range sf.Relationships {
+			if rel.Name == "" || !internal(rel.Name) {
+				continue
+			}
+			if _, defined := services[rel.Name]; defined {
+				continue
+			}
+
+			target := UnresolvedTarget{Service: sf.Info.Name, Target: rel.Name}
+			if _, exists := seen[target]; exists {
+				continue
+			}
+			seen[target] = struct{}{}
+
+			unresolved = append(unresolved, target)
+		}
+	}
+
+	sort.Slice(unresolved, func(i, j int) bool {
+		if unresolved[i].Service != unresolved[j].Service {
+			return unresolved[i].Service < unresolved[j].Service
+		}
+		return unresolved[i].Target < unresolved[j].Target
+	})
+
+	return unresolved
+}
@@ -0,0 +1,455 @@
+package servicefile
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		sf          *ServiceFile
+		wantErr     bool
+		errContains []string
+	}{
+		{
+			name: "distinct relationships",
+			sf: &ServiceFile{
+				Version: Version,
+				Info:    Info{Name: "api"},
+				Relationships: []Relationship{
+					{Action: "uses", Name: "database", Technology: "postgres"},
+					{Action: "uses", Name: "database", Technology: "postgres", Proto: "tcp"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate relationships",
+			sf: &ServiceFile{
+				Version: Version,
+				Info:    Info{Name: "api"},
+				Relationships: []Relationship{
+					{Action: "uses", Name: "database", Technology: "postgres"},
+					{Action: "sends", Name: "events", Technology: "kafka"},
+					{Action: "uses", Name: "database", Technology: "postgres"},
+					{Action: "sends", Name: "events", Technology: "kafka"},
+				},
+			},
+			wantErr: true,
+			errContains: []string{
+				"relationship 2 (uses database) duplicates relationship 0",
+				"relationship 3 (sends events) duplicates relationship 1",
+			},
+		},
+		{
+			name: "unsupported version",
+			sf: &ServiceFile{
+				Version: "0.0.1",
+				Info:    Info{Name: "api"},
+			},
+			wantErr:     true,
+			errContains: []string{`unsupported version "0.0.1", expected "0.1.0"`},
+		},
+		{
+			name: "every problem is reported",
+			sf: &ServiceFile{
+				Info: Info{},
+				Relationships: []Relationship{
+					{Action: "usess", Name: "database"},
+					{Action: "uses", Name: "cache"},
+					{Action: "uses", Name: "cache"},
+				},
+			},
+			wantErr: true,
+			errContains: []string{
+				`unsupported version ""`,
+				"service has no name",
+				`relationship 0 (usess database) has unknown action "usess"`,
+				"relationship 2 (uses cache) duplicates relationship 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			relationships := append([]Relationship(nil), tt.sf.Relationships...)
+
+			err := tt.sf.Validate()
+
+			if tt.wantErr {
+				require.Error(t, err)
+				for _, s := range tt.errContains {
+					assert.Contains(t, err.Error(), s)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, relationships, tt.sf.Relationships, "Validate must not modify relationships")
+		})
+	}
+}
+
+func TestValidateTechnologyProtos(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		rel         Relationship
+		opts        []ValidateOption
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:    "consistent pair",
+			rel:     Relationship{Action: "requests", Name: "billing", Technology: "grpc", Proto: "grpc"},
+			opts:    []ValidateOption{WithTechnologyProtoCheck(nil)},
+			wantErr: false,
+		},
+		{
+			name:        "contradictory pair",
+			rel:         Relationship{Action: "requests", Name: "billing", Technology: "rest", Proto: "grpc"},
+			opts:        []ValidateOption{WithTechnologyProtoCheck(nil)},
+			wantErr:     true,
+			errContains: `relationship 1 (requests billing) uses technology "rest" over unexpected proto "grpc"`,
+		},
+		{
+			name: "contradictory pair parsed from code",
+			rel: Relationship{
+				Action: "requests", Name: "billing", Technology: "rest", Proto: "grpc",
+				Source: Source{File: "orders/billing.go", Line: 12},
+			},
+			opts:        []ValidateOption{WithTechnologyProtoCheck(nil)},
+			wantErr:     true,
+			errContains: `relationship at orders/billing.go:12 (requests billing) uses technology "rest" over unexpected proto "grpc"`,
+		},
+		{
+			name:    "contradictory pair with check disabled",
+			rel:     Relationship{Action: "requests", Name: "billing", Technology: "rest", Proto: "grpc"},
+			wantErr: false,
+		},
+		{
+			name:    "technology case is ignored",
+			rel:     Relationship{Action: "uses", Name: "database", Technology: "PostgreSQL", Proto: "TCP"},
+			opts:    []ValidateOption{WithTechnologyProtoCheck(nil)},
+			wantErr: false,
+		},
+		{
+			name:    "unknown technology",
+			rel:     Relationship{Action: "uses", Name: "mainframe", Technology: "cobol", Proto: "tcp"},
+			opts:    []ValidateOption{WithTechnologyProtoCheck(nil)},
+			wantErr: false,
+		},
+		{
+			name:        "custom table",
+			rel:         Relationship{Action: "uses", Name: "mainframe", Technology: "cobol", Proto: "tcp"},
+			opts:        []ValidateOption{WithTechnologyProtoCheck(map[string][]string{"cobol": {"sna"}})},
+			wantErr:     true,
+			errContains: `uses technology "cobol" over unexpected proto "tcp"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sf := &ServiceFile{
+				Version: Version,
+				Info:    Info{Name: "api"},
+				Relationships: []Relationship{
+					{Action: "uses", Name: "cache", Technology: "redis", Proto: "tcp"},
+					tt.rel,
+				},
+			}
+
+			err := sf.Validate(tt.opts...)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckRuleSeverities(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "api", Description: "Serves the public API"},
+		Relationships: []Relationship{
+			{Action: "uses", Name: "database", Technology: "postgres", Description: "Stores users"},
+			{Action: "sends", Name: "events", Technology: "kafka"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		opts         []ValidateOption
+		wantIssues   []Issue
+		wantValidErr bool
+	}{
+		{
+			name: "default severity",
+			wantIssues: []Issue{
+				{Rule: RuleMissingDescription, Severity: SeverityWarning, Message: "relationship 1 (sends events) has no description"},
+			},
+			wantValidErr: false,
+		},
+		{
+			name: "missing description configured as error",
+			opts: []ValidateOption{WithRuleSeverities(map[string]Severity{RuleMissingDescription: SeverityError})},
+			wantIssues: []Issue{
+				{Rule: RuleMissingDescription, Severity: SeverityError, Message: "relationship 1 (sends events) has no description"},
+			},
+			wantValidErr: true,
+		},
+		{
+			name:         "missing description turned off",
+			opts:         []ValidateOption{WithRuleSeverities(map[string]Severity{RuleMissingDescription: SeverityOff})},
+			wantIssues:   nil,
+			wantValidErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.wantIssues, sf.Check(tt.opts...))
+
+			err := sf.Validate(tt.opts...)
+			if tt.wantValidErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "relationship 1 (sends events) has no description")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckSelfRelationship(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "catalog", Description: "Lists the products on sale"},
+		Relationships: []Relationship{
+			{Action: "uses", Name: "database", Description: "Stores products"},
+			{Action: "requests", Name: "catalog", Description: "Meant for pricing"},
+		},
+	}
+
+	want := Issue{Rule: RuleSelfRelationship, Severity: SeverityWarning, Message: "relationship 1 (requests catalog) targets its own service"}
+	assert.Equal(t, []Issue{want}, sf.Check())
+	require.NoError(t, sf.Validate())
+
+	err := sf.Validate(WithRuleSeverities(map[string]Severity{RuleSelfRelationship: SeverityError}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), want.Message)
+}
+
+func TestCheckUndocumentedDeprecation(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "checkout", Description: "Takes orders"},
+		Relationships: []Relationship{
+			{Action: "requests", Name: "legacy-auth", Description: "Replaced by auth, removed once v2 clients are gone", Deprecated: true},
+			{Action: "requests", Name: "old-billing", Deprecated: true},
+		},
+	}
+
+	issues := sf.Check(WithRuleSeverities(map[string]Severity{RuleMissingDescription: SeverityOff}))
+	assert.Equal(t, []Issue{{
+		Rule:     RuleUndocumentedDeprecation,
+		Severity: SeverityWarning,
+		Message:  "relationship 1 (requests old-billing) is deprecated without a description of the migration",
+	}}, issues)
+	require.NoError(t, sf.Validate())
+}
+
+func TestParseSeverity(t *testing.T) {
+	t.Parallel()
+
+	for _, severity := range []Severity{SeverityOff, SeverityInfo, SeverityWarning, SeverityError} {
+		parsed, err := ParseSeverity(severity.String())
+		require.NoError(t, err)
+		assert.Equal(t, severity, parsed)
+	}
+
+	parsed, err := ParseSeverity(" Warning ")
+	require.NoError(t, err)
+	assert.Equal(t, SeverityWarning, parsed)
+
+	_, err = ParseSeverity("fatal")
+	require.Error(t, err)
+}
+
+func TestValidateUniqueServices(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		files       []*ServiceFile
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "same name across systems",
+			files: []*ServiceFile{
+				{Info: Info{Name: "worker", System: "billing"}},
+				{Info: Info{Name: "worker", System: "shipping"}},
+				{Info: Info{Name: "api", System: "billing"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "same name within system",
+			files: []*ServiceFile{
+				{Info: Info{Name: "worker", System: "billing"}},
+				{Info: Info{Name: "api", System: "billing"}},
+				{Info: Info{Name: "worker", System: "billing"}},
+			},
+			wantErr:     true,
+			errContains: `service "worker" is defined more than once in system "billing"`,
+		},
+		{
+			name: "same name without system",
+			files: []*ServiceFile{
+				{Info: Info{Name: "worker"}},
+				{Info: Info{Name: "worker"}},
+			},
+			wantErr:     true,
+			errContains: `service "worker" is defined more than once in system ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateUniqueServices(tt.files)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConflictingActions(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "orders"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionUses, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionRequests, Name: "billing", Technology: "grpc"},
+				{Action: RelationshipActionUses, Name: "billing", Technology: "http"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+				{Action: RelationshipActionReplies},
+				{Action: RelationshipActionRequests},
+			},
+		},
+		{
+			Info: Info{Name: "billing"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "orders"},
+			},
+		},
+	}
+
+	assert.Equal(t, []ActionConflict{
+		{
+			Service: "orders",
+			Target:  "billing",
+			Actions: []RelationshipAction{RelationshipActionRequests, RelationshipActionUses},
+		},
+	}, ConflictingActions(files))
+
+	assert.Empty(t, ConflictingActions(files[1:]))
+}
+
+func TestCheckTargetsResolve(t *testing.T) {
+	t.Parallel()
+
+	files := []*ServiceFile{
+		{
+			Info: Info{Name: "OrderService"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionRequests, Name: "PaymentService"},
+				{Action: RelationshipActionRequests, Name: "PaymentService", Technology: "grpc"},
+				{Action: RelationshipActionRequests, Name: "StockService"},
+				{Action: RelationshipActionUses, Name: "PostgreSQL"},
+			},
+		},
+		{
+			Info: Info{Name: "StockService"},
+			Relationships: []Relationship{
+				{Action: RelationshipActionReplies, Name: "OrderService"},
+				{Action: RelationshipActionRequests, Name: "warehouse-service"},
+				{Action: RelationshipActionUses, Name: "Ledger"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts ResolveOptions
+		want []UnresolvedTarget
+	}{
+		{
+			name: "naming convention",
+			want: []UnresolvedTarget{
+				{Service: "OrderService", Target: "PaymentService"},
+				{Service: "StockService", Target: "warehouse-service"},
+			},
+		},
+		{
+			name: "allowlist and denylist",
+			opts: ResolveOptions{Internal: []string{"Ledger"}, External: []string{"PaymentService"}},
+			want: []UnresolvedTarget{
+				{Service: "StockService", Target: "Ledger"},
+				{Service: "StockService", Target: "warehouse-service"},
+			},
+		},
+		{
+			name: "custom pattern",
+			opts: ResolveOptions{Pattern: regexp.MustCompile(`^[A-Z]\w+$`)},
+			want: []UnresolvedTarget{
+				{Service: "OrderService", Target: "PaymentService"},
+				{Service: "OrderService", Target: "PostgreSQL"},
+				{Service: "StockService", Target: "Ledger"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, CheckTargetsResolve(files, tt.opts))
+		})
+	}
+
+	assert.Equal(t, []UnresolvedTarget{{Service: "StockService", Target: "OrderService"}},
+		CheckTargetsResolve(files[1:], ResolveOptions{External: []string{"warehouse-service"}}))
+}
@@ -0,0 +1,49 @@
+package servicefile
+
+import (
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalYAML returns the YAML encoding of the service file, with sorted relationships.
+// Optional fields are omitted when empty. The service file itself is left untouched.
+func MarshalYAML(sf *ServiceFile) ([]byte, error) {
+	sorted := *sf
+	sorted.Relationships = make([]Relationship, len(sf.Relationships))
+	copy(sorted.Relationships, sf.Relationships)
+	sorted.Info.Tags = slices.Clone(sf.Info.Tags)
+	sorted.Sort()
+
+	data, err := yaml.Marshal(&sorted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal service %s: %w", sf.Info.Name, err)
+	}
+
+	return data, nil
+}
+
+// ParseYAML decodes a service file encoded by MarshalYAML.
+// An error is returned if the service file version is not Version.
+func ParseYAML(data []byte) (*ServiceFile, error) {
+	var sf ServiceFile
+	if err := yaml.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("failed to parse service file: %w", err)
+	}
+
+	if err := checkVersion(&sf); err != nil {
+		return nil, err
+	}
+
+	return &sf, nil
+}
+
+// checkVersion returns an error if the service file doesn't have a supported version.
+func checkVersion(sf *ServiceFile) error {
+	if sf.Version != Version {
+		return fmt.Errorf("unsupported version %q, expected %q", sf.Version, Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package servicefile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	sf := &ServiceFile{
+		Version: Version,
+		Info:    Info{Name: "orders", Description: "Takes orders"},
+		Relationships: []Relationship{
+			{Action: RelationshipActionUses, Name: "PostgreSQL", Technology: "postgresql", Proto: "tcp", Description: "Stores orders"},
+			{Action: RelationshipActionReplies},
+		},
+	}
+
+	data, err := MarshalYAML(sf)
+	require.NoError(t, err)
+
+	assert.Equal(t, `servicefile: 0.1.0
+info:
+    name: orders
+    description: Takes orders
+relationships:
+    - action: replies
+    - action: uses
+      name: PostgreSQL
+      description: Stores orders
+      technology: postgresql
+      proto: tcp
+`, string(data))
+
+	assert.Equal(t, RelationshipActionUses, sf.Relationships[0].Action, "input must be left untouched")
+
+	parsed, err := ParseYAML(data)
+	require.NoError(t, err)
+
+	sf.Sort()
+	assert.Equal(t, sf, parsed)
+}
+
+func TestParseYAML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		data        string
+		errContains string
+	}{
+		{
+			name: "supported version",
+			data: "servicefile: 0.1.0\ninfo:\n    name: orders\n",
+		},
+		{
+			name:        "unsupported version",
+			data:        "servicefile: 2.0.0\ninfo:\n    name: orders\n",
+			errContains: `unsupported version "2.0.0", expected "0.1.0"`,
+		},
+		{
+			name:        "missing version",
+			data:        "info:\n    name: orders\n",
+			errContains: `unsupported version ""`,
+		},
+		{
+			name:        "invalid yaml",
+			data:        "info: [",
+			errContains: "failed to parse service file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sf, err := ParseYAML([]byte(tt.data))
+
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				assert.Nil(t, sf)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "orders", sf.Info.Name)
+			}
+		})
+	}
+}